@@ -26,6 +26,9 @@ const (
 
 	// BackendVRF is the name of the VRF backend.
 	BackendVRF = "vrf"
+
+	// MaxInsecureHashRounds is the maximum number of hash rounds of the insecure backend.
+	MaxInsecureHashRounds = 1000
 )
 
 var (
@@ -35,6 +38,9 @@ var (
 	// ErrBeaconNotAvailable is the error returned when a beacon is not
 	// available for the requested height for any reason.
 	ErrBeaconNotAvailable = errors.New(ModuleName, 2, "beacon: random beacon not available")
+
+	// ErrInvalidBeaconProof is the error returned when a beacon proof fails to verify.
+	ErrInvalidBeaconProof = errors.New(ModuleName, 3, "beacon: invalid beacon proof")
 )
 
 // EpochTime is the number of intervals (epochs) since a fixed instant
@@ -107,6 +113,10 @@ type Backend interface {
 
 	// ConsensusParameters returns the beacon consensus parameters.
 	ConsensusParameters(ctx context.Context, height int64) (*ConsensusParameters, error)
+
+	// HealthCheck returns an error if the beacon backend is unable to provide the beacon
+	// for the current epoch.
+	HealthCheck(context.Context) error
 }
 
 // SetableBackend is a Backend that supports setting the current epoch.
@@ -157,6 +167,12 @@ func (cp *ConsensusParameters) Interval() int64 {
 type InsecureParameters struct {
 	// Interval is the epoch interval (in blocks).
 	Interval int64 `json:"interval,omitempty"`
+
+	// HashRounds is the number of hash rounds applied when deriving the beacon, zero meaning
+	// a single round. Additional rounds only make the derivation more expensive, so that
+	// tests and benchmarks can simulate a heavier beacon, and have no security benefit.
+	// At most MaxInsecureHashRounds rounds are allowed.
+	HashRounds uint64 `json:"hash_rounds,omitempty"`
 }
 
 // EpochEvent is the epoch event.
@@ -26,6 +26,8 @@ var (
 	methodWaitEpoch = serviceName.NewMethod("WaitEpoch", EpochTime(0))
 	// methodGetBeacon is the GetBeacon method.
 	methodGetBeacon = serviceName.NewMethod("GetBeacon", int64(0))
+	// methodHealthCheck is the HealthCheck method.
+	methodHealthCheck = serviceName.NewMethod("HealthCheck", nil)
 	// methodStateToGenesis is the StateToGenesis method.
 	methodStateToGenesis = serviceName.NewMethod("StateToGenesis", int64(0))
 	// methodConsensusParameters is the ConsensusParameters method.
@@ -43,6 +45,10 @@ var (
 				MethodName: methodGetBaseEpoch.ShortName(),
 				Handler:    handlerGetBaseEpoch,
 			},
+			{
+				MethodName: methodHealthCheck.ShortName(),
+				Handler:    handlerHealthCheck,
+			},
 			{
 				MethodName: methodGetEpoch.ShortName(),
 				Handler:    handlerGetEpoch,
@@ -101,6 +107,25 @@ func handlerGetBaseEpoch(
 	return interceptor(ctx, nil, info, handler)
 }
 
+func handlerHealthCheck(
+	srv interface{},
+	ctx context.Context,
+	_ func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	if interceptor == nil {
+		return nil, srv.(Backend).HealthCheck(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodHealthCheck.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, srv.(Backend).HealthCheck(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
 func handlerGetEpoch(
 	srv interface{},
 	ctx context.Context,
@@ -307,6 +332,10 @@ func (c *beaconClient) GetBaseEpoch(ctx context.Context) (EpochTime, error) {
 	return rsp, nil
 }
 
+func (c *beaconClient) HealthCheck(ctx context.Context) error {
+	return c.conn.Invoke(ctx, methodHealthCheck.FullName(), nil, nil)
+}
+
 func (c *beaconClient) GetEpoch(ctx context.Context, height int64) (EpochTime, error) {
 	var rsp EpochTime
 	if err := c.conn.Invoke(ctx, methodGetEpoch.FullName(), height, &rsp); err != nil {
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// hashChainCtx is the domain separation context used when hashing hash chain values.
+var hashChainCtx = []byte("oasis-core/beacon: hash chain")
+
+// HashChainBeacon is a beacon built on a reverse hash chain.
+//
+// The chain is derived by repeatedly hashing a secret seed and the last value of the chain
+// (the tip) is published in advance as a commitment. Beacons are the pre-images of the tip,
+// revealed in reverse order, one per epoch, so that each revealed beacon can be verified
+// against the tip while the beacons of future epochs remain unpredictable.
+//
+// The chain is exhausted after length epochs, after which no more beacons are available.
+type HashChainBeacon struct {
+	chain [][]byte
+}
+
+// NewHashChainBeacon creates a new hash chain beacon of the given length from a secret seed.
+func NewHashChainBeacon(seed []byte, length int) (*HashChainBeacon, error) {
+	if len(seed) == 0 {
+		return nil, fmt.Errorf("%w: empty hash chain seed", ErrInvalidArgument)
+	}
+	if length <= 0 {
+		return nil, fmt.Errorf("%w: invalid hash chain length: %d", ErrInvalidArgument, length)
+	}
+
+	chain := make([][]byte, length+1)
+	chain[0] = hashChainStep(seed)
+	for i := 1; i <= length; i++ {
+		chain[i] = hashChainStep(chain[i-1])
+	}
+
+	return &HashChainBeacon{
+		chain: chain,
+	}, nil
+}
+
+// Tip returns the last value of the hash chain, which commits to all the beacons.
+func (b *HashChainBeacon) Tip() []byte {
+	return bytes.Clone(b.chain[len(b.chain)-1])
+}
+
+// Length returns the number of epochs for which the chain provides beacons.
+func (b *HashChainBeacon) Length() int {
+	return len(b.chain) - 1
+}
+
+// GetBeacon returns the beacon for the given epoch.
+//
+// Epochs are counted from zero and ErrBeaconNotAvailable is returned once the chain
+// is exhausted.
+func (b *HashChainBeacon) GetBeacon(epoch EpochTime) ([]byte, error) {
+	if epoch >= EpochTime(b.Length()) {
+		return nil, ErrBeaconNotAvailable
+	}
+	return bytes.Clone(b.chain[b.Length()-1-int(epoch)]), nil
+}
+
+// GetBeaconInt returns a uniformly distributed integer in [0, max) derived from the beacon
+// for the given epoch, see BeaconInt.
+func (b *HashChainBeacon) GetBeaconInt(epoch EpochTime, max *big.Int) (*big.Int, error) {
+	beacon, err := b.GetBeacon(epoch)
+	if err != nil {
+		return nil, err
+	}
+	return BeaconInt(beacon, max)
+}
+
+// VerifyHashChainBeacon verifies that the beacon for the given epoch hashes forward
+// to the committed tip of a chain of the given length.
+func VerifyHashChainBeacon(tip, beacon []byte, epoch EpochTime, length int) error {
+	if len(beacon) != BeaconSize {
+		return fmt.Errorf("%w: malformed hash chain beacon", ErrInvalidArgument)
+	}
+	if length <= 0 || epoch >= EpochTime(length) {
+		return fmt.Errorf("%w: epoch %d outside of the hash chain of length %d", ErrInvalidArgument, epoch, length)
+	}
+
+	v := beacon
+	for i := 0; i <= int(epoch); i++ {
+		v = hashChainStep(v)
+	}
+	if !bytes.Equal(v, tip) {
+		return fmt.Errorf("beacon: hash chain beacon doesn't match the tip")
+	}
+
+	return nil
+}
+
+func hashChainStep(v []byte) []byte {
+	h := sha3.New256()
+	_, _ = h.Write(hashChainCtx)
+	_, _ = h.Write(v)
+	return h.Sum(nil)
+}
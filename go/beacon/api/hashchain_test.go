@@ -0,0 +1,64 @@
+package api
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashChainBeacon(t *testing.T) {
+	require := require.New(t)
+
+	const length = 10
+	b, err := NewHashChainBeacon([]byte("seed"), length)
+	require.NoError(err, "NewHashChainBeacon")
+	require.Equal(length, b.Length())
+	require.Len(b.Tip(), BeaconSize)
+
+	seen := make(map[string]struct{})
+	for epoch := EpochTime(0); epoch < length; epoch++ {
+		v, err := b.GetBeacon(epoch)
+		require.NoError(err, "GetBeacon(%d)", epoch)
+		require.Len(v, BeaconSize)
+		require.NoError(VerifyHashChainBeacon(b.Tip(), v, epoch, length), "beacon should hash forward to the tip")
+
+		// Beacons should not verify for other epochs.
+		require.Error(VerifyHashChainBeacon(b.Tip(), v, epoch+1, length))
+
+		seen[string(v)] = struct{}{}
+	}
+	require.Len(seen, length, "beacons should be unique")
+
+	// The chain should be exhausted after length epochs.
+	_, err = b.GetBeacon(length)
+	require.ErrorIs(err, ErrBeaconNotAvailable)
+
+	// Beacons should not verify against other chains.
+	other, err := NewHashChainBeacon([]byte("other seed"), length)
+	require.NoError(err, "NewHashChainBeacon")
+	v, err := b.GetBeacon(0)
+	require.NoError(err, "GetBeacon")
+	require.Error(VerifyHashChainBeacon(other.Tip(), v, 0, length))
+
+	// Malformed beacons should be rejected.
+	require.Error(VerifyHashChainBeacon(b.Tip(), v[:1], 0, length))
+
+	// Epochs outside of the chain should be rejected without hashing.
+	err = VerifyHashChainBeacon(b.Tip(), v, length, length)
+	require.ErrorIs(err, ErrInvalidArgument)
+	err = VerifyHashChainBeacon(b.Tip(), v, EpochTime(math.MaxUint64), length)
+	require.ErrorIs(err, ErrInvalidArgument)
+
+	// Returned values should not alias the chain.
+	v[0] ^= 0xff
+	v, err = b.GetBeacon(0)
+	require.NoError(err, "GetBeacon")
+	require.NoError(VerifyHashChainBeacon(b.Tip(), v, 0, length), "chain should not be modified")
+
+	// Invalid arguments should be rejected.
+	_, err = NewHashChainBeacon(nil, length)
+	require.ErrorIs(err, ErrInvalidArgument)
+	_, err = NewHashChainBeacon([]byte("seed"), 0)
+	require.ErrorIs(err, ErrInvalidArgument)
+}
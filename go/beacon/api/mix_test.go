@@ -0,0 +1,28 @@
+package api
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMixBeacons(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		sources  [][]byte
+		expected string
+	}{
+		{nil, "67f1e79583c8d799b652ec9b9a7852d0d828093abf36919059acbec54226b2af"},
+		{[][]byte{[]byte("beacon")}, "a1ad63fcb8023d9c2d1297bee7198d573003eae5cc4fccfc8f02e25b296f3422"},
+		{[][]byte{[]byte("beacon"), []byte("drand")}, "d34224c6444ca457aa5b83c052edb98a2b5ab2bc94407c6c9da867e22ca27bc5"},
+		{[][]byte{[]byte("drand"), []byte("beacon")}, "a88427f20cf3be500e9597eff1a95a99c12508aae5acbbc7e6d97bbde7c9c7f5"},
+		{[][]byte{[]byte("beacondrand")}, "a52c0b63fe042d7fa2fd929dd52e9f171490cc7c78036fff06d681c4a6f7884f"},
+		{[][]byte{nil, nil}, "8ff6d6a5d5375723e12eaadd523757a51f1a3aea1488025bd04230157208b07e"},
+	} {
+		b := MixBeacons(tc.sources...)
+		require.Len(b, BeaconSize)
+		require.Equal(tc.expected, hex.EncodeToString(b), "MixBeacons(%q)", tc.sources)
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+)
+
+// readOnlyBackend is a Backend that only exposes the read methods of another backend.
+//
+// All methods are forwarded explicitly rather than by embedding the inner backend, so that
+// any method added to the Backend interface needs to be reviewed before being exposed.
+type readOnlyBackend struct {
+	inner Backend
+}
+
+// NewReadOnlyBeacon wraps the given backend so that it can only be used to read the beacon
+// and epoch state.
+//
+// The returned backend never implements SetableBackend, even if the inner backend does,
+// so the epoch cannot be advanced through it.
+func NewReadOnlyBeacon(inner Backend) Backend {
+	if ro, ok := inner.(*readOnlyBackend); ok {
+		return ro
+	}
+	return &readOnlyBackend{
+		inner: inner,
+	}
+}
+
+func (b *readOnlyBackend) GetBaseEpoch(ctx context.Context) (EpochTime, error) {
+	return b.inner.GetBaseEpoch(ctx)
+}
+
+func (b *readOnlyBackend) GetEpoch(ctx context.Context, height int64) (EpochTime, error) {
+	return b.inner.GetEpoch(ctx, height)
+}
+
+func (b *readOnlyBackend) GetFutureEpoch(ctx context.Context, height int64) (*EpochTimeState, error) {
+	return b.inner.GetFutureEpoch(ctx, height)
+}
+
+func (b *readOnlyBackend) GetEpochBlock(ctx context.Context, epoch EpochTime) (int64, error) {
+	return b.inner.GetEpochBlock(ctx, epoch)
+}
+
+func (b *readOnlyBackend) WaitEpoch(ctx context.Context, epoch EpochTime) error {
+	return b.inner.WaitEpoch(ctx, epoch)
+}
+
+func (b *readOnlyBackend) WatchEpochs(ctx context.Context) (<-chan EpochTime, pubsub.ClosableSubscription, error) {
+	return b.inner.WatchEpochs(ctx)
+}
+
+func (b *readOnlyBackend) WatchLatestEpoch(ctx context.Context) (<-chan EpochTime, pubsub.ClosableSubscription, error) {
+	return b.inner.WatchLatestEpoch(ctx)
+}
+
+func (b *readOnlyBackend) GetBeacon(ctx context.Context, height int64) ([]byte, error) {
+	return b.inner.GetBeacon(ctx, height)
+}
+
+func (b *readOnlyBackend) StateToGenesis(ctx context.Context, height int64) (*Genesis, error) {
+	return b.inner.StateToGenesis(ctx, height)
+}
+
+func (b *readOnlyBackend) ConsensusParameters(ctx context.Context, height int64) (*ConsensusParameters, error) {
+	return b.inner.ConsensusParameters(ctx, height)
+}
+
+func (b *readOnlyBackend) HealthCheck(ctx context.Context) error {
+	return b.inner.HealthCheck(ctx)
+}
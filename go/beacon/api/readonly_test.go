@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testSetableBackend struct {
+	Backend
+
+	epoch EpochTime
+}
+
+func (b *testSetableBackend) GetEpoch(context.Context, int64) (EpochTime, error) {
+	return b.epoch, nil
+}
+
+func (b *testSetableBackend) GetBeacon(context.Context, int64) ([]byte, error) {
+	return []byte{byte(b.epoch)}, nil
+}
+
+func (b *testSetableBackend) SetEpoch(_ context.Context, epoch EpochTime) error {
+	b.epoch = epoch
+	return nil
+}
+
+func TestReadOnlyBeacon(t *testing.T) {
+	require := require.New(t)
+
+	ctx := context.Background()
+	inner := &testSetableBackend{epoch: 10}
+	var _ SetableBackend = inner
+
+	b := NewReadOnlyBeacon(inner)
+	_, ok := b.(SetableBackend)
+	require.False(ok, "read-only beacon should not allow setting the epoch")
+
+	// Reads should be forwarded to the inner backend.
+	epoch, err := b.GetEpoch(ctx, 0)
+	require.NoError(err, "GetEpoch")
+	require.Equal(EpochTime(10), epoch)
+	beacon, err := b.GetBeacon(ctx, 0)
+	require.NoError(err, "GetBeacon")
+	require.Equal([]byte{10}, beacon)
+
+	// Changes of the inner backend should be visible.
+	require.NoError(inner.SetEpoch(ctx, 11), "SetEpoch")
+	epoch, err = b.GetEpoch(ctx, 0)
+	require.NoError(err, "GetEpoch")
+	require.Equal(EpochTime(11), epoch)
+
+	// Wrapping should be idempotent.
+	require.Same(b, NewReadOnlyBeacon(b))
+}
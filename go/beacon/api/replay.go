@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+)
+
+// replayFilePerm is the permission of the files written by SaveReplayBeacons.
+const replayFilePerm = 0o600
+
+// ReplayBeaconEntry is a recorded beacon of a single epoch, as stored in a replay file.
+//
+// A replay file is a JSON array of entries sorted by epoch, with the beacons hex-encoded.
+type ReplayBeaconEntry struct {
+	// Epoch is the epoch of the beacon.
+	Epoch EpochTime `json:"epoch"`
+
+	// Beacon is the hex-encoded beacon.
+	Beacon string `json:"beacon"`
+}
+
+// ReplayBeacon is a beacon that serves the beacons recorded from a previous run, so that
+// the elections of that run can be reproduced deterministically.
+type ReplayBeacon struct {
+	beacons map[EpochTime][]byte
+}
+
+// NewReplayBeacon creates a new replay beacon from the replay file at the given path.
+//
+// The file is validated on load, it must contain at least one beacon, all beacons must be
+// well-formed and no epoch may be recorded more than once.
+func NewReplayBeacon(path string) (*ReplayBeacon, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("beacon: failed to read replay file: %w", err)
+	}
+
+	var entries []ReplayBeaconEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%w: malformed replay file: %w", ErrInvalidArgument, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: empty replay file", ErrInvalidArgument)
+	}
+
+	beacons := make(map[EpochTime][]byte, len(entries))
+	for _, e := range entries {
+		if _, ok := beacons[e.Epoch]; ok {
+			return nil, fmt.Errorf("%w: duplicate replay beacon for epoch %d", ErrInvalidArgument, e.Epoch)
+		}
+		beacon, err := hex.DecodeString(e.Beacon)
+		if err != nil || len(beacon) != BeaconSize {
+			return nil, fmt.Errorf("%w: malformed replay beacon for epoch %d", ErrInvalidArgument, e.Epoch)
+		}
+		beacons[e.Epoch] = beacon
+	}
+
+	return &ReplayBeacon{
+		beacons: beacons,
+	}, nil
+}
+
+// Epochs returns the sorted epochs for which beacons are recorded.
+func (b *ReplayBeacon) Epochs() []EpochTime {
+	epochs := make([]EpochTime, 0, len(b.beacons))
+	for epoch := range b.beacons {
+		epochs = append(epochs, epoch)
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+	return epochs
+}
+
+// GetBeacon returns the recorded beacon for the given epoch.
+//
+// ErrBeaconNotAvailable is returned if no beacon is recorded for the epoch.
+func (b *ReplayBeacon) GetBeacon(epoch EpochTime) ([]byte, error) {
+	beacon, ok := b.beacons[epoch]
+	if !ok {
+		return nil, fmt.Errorf("%w: no replay beacon for epoch %d", ErrBeaconNotAvailable, epoch)
+	}
+	return beacon, nil
+}
+
+// GetBeaconInt returns a uniformly distributed integer in [0, max) derived from the recorded
+// beacon for the given epoch, see BeaconInt.
+func (b *ReplayBeacon) GetBeaconInt(epoch EpochTime, max *big.Int) (*big.Int, error) {
+	beacon, err := b.GetBeacon(epoch)
+	if err != nil {
+		return nil, err
+	}
+	return BeaconInt(beacon, max)
+}
+
+// SaveReplayBeacons writes the given beacons, keyed by epoch, to a replay file at the given
+// path that can be loaded with NewReplayBeacon.
+func SaveReplayBeacons(path string, beacons map[EpochTime][]byte) error {
+	entries := make([]ReplayBeaconEntry, 0, len(beacons))
+	for epoch, beacon := range beacons {
+		if len(beacon) != BeaconSize {
+			return fmt.Errorf("%w: malformed beacon for epoch %d", ErrInvalidArgument, epoch)
+		}
+		entries = append(entries, ReplayBeaconEntry{
+			Epoch:  epoch,
+			Beacon: hex.EncodeToString(beacon),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Epoch < entries[j].Epoch })
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, replayFilePerm)
+}
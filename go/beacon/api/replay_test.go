@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayBeacon(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "beacons.json")
+
+	beacons := map[EpochTime][]byte{
+		3: bytes.Repeat([]byte{3}, BeaconSize),
+		1: bytes.Repeat([]byte{1}, BeaconSize),
+		7: bytes.Repeat([]byte{7}, BeaconSize),
+	}
+	require.NoError(SaveReplayBeacons(path, beacons), "SaveReplayBeacons")
+
+	// Saved beacons should round-trip.
+	b, err := NewReplayBeacon(path)
+	require.NoError(err, "NewReplayBeacon")
+	require.Equal([]EpochTime{1, 3, 7}, b.Epochs())
+	for epoch, expected := range beacons {
+		v, err := b.GetBeacon(epoch)
+		require.NoError(err, "GetBeacon(%d)", epoch)
+		require.Equal(expected, v)
+	}
+
+	// Epochs that were not recorded should not be served.
+	_, err = b.GetBeacon(2)
+	require.ErrorIs(err, ErrBeaconNotAvailable)
+	_, err = b.GetBeaconInt(2, big.NewInt(10))
+	require.ErrorIs(err, ErrBeaconNotAvailable)
+
+	n, err := b.GetBeaconInt(1, big.NewInt(10))
+	require.NoError(err, "GetBeaconInt")
+	expected, err := BeaconInt(beacons[1], big.NewInt(10))
+	require.NoError(err, "BeaconInt")
+	require.Equal(expected, n)
+
+	// Malformed beacons should not be saved.
+	err = SaveReplayBeacons(path, map[EpochTime][]byte{1: {1, 2, 3}})
+	require.ErrorIs(err, ErrInvalidArgument)
+
+	// Replay files should be validated on load.
+	beacon := `"` + string(bytes.Repeat([]byte("ab"), BeaconSize)) + `"`
+	for _, tc := range []struct {
+		name string
+		data string
+	}{
+		{"malformed", `{"epoch": 1}`},
+		{"empty", `[]`},
+		{"duplicate epoch", `[{"epoch": 1, "beacon": ` + beacon + `}, {"epoch": 1, "beacon": ` + beacon + `}]`},
+		{"malformed hex", `[{"epoch": 1, "beacon": "zz"}]`},
+		{"short beacon", `[{"epoch": 1, "beacon": "abcd"}]`},
+	} {
+		path := filepath.Join(dir, "invalid.json")
+		require.NoError(os.WriteFile(path, []byte(tc.data), 0o600), "WriteFile")
+		_, err = NewReplayBeacon(path)
+		require.ErrorIs(err, ErrInvalidArgument, tc.name)
+	}
+
+	_, err = NewReplayBeacon(filepath.Join(dir, "missing.json"))
+	require.Error(err, "missing replay files should be rejected")
+}
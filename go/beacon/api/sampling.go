@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"math/bits"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	// beaconIntCtx is the domain separation context used when sampling integers from beacons.
+	beaconIntCtx = []byte("oasis-core/beacon: integer sampling")
+
+	// beaconShuffleCtx is the domain separation context used when shuffling with beacons.
+	beaconShuffleCtx = []byte("oasis-core/beacon: shuffle")
+)
+
+// BeaconInt deterministically derives a uniformly distributed integer in [0, max) from
+// the given beacon.
+//
+// Candidates are read from a SHAKE256 stream seeded with the beacon and interpreted as
+// big-endian integers with just enough bits to represent max-1. Candidates that are not
+// smaller than max are rejected and the next one is read, which avoids the modulo bias
+// of reducing a single value.
+func BeaconInt(beacon []byte, max *big.Int) (*big.Int, error) {
+	if max == nil || max.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: invalid sampling range", ErrInvalidArgument)
+	}
+
+	bits := new(big.Int).Sub(max, big.NewInt(1)).BitLen()
+	if bits == 0 {
+		return new(big.Int), nil
+	}
+	buf := make([]byte, (bits+7)/8)
+
+	xof := sha3.NewShake256()
+	_, _ = xof.Write(beaconIntCtx)
+	_, _ = xof.Write(beacon)
+
+	v := new(big.Int)
+	for {
+		_, _ = xof.Read(buf)
+		// Clear the excess bits so that each candidate is accepted with a probability
+		// of at least one half.
+		buf[0] &= byte(0xff >> (8*len(buf) - bits))
+		if v.SetBytes(buf).Cmp(max) < 0 {
+			return v, nil
+		}
+	}
+}
+
+// ShuffleWithBeacon deterministically derives a permutation of [0, n) from the given beacon.
+//
+// The permutation is obtained by a Fisher-Yates shuffle of the identity permutation, going
+// from the last element to the second one, swapping the element at index i with the one at
+// index j, uniformly sampled from [0, i]. Indices are sampled by rejection from a SHAKE256
+// stream seeded with the beacon, where each candidate is a big-endian 64-bit integer with
+// the excess bits cleared.
+func ShuffleWithBeacon(beacon []byte, n int) []int {
+	if n <= 0 {
+		return []int{}
+	}
+
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+
+	xof := sha3.NewShake256()
+	_, _ = xof.Write(beaconShuffleCtx)
+	_, _ = xof.Write(beacon)
+
+	for i := n - 1; i > 0; i-- {
+		j := sampleUint64(xof, uint64(i)+1)
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return perm
+}
+
+// sampleUint64 reads a uniformly distributed integer in [0, max) from the given stream.
+func sampleUint64(r io.Reader, max uint64) uint64 {
+	mask := uint64(1)<<bits.Len64(max-1) - 1
+	var buf [8]byte
+	for {
+		_, _ = r.Read(buf[:])
+		if v := binary.BigEndian.Uint64(buf[:]) & mask; v < max {
+			return v
+		}
+	}
+}
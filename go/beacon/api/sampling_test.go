@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestBeaconInt(t *testing.T) {
+	require := require.New(t)
+
+	beacon := sha3.Sum256([]byte("beacon"))
+
+	// Known answers, the candidates are interpreted as big-endian integers.
+	for _, tc := range []struct {
+		max string
+		v   string
+	}{
+		{"1", "0"},
+		{"1000", "231"},
+		{"18446744073709551616", "13900148274694206296"},
+		{"1000000000000000000000000000000", "71567453098031936174125509593"},
+	} {
+		max, _ := new(big.Int).SetString(tc.max, 10)
+		v, err := BeaconInt(beacon[:], max)
+		require.NoError(err, "BeaconInt(%s)", tc.max)
+		require.Equal(tc.v, v.String(), "BeaconInt(%s)", tc.max)
+	}
+
+	// Invalid ranges should be rejected.
+	for _, max := range []*big.Int{nil, big.NewInt(0), big.NewInt(-1)} {
+		_, err := BeaconInt(beacon[:], max)
+		require.ErrorIs(err, ErrInvalidArgument, "BeaconInt(%v)", max)
+	}
+}
+
+func TestBeaconIntUniform(t *testing.T) {
+	require := require.New(t)
+
+	// Reducing a byte modulo 192 would sample the first third of the range twice as often
+	// as the others, while rejection sampling should sample all thirds equally.
+	const samples = 30000
+	max := big.NewInt(192)
+	var counts [3]int
+	for i := uint64(0); i < samples; i++ {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], i)
+		beacon := sha3.Sum256(buf[:])
+
+		v, err := BeaconInt(beacon[:], max)
+		require.NoError(err, "BeaconInt")
+		require.True(v.Sign() >= 0 && v.Cmp(max) < 0, "value should be in range")
+		counts[v.Int64()/64]++
+	}
+	for i, n := range counts {
+		require.InDelta(samples/3, n, samples/3*0.05, "third %d should be sampled uniformly", i)
+	}
+}
+
+func TestShuffleWithBeacon(t *testing.T) {
+	require := require.New(t)
+
+	beacon := sha3.Sum256([]byte("beacon"))
+
+	// Known answers.
+	for _, tc := range []struct {
+		n    int
+		perm []int
+	}{
+		{0, []int{}},
+		{1, []int{0}},
+		{2, []int{1, 0}},
+		{10, []int{7, 6, 8, 5, 3, 9, 0, 1, 2, 4}},
+	} {
+		require.Equal(tc.perm, ShuffleWithBeacon(beacon[:], tc.n), "ShuffleWithBeacon(%d)", tc.n)
+	}
+
+	// Larger shuffles should still be permutations.
+	perm := ShuffleWithBeacon(beacon[:], 1000)
+	identity := make([]int, len(perm))
+	for i := range identity {
+		identity[i] = i
+	}
+	require.ElementsMatch(identity, perm, "shuffle should be a permutation")
+	require.NotEqual(ShuffleWithBeacon(nil, 1000), perm, "shuffle should depend on the beacon")
+}
+
+func TestHashChainBeaconInt(t *testing.T) {
+	require := require.New(t)
+
+	b, err := NewHashChainBeacon([]byte("seed"), 2)
+	require.NoError(err, "NewHashChainBeacon")
+
+	max := big.NewInt(1000)
+	v, err := b.GetBeaconInt(0, max)
+	require.NoError(err, "GetBeaconInt")
+
+	beacon, err := b.GetBeacon(0)
+	require.NoError(err, "GetBeacon")
+	expected, err := BeaconInt(beacon, max)
+	require.NoError(err, "BeaconInt")
+	require.Equal(expected, v, "integers should be derived from the epoch beacon")
+
+	_, err = b.GetBeaconInt(2, max)
+	require.ErrorIs(err, ErrBeaconNotAvailable)
+}
@@ -31,6 +31,9 @@ func (p *ConsensusParameters) SanityCheck() error {
 		if params.Interval <= 0 && !p.DebugMockBackend {
 			return fmt.Errorf("epoch interval must be > 0")
 		}
+		if params.HashRounds > MaxInsecureHashRounds {
+			return fmt.Errorf("hash rounds must be <= %d", MaxInsecureHashRounds)
+		}
 	case BackendVRF:
 		params := p.VRFParameters
 		if params == nil {
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// subBeaconCtx is the domain separation context used when deriving sub-beacons.
+var subBeaconCtx = []byte("oasis-core/beacon: sub-beacon")
+
+// SubBeacon deterministically derives an independent beacon for the given scope from the
+// base beacon of the given epoch.
+//
+// The base beacon and the scope are length-prefixed before being hashed, so sub-beacons
+// derived for different scopes (or epochs) are uncorrelated, while deriving a sub-beacon
+// for the same scope and epoch always yields the same value.
+func SubBeacon(beacon []byte, epoch EpochTime, scope []byte) []byte {
+	h := sha3.New256()
+	_, _ = h.Write(subBeaconCtx)
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(epoch))
+	_, _ = h.Write(buf[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(len(beacon)))
+	_, _ = h.Write(buf[:])
+	_, _ = h.Write(beacon)
+	binary.BigEndian.PutUint64(buf[:], uint64(len(scope)))
+	_, _ = h.Write(buf[:])
+	_, _ = h.Write(scope)
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubBeacon(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		beacon   []byte
+		epoch    EpochTime
+		scope    []byte
+		expected string
+	}{
+		{nil, 0, nil, "01c4dc5e0f2c9c1bb14493c3bd570f789e3127997618d7015999e932baf17ee6"},
+		{[]byte("beacon"), 1, nil, "8d283806e7a4bed028909e022617b6ddfc61f2342eed6beb65957edb11f49373"},
+		{[]byte("beacon"), 1, []byte("runtime-a"), "ee4dfaba8320dade57943c946d0b10ea13b3c7eade14dad632eb5132ff06538e"},
+		{[]byte("beacon"), 1, []byte("runtime-b"), "312d7ed99adef5ee8c0266d40822a69a0d3245d3509f54ce686958b67d93bb84"},
+		{[]byte("beacon"), 2, []byte("runtime-a"), "526d53bbdc7e1caee1c0cd9033cede98da9d3f855e307679e526861ff8ddc6ba"},
+	} {
+		b := SubBeacon(tc.beacon, tc.epoch, tc.scope)
+		require.Len(b, BeaconSize)
+		require.Equal(tc.expected, hex.EncodeToString(b), "SubBeacon(%q, %d, %q)", tc.beacon, tc.epoch, tc.scope)
+		require.Equal(b, SubBeacon(tc.beacon, tc.epoch, tc.scope), "sub-beacons should be reproducible")
+	}
+
+	// Moving bytes between the beacon and the scope must not yield the same sub-beacon.
+	require.NotEqual(
+		SubBeacon([]byte("beacon"), 1, []byte("scope")),
+		SubBeacon([]byte("beaconscope"), 1, nil),
+	)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// verifiableBeaconCtx is the domain separation context used when deriving the VRF input
+// for verifiable beacons.
+var verifiableBeaconCtx = []byte("oasis-core/beacon: verifiable")
+
+// VerifiableBeacon is a Backend that can also provide proofs that its beacons were
+// generated correctly.
+//
+// This is an optional capability, clients should type-assert a Backend to find out
+// whether it is supported.
+type VerifiableBeacon interface {
+	Backend
+
+	// GetBeaconAndProof gets the beacon for the provided epoch, together with a proof
+	// that can be verified using VerifyBeaconProof.
+	GetBeaconAndProof(ctx context.Context, epoch EpochTime) ([]byte, []byte, error)
+}
+
+// VerifiableBeaconAlpha returns the VRF input (alpha) from which the verifiable beacon
+// for the given epoch is derived.
+func VerifiableBeaconAlpha(epoch EpochTime) []byte {
+	alpha := make([]byte, 0, len(verifiableBeaconCtx)+8)
+	alpha = append(alpha, verifiableBeaconCtx...)
+	return binary.BigEndian.AppendUint64(alpha, uint64(epoch))
+}
+
+// ProveBeacon generates the verifiable beacon for the given epoch together with its proof.
+func ProveBeacon(signer signature.Signer, epoch EpochTime) ([]byte, []byte, error) {
+	proof, err := signature.Prove(signer, VerifiableBeaconAlpha(epoch))
+	if err != nil {
+		return nil, nil, err
+	}
+	beacon := proof.UnsafeToHash()[:BeaconSize]
+
+	return beacon, proof.Proof[:], nil
+}
+
+// VerifyBeaconProof verifies that the beacon for the given epoch was generated by
+// the holder of the given public key.
+func VerifyBeaconProof(pubKey signature.PublicKey, epoch EpochTime, beacon, proof []byte) error {
+	if len(beacon) != BeaconSize {
+		return fmt.Errorf("%w: malformed beacon", ErrInvalidBeaconProof)
+	}
+
+	ok, beta := pubKey.VerifyVRF(VerifiableBeaconAlpha(epoch), proof)
+	if !ok {
+		return fmt.Errorf("%w: invalid VRF proof", ErrInvalidBeaconProof)
+	}
+	if !bytes.Equal(beta[:BeaconSize], beacon) {
+		return fmt.Errorf("%w: beacon mismatch", ErrInvalidBeaconProof)
+	}
+
+	return nil
+}
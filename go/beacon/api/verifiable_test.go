@@ -0,0 +1,53 @@
+package api
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+)
+
+func TestVerifyBeaconProof(t *testing.T) {
+	require := require.New(t)
+
+	factory := memorySigner.NewFactory()
+	signer, err := factory.Generate(signature.SignerVRF, rand.Reader)
+	require.NoError(err, "Generate")
+	other, err := factory.Generate(signature.SignerVRF, rand.Reader)
+	require.NoError(err, "Generate")
+
+	beacon, proof, err := ProveBeacon(signer, 42)
+	require.NoError(err, "ProveBeacon")
+	require.Len(beacon, BeaconSize)
+	require.Len(proof, signature.ProofSize)
+
+	require.NoError(VerifyBeaconProof(signer.Public(), 42, beacon, proof))
+
+	// Proving is deterministic.
+	beacon2, _, err := ProveBeacon(signer, 42)
+	require.NoError(err, "ProveBeacon")
+	require.Equal(beacon, beacon2)
+
+	// Wrong epoch.
+	err = VerifyBeaconProof(signer.Public(), 43, beacon, proof)
+	require.ErrorIs(err, ErrInvalidBeaconProof)
+
+	// Wrong public key.
+	err = VerifyBeaconProof(other.Public(), 42, beacon, proof)
+	require.ErrorIs(err, ErrInvalidBeaconProof)
+
+	// Tampered beacon.
+	tampered := append([]byte{}, beacon...)
+	tampered[0] ^= 0xff
+	err = VerifyBeaconProof(signer.Public(), 42, tampered, proof)
+	require.ErrorIs(err, ErrInvalidBeaconProof)
+
+	// Malformed beacon and proof.
+	err = VerifyBeaconProof(signer.Public(), 42, beacon[:1], proof)
+	require.ErrorIs(err, ErrInvalidBeaconProof)
+	err = VerifyBeaconProof(signer.Public(), 42, beacon, proof[:1])
+	require.ErrorIs(err, ErrInvalidBeaconProof)
+}
@@ -31,6 +31,57 @@ func BeaconImplementationTests(t *testing.T, backend api.SetableBackend) {
 	require.NoError(err, "GetBeacon")
 	require.Len(newBeacon, api.BeaconSize, "GetBeacon - length")
 	require.NotEqual(beacon, newBeacon, "After epoch transition, new beacon should be generated.")
+
+	err = backend.HealthCheck(context.Background())
+	require.NoError(err, "HealthCheck")
+}
+
+// ReadOnlyBeaconImplementationTests exercises the basic functionality of a
+// beacon backend through its read-only wrapper.
+func ReadOnlyBeaconImplementationTests(t *testing.T, backend api.SetableBackend) {
+	require := require.New(t)
+
+	readOnly := api.NewReadOnlyBeacon(backend)
+	_, ok := readOnly.(api.SetableBackend)
+	require.False(ok, "read-only beacon should not be setable")
+
+	// The epoch can only be advanced through the wrapped backend.
+	BeaconImplementationTests(t, &readOnlyTimeSource{
+		Backend:    readOnly,
+		timeSource: backend,
+	})
+
+	// Reads should match the wrapped backend.
+	ctx := context.Background()
+	epoch, err := readOnly.GetEpoch(ctx, consensus.HeightLatest)
+	require.NoError(err, "GetEpoch")
+	expectedEpoch, err := backend.GetEpoch(ctx, consensus.HeightLatest)
+	require.NoError(err, "GetEpoch")
+	require.Equal(expectedEpoch, epoch, "GetEpoch should match the wrapped backend")
+
+	beacon, err := readOnly.GetBeacon(ctx, consensus.HeightLatest)
+	require.NoError(err, "GetBeacon")
+	expectedBeacon, err := backend.GetBeacon(ctx, consensus.HeightLatest)
+	require.NoError(err, "GetBeacon")
+	require.Equal(expectedBeacon, beacon, "GetBeacon should match the wrapped backend")
+
+	params, err := readOnly.ConsensusParameters(ctx, consensus.HeightLatest)
+	require.NoError(err, "ConsensusParameters")
+	expectedParams, err := backend.ConsensusParameters(ctx, consensus.HeightLatest)
+	require.NoError(err, "ConsensusParameters")
+	require.Equal(expectedParams, params, "ConsensusParameters should match the wrapped backend")
+}
+
+// readOnlyTimeSource is a read-only beacon backend whose epoch is advanced
+// through the backend it wraps.
+type readOnlyTimeSource struct {
+	api.Backend
+
+	timeSource api.SetableBackend
+}
+
+func (s *readOnlyTimeSource) SetEpoch(ctx context.Context, epoch api.EpochTime) error {
+	return s.timeSource.SetEpoch(ctx, epoch)
 }
 
 // EpochtimeSetableImplementationTest exercises the basic functionality of
@@ -439,6 +439,11 @@ type Runtime struct {
 	// ExtraInfo is the extra per node + per runtime opaque data associated
 	// with the current instance.
 	ExtraInfo []byte `json:"extra_info"`
+
+	// Shadow marks the runtime instance as a shadow (canary) instance. Shadow key manager
+	// instances are fully verified when the key manager committee is formed, but never
+	// join the committee.
+	Shadow bool `json:"shadow,omitempty"`
 }
 
 // TLSInfo contains information for connecting to this node via TLS.
@@ -90,7 +90,7 @@ func (impl *backendInsecure) OnBeginBlock(
 	impl.app.doEmitEpochEvent(ctx, future.Epoch)
 
 	// Generate the beacon
-	return impl.onEpochChangeBeacon(ctx, future.Epoch)
+	return impl.onEpochChangeBeacon(ctx, params.InsecureParameters, future.Epoch)
 }
 
 func (impl *backendInsecure) scheduleEpochTransitionBlock(
@@ -106,6 +106,7 @@ func (impl *backendInsecure) scheduleEpochTransitionBlock(
 
 func (impl *backendInsecure) onEpochChangeBeacon(
 	ctx *api.Context,
+	params *beacon.InsecureParameters,
 	epoch beacon.EpochTime,
 ) error {
 	var entropy []byte
@@ -116,7 +117,11 @@ func (impl *backendInsecure) onEpochChangeBeacon(
 	ctx.Logger().Debug("onBeaconEpochChange: using block hash as entropy")
 	entropy = insecureBlockEntropy(ctx)
 
-	b := GetBeacon(epoch, entropyCtx, entropy)
+	var rounds uint64
+	if params != nil {
+		rounds = params.HashRounds
+	}
+	b := GetBeaconRounds(epoch, entropyCtx, entropy, rounds)
 
 	ctx.Logger().Debug("onBeaconEpochChange: generated beacon",
 		"epoch", epoch,
@@ -19,6 +19,9 @@ import (
 var (
 	prodEntropyCtx = []byte("EkB-tmnt")
 
+	// beaconExpansionCtx is the domain separation context used when expanding a beacon.
+	beaconExpansionCtx = []byte("oasis-core/beacon: expand")
+
 	_ api.Application = (*beaconApplication)(nil)
 )
 
@@ -167,3 +170,42 @@ func GetBeacon(epoch beacon.EpochTime, entropyCtx, entropy []byte) []byte {
 	_, _ = h.Write(tmp[:])
 	return h.Sum(nil)
 }
+
+// GetBeaconRounds derives the beacon like GetBeacon, re-hashing it until the given number of
+// hash rounds has been applied. Zero or one rounds yield the same beacon as GetBeacon.
+//
+// This is only meant to simulate a more expensive beacon in tests and benchmarks, additional
+// rounds don't affect the security of the beacon.
+func GetBeaconRounds(epoch beacon.EpochTime, entropyCtx, entropy []byte, rounds uint64) []byte {
+	b := GetBeacon(epoch, entropyCtx, entropy)
+	for i := uint64(1); i < rounds; i++ {
+		h := sha3.Sum256(b)
+		b = h[:]
+	}
+	return b
+}
+
+// GetBeaconN derives n bytes of beacon entropy from the epoch and entropy source.
+//
+// The first beacon.BeaconSize bytes are equal to the output of GetBeacon, any further
+// bytes are deterministically derived from it using SHAKE256.
+func GetBeaconN(epoch beacon.EpochTime, entropyCtx, entropy []byte, n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("beacon: invalid beacon length: %d", n)
+	}
+
+	b := GetBeacon(epoch, entropyCtx, entropy)
+	if n <= len(b) {
+		return b[:n], nil
+	}
+
+	out := make([]byte, n)
+	copy(out, b)
+
+	xof := sha3.NewShake256()
+	_, _ = xof.Write(beaconExpansionCtx)
+	_, _ = xof.Write(b)
+	_, _ = xof.Read(out[len(b):])
+
+	return out, nil
+}
@@ -0,0 +1,92 @@
+package beacon
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+)
+
+func TestGetBeaconVectors(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		epoch   beacon.EpochTime
+		entropy []byte
+		beacon  string
+	}{
+		{0, nil, "b3d1a0a7de1ddc77f5e5628d21f99b1f014520661bd916c796376d61d9f543d7"},
+		{42, []byte("entropy"), "5c13f7c89bec774fc6e8fa1a89f3226e06baf24112584146a423367c7adab62b"},
+		{beacon.EpochMax, make([]byte, 32), "4f017cbdde28d58a4950dbcd5af3e1844de7805bda2c4be39d3b61c4ca456e08"},
+	} {
+		expected, err := hex.DecodeString(tc.beacon)
+		require.NoError(err, "hex.DecodeString")
+
+		require.Equal(expected, GetBeacon(tc.epoch, prodEntropyCtx, tc.entropy), "GetBeacon(%d)", tc.epoch)
+		require.Equal(expected, GetBeaconRounds(tc.epoch, prodEntropyCtx, tc.entropy, 0), "GetBeaconRounds(%d, 0)", tc.epoch)
+		require.Equal(expected, GetBeaconRounds(tc.epoch, prodEntropyCtx, tc.entropy, 1), "GetBeaconRounds(%d, 1)", tc.epoch)
+	}
+}
+
+func TestGetBeaconN(t *testing.T) {
+	require := require.New(t)
+
+	epoch := beacon.EpochTime(42)
+	entropy := []byte("entropy")
+	b := GetBeacon(epoch, prodEntropyCtx, entropy)
+	require.Len(b, beacon.BeaconSize)
+
+	for _, n := range []int{1, beacon.BeaconSize - 1, beacon.BeaconSize, beacon.BeaconSize + 1, 1024} {
+		bn, err := GetBeaconN(epoch, prodEntropyCtx, entropy, n)
+		require.NoError(err, "GetBeaconN(%d)", n)
+		require.Len(bn, n)
+
+		if n <= beacon.BeaconSize {
+			require.Equal(b[:n], bn, "output should be a prefix of GetBeacon")
+		} else {
+			require.Equal(b, bn[:beacon.BeaconSize], "output should be prefixed by GetBeacon")
+		}
+
+		again, err := GetBeaconN(epoch, prodEntropyCtx, entropy, n)
+		require.NoError(err, "GetBeaconN(%d)", n)
+		require.Equal(bn, again, "output should be deterministic")
+	}
+
+	// Longer outputs should extend shorter ones.
+	short, err := GetBeaconN(epoch, prodEntropyCtx, entropy, 64)
+	require.NoError(err, "GetBeaconN")
+	long, err := GetBeaconN(epoch, prodEntropyCtx, entropy, 128)
+	require.NoError(err, "GetBeaconN")
+	require.Equal(short, long[:64])
+
+	// Different epochs should yield different outputs.
+	other, err := GetBeaconN(epoch+1, prodEntropyCtx, entropy, 128)
+	require.NoError(err, "GetBeaconN")
+	require.NotEqual(long, other)
+
+	for _, n := range []int{0, -1} {
+		_, err = GetBeaconN(epoch, prodEntropyCtx, entropy, n)
+		require.Error(err, "GetBeaconN(%d) should fail", n)
+	}
+}
+
+func TestGetBeaconRounds(t *testing.T) {
+	require := require.New(t)
+
+	epoch := beacon.EpochTime(42)
+	entropy := []byte("entropy")
+	b := GetBeacon(epoch, prodEntropyCtx, entropy)
+
+	require.Equal(b, GetBeaconRounds(epoch, prodEntropyCtx, entropy, 0), "zero rounds should match GetBeacon")
+	require.Equal(b, GetBeaconRounds(epoch, prodEntropyCtx, entropy, 1), "one round should match GetBeacon")
+
+	h := sha3.Sum256(b)
+	require.Equal(h[:], GetBeaconRounds(epoch, prodEntropyCtx, entropy, 2), "each round should re-hash the beacon")
+
+	b3 := GetBeaconRounds(epoch, prodEntropyCtx, entropy, 3)
+	require.Len(b3, beacon.BeaconSize)
+	require.Equal(b3, GetBeaconRounds(epoch, prodEntropyCtx, entropy, 3), "output should be deterministic")
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
 	tmapi "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	governanceApi "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/governance/api"
 	"github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets"
 	registryapp "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/registry"
 	registryState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/registry/state"
@@ -54,6 +55,10 @@ func (app *keymanagerApplication) OnRegister(state tmapi.ApplicationState, md tm
 	for _, ext := range app.exts {
 		ext.OnRegister(state, md)
 	}
+
+	// Subscribe to messages emitted by other apps.
+	md.Subscribe(governanceApi.MessageChangeParameters, app)
+	md.Subscribe(governanceApi.MessageValidateParameterChanges, app)
 }
 
 // OnCleanup implements api.Application.
@@ -79,8 +84,18 @@ func (app *keymanagerApplication) BeginBlock(ctx *tmapi.Context) error {
 }
 
 // ExecuteMessage implements api.Application.
-func (app *keymanagerApplication) ExecuteMessage(*tmapi.Context, interface{}, interface{}) (interface{}, error) {
-	return nil, fmt.Errorf("keymanager: unexpected message")
+func (app *keymanagerApplication) ExecuteMessage(ctx *tmapi.Context, kind, msg interface{}) (interface{}, error) {
+	switch kind {
+	case governanceApi.MessageValidateParameterChanges:
+		// A change parameters proposal is about to be submitted. Validate changes.
+		return app.changeParameters(ctx, msg, false)
+	case governanceApi.MessageChangeParameters:
+		// A change parameters proposal has just been accepted and closed. Validate and apply
+		// changes.
+		return app.changeParameters(ctx, msg, true)
+	default:
+		return nil, fmt.Errorf("keymanager: unexpected message")
+	}
 }
 
 // ExecuteTx implements api.Application.
@@ -5,6 +5,7 @@ import (
 
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	secretsApp "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets"
 	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
 	governance "github.com/oasisprotocol/oasis-core/go/governance/api"
 	keymanager "github.com/oasisprotocol/oasis-core/go/keymanager/api"
@@ -42,12 +43,22 @@ func (app *keymanagerApplication) changeParameters(ctx *api.Context, msg interfa
 	if err = params.SanityCheck(); err != nil {
 		return nil, fmt.Errorf("keymanager: failed to validate consensus parameters: %w", err)
 	}
+	for i := range changes.CompromisedSecrets {
+		if err = secretsApp.MarkSecretsCompromised(ctx, AppName, &changes.CompromisedSecrets[i], false); err != nil {
+			return nil, fmt.Errorf("keymanager: failed to validate compromised secrets: %w", err)
+		}
+	}
 
 	// Apply changes.
 	if apply {
 		if err = state.SetConsensusParameters(ctx, params); err != nil {
 			return nil, fmt.Errorf("keymanager: failed to update consensus parameters: %w", err)
 		}
+		for i := range changes.CompromisedSecrets {
+			if err = secretsApp.MarkSecretsCompromised(ctx, AppName, &changes.CompromisedSecrets[i], true); err != nil {
+				return nil, fmt.Errorf("keymanager: failed to mark secrets compromised: %w", err)
+			}
+		}
 	}
 
 	// Non-nil response signals that changes are valid and were successfully applied (if required).
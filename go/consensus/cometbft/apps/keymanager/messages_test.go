@@ -5,13 +5,17 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
 	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
 	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
 	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
+	registryState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/registry/state"
 	governance "github.com/oasisprotocol/oasis-core/go/governance/api"
 	keymanager "github.com/oasisprotocol/oasis-core/go/keymanager/api"
 	"github.com/oasisprotocol/oasis-core/go/keymanager/secrets"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
 )
 
 func TestChangeParameters(t *testing.T) {
@@ -93,4 +97,52 @@ func TestChangeParameters(t *testing.T) {
 		_, err := app.changeParameters(ctx, &proposal, true)
 		require.EqualError(err, "keymanager: failed to validate consensus parameter changes: consensus parameter changes should not be empty")
 	})
+	t.Run("compromised secrets", func(t *testing.T) {
+		require := require.New(t)
+
+		// Register a key manager runtime with 2 generations of the master secret.
+		var kmID common.Namespace
+		err := kmID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000001")
+		require.NoError(err, "failed to unmarshal keymanager id")
+		kmRt := registry.Runtime{
+			ID:          kmID,
+			Kind:        registry.KindKeyManager,
+			TEEHardware: node.TEEHardwareIntelSGX,
+		}
+		err = registryState.NewMutableState(ctx.State()).SetRuntime(ctx, &kmRt, false)
+		require.NoError(err, "registry.SetRuntime")
+		err = state.SetStatus(ctx, &secrets.Status{
+			ID:            kmID,
+			IsInitialized: true,
+			Generation:    1,
+		})
+		require.NoError(err, "keymanager.SetStatus")
+
+		newProposal := func(generations ...uint64) *governance.ChangeParametersProposal {
+			return &governance.ChangeParametersProposal{
+				Module: keymanager.ModuleName,
+				Changes: cbor.Marshal(secrets.ConsensusParameterChanges{
+					CompromisedSecrets: []secrets.CompromisedSecrets{{
+						ID:          kmID,
+						Generations: generations,
+					}},
+				}),
+			}
+		}
+
+		_, err = app.changeParameters(ctx, newProposal(2), false)
+		require.EqualError(err, "keymanager: failed to validate compromised secrets: keymanager: sanity check failed: master secret generation 2 has not been generated")
+
+		_, err = app.changeParameters(ctx, newProposal(1), false)
+		require.NoError(err, "validation of compromised secrets should succeed")
+		status, err := state.Status(ctx, kmID)
+		require.NoError(err, "keymanager.Status")
+		require.Empty(status.CompromisedGenerations, "generations shouldn't be marked")
+
+		_, err = app.changeParameters(ctx, newProposal(1), true)
+		require.NoError(err, "marking secrets compromised should succeed")
+		status, err = state.Status(ctx, kmID)
+		require.NoError(err, "keymanager.Status")
+		require.Equal([]uint64{1}, status.CompromisedGenerations, "generations should be marked")
+	})
 }
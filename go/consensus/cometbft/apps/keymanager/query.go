@@ -2,10 +2,14 @@ package keymanager
 
 import (
 	"context"
+	"fmt"
 
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
 	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	beaconState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/beacon/state"
 	"github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets"
 	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
+	registryState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/registry/state"
 )
 
 // Query is the key manager query interface.
@@ -20,19 +24,53 @@ type QueryFactory struct {
 
 // QueryAt returns the key manager query interface for a specific height.
 func (sf *QueryFactory) QueryAt(ctx context.Context, height int64) (Query, error) {
+	// Historical queries cannot be served from before the earliest retained state,
+	// which never predates the genesis.
+	if abciAPI.FromCtx(ctx) == nil && height > 0 {
+		lastRetained, err := sf.state.LastRetainedVersion()
+		if err != nil {
+			return nil, err
+		}
+		if height < lastRetained {
+			return nil, fmt.Errorf("%w: height %d predates the earliest available state (height: %d)",
+				consensus.ErrVersionNotFound, height, lastRetained,
+			)
+		}
+	}
+
 	state, err := secretsState.NewImmutableState(ctx, sf.state, height) // TODO: not ok
 	if err != nil {
 		return nil, err
 	}
-	return &keymanagerQuerier{state}, nil
+
+	// Some queries need access to the registry to give useful responses.
+	regState, err := registryState.NewImmutableState(ctx, sf.state, height)
+	if err != nil {
+		return nil, err
+	}
+
+	// Status projections need access to the current epoch.
+	beaconState, err := beaconState.NewImmutableState(ctx, sf.state, height)
+	if err != nil {
+		return nil, err
+	}
+
+	if height <= 0 || height > sf.state.BlockHeight() {
+		height = sf.state.BlockHeight()
+	}
+
+	return &keymanagerQuerier{state, regState, beaconState, height}, nil
 }
 
 type keymanagerQuerier struct {
-	state *secretsState.ImmutableState
+	state       *secretsState.ImmutableState
+	regState    *registryState.ImmutableState
+	beaconState *beaconState.ImmutableState
+	height      int64
 }
 
 func (kq *keymanagerQuerier) Secrets() secrets.Query {
-	return secrets.NewQuery(kq.state)
+	return secrets.NewQuery(kq.state, kq.regState, kq.beaconState, kq.height)
 }
 
 func (app *keymanagerApplication) QueryFactory() interface{} {
@@ -0,0 +1,27 @@
+package keymanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	genesis "github.com/oasisprotocol/oasis-core/go/genesis/api"
+)
+
+func TestQueryAtHeight(t *testing.T) {
+	require := require.New(t)
+
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{
+		BlockHeight: 100,
+		Genesis: &genesis.Document{
+			Height: 10,
+		},
+	})
+	qf := NewQueryFactory(appState)
+
+	_, err := qf.QueryAt(context.Background(), 9)
+	require.ErrorIs(err, consensus.ErrVersionNotFound, "queries before the genesis should fail")
+}
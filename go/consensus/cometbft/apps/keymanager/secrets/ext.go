@@ -20,6 +20,8 @@ type secretsExt struct {
 
 // New creates a new master and ephemeral secrets extension for the key manager application.
 func New(appName string) tmapi.Extension {
+	initMetrics()
+
 	return &secretsExt{
 		appName: appName,
 	}
@@ -58,6 +60,12 @@ func (ext *secretsExt) ExecuteTx(ctx *tmapi.Context, tx *transaction.Transaction
 			return secrets.ErrInvalidArgument
 		}
 		return ext.publishEphemeralSecret(ctx, state, &sigSec)
+	case secrets.MethodRefreshStatus:
+		var sr secrets.StatusRefresh
+		if err := cbor.Unmarshal(tx.Body, &sr); err != nil {
+			return secrets.ErrInvalidArgument
+		}
+		return ext.refreshStatus(ctx, state, &sr)
 	default:
 		panic(fmt.Sprintf("keymanager: secrets: invalid method: %s", tx.Method))
 	}
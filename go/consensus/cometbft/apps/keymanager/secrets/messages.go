@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"fmt"
+	"slices"
+
+	tmapi "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
+	registryState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/registry/state"
+	"github.com/oasisprotocol/oasis-core/go/keymanager/secrets"
+)
+
+// MarkSecretsCompromised marks generations of the master secret as compromised. If apply
+// is false, the generations are only validated.
+//
+// This is a security incident response tool, available via governance change parameters
+// proposals, so that it doesn't depend on the keys of the key manager owner, which may
+// themselves be compromised. Compromised generations are recorded in the key manager status,
+// so that runtimes can stop trusting data encrypted under them and force its re-encryption.
+// The secrets themselves are not deleted, as they are still needed to decrypt the data during
+// the migration. Generations cannot be unmarked.
+func MarkSecretsCompromised(ctx *tmapi.Context, appName string, cs *secrets.CompromisedSecrets, apply bool) error {
+	// Ensure that the runtime exists and is a key manager.
+	regState := registryState.NewMutableState(ctx.State())
+	kmRt, err := keyManagerRuntime(ctx, regState.ImmutableState, cs.ID)
+	if err != nil {
+		return err
+	}
+
+	// Only generated master secrets can be marked as compromised.
+	state := secretsState.NewMutableState(ctx.State())
+	status, err := state.Status(ctx, kmRt.ID)
+	if err != nil {
+		return err
+	}
+	if err = cs.SanityCheck(status.NextGeneration()); err != nil {
+		return err
+	}
+
+	if !apply {
+		return nil
+	}
+
+	// Merge the generations with the already compromised ones.
+	generations := append(slices.Clone(status.CompromisedGenerations), cs.Generations...)
+	slices.Sort(generations)
+	generations = slices.Compact(generations)
+
+	newStatus := *status
+	newStatus.CompromisedGenerations = generations
+	if len(newStatus.ChangedFields(status)) == 0 {
+		return nil
+	}
+
+	ctx.Logger().Warn("master secret generations marked as compromised",
+		"id", kmRt.ID,
+		"generations", cs.Generations,
+	)
+
+	if err = state.SetStatus(ctx, &newStatus); err != nil {
+		ctx.Logger().Error("keymanager: failed to set key manager status",
+			"err", err,
+		)
+		return fmt.Errorf("keymanager: failed to set key manager status: %w", err)
+	}
+
+	emitStatusUpdateEvent(ctx, appName, []*secrets.Status{status}, []*secrets.Status{&newStatus})
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
+	registryState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/registry/state"
+	"github.com/oasisprotocol/oasis-core/go/keymanager/secrets"
+	registryAPI "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+func TestMarkSecretsCompromised(t *testing.T) {
+	require := require.New(t)
+
+	// Prepare abci context.
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	regState := registryState.NewMutableState(ctx.State())
+
+	// Register a key manager runtime.
+	var kmID common.Namespace
+	err := kmID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000001")
+	require.NoError(err, "failed to unmarshal keymanager id")
+	kmRt := registryAPI.Runtime{
+		ID:          kmID,
+		Kind:        registryAPI.KindKeyManager,
+		TEEHardware: node.TEEHardwareIntelSGX,
+	}
+	err = regState.SetRuntime(ctx, &kmRt, false)
+	require.NoError(err, "registry.SetRuntime")
+
+	// Set the key manager status with 4 generations of the master secret.
+	status := &secrets.Status{
+		ID:            kmID,
+		IsInitialized: true,
+		IsSecure:      true,
+		Generation:    3,
+		Checksum:      []byte{1, 2, 3},
+	}
+	err = kmState.SetStatus(ctx, status)
+	require.NoError(err, "keymanager.SetStatus")
+
+	markCompromised := func(apply bool, generations ...uint64) error {
+		return MarkSecretsCompromised(ctx, "keymanager", &secrets.CompromisedSecrets{
+			ID:          kmID,
+			Generations: generations,
+		}, apply)
+	}
+
+	// Generations that have not been generated cannot be marked.
+	err = markCompromised(false, 4)
+	require.EqualError(err, "keymanager: sanity check failed: master secret generation 4 has not been generated")
+	err = markCompromised(true)
+	require.EqualError(err, "keymanager: sanity check failed: no compromised generations")
+
+	// Validation should not mark the generations.
+	err = markCompromised(false, 2, 0)
+	require.NoError(err, "MarkSecretsCompromised")
+	newStatus, err := kmState.Status(ctx, kmID)
+	require.NoError(err, "keymanager.Status")
+	require.Empty(newStatus.CompromisedGenerations, "validation should not mark generations")
+
+	// Compromised generations are merged with the already marked ones.
+	err = markCompromised(true, 2, 0)
+	require.NoError(err, "MarkSecretsCompromised")
+
+	var ev secrets.StatusUpdateEvent
+	require.NoError(ctx.DecodeEvent(0, &ev), "DecodeEvent")
+	require.Equal([]secrets.StatusChange{{Kind: secrets.StatusChangeSecretsCompromised}}, ev.Changes[kmID], "compromised generations should be reported as changed")
+
+	err = markCompromised(true, 2, 1)
+	require.NoError(err, "MarkSecretsCompromised")
+
+	newStatus, err = kmState.Status(ctx, kmID)
+	require.NoError(err, "keymanager.Status")
+	require.Equal([]uint64{0, 1, 2}, newStatus.CompromisedGenerations)
+	require.True(newStatus.IsGenerationCompromised(1))
+	require.False(newStatus.IsGenerationCompromised(3))
+
+	// The secret itself is not affected.
+	require.Equal(status.Generation, newStatus.Generation)
+	require.Equal(status.Checksum, newStatus.Checksum)
+}
@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	tmapi "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	"github.com/oasisprotocol/oasis-core/go/keymanager/secrets"
+)
+
+const (
+	opUpdatePolicy           = "update_policy"
+	opPublishMasterSecret    = "publish_master"
+	opPublishEphemeralSecret = "publish_ephemeral"
+	opRefreshStatus          = "refresh_status"
+
+	reasonInvalidRuntime     = "invalid_runtime"
+	reasonInvalidSigner      = "invalid_signer"
+	reasonNotCommittee       = "not_committee"
+	reasonNoCommittee        = "no_committee"
+	reasonAlreadyPublished   = "already_published"
+	reasonVerifyFailed       = "verify_failed"
+	reasonInvalidGeneration  = "invalid_generation"
+	reasonRotationNotAllowed = "rotation_not_allowed"
+	reasonProposalCooldown   = "proposal_cooldown"
+	reasonInsecureDisabled   = "insecure_disabled"
+	reasonNoCommitteeREKs    = "no_committee_reks"
+	reasonUnknownREK         = "unknown_rek"
+	reasonPolicyTooLarge     = "policy_too_large"
+
+	stageCheck   = "check"
+	stageDeliver = "deliver"
+)
+
+var (
+	rejectedTxTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_consensus_keymanager_rejected_tx_total",
+			Help: "Number of key manager transactions rejected by the secrets application.",
+		},
+		[]string{"operation", "reason", "stage"},
+	)
+
+	secretsCollectors = []prometheus.Collector{
+		rejectedTxTotal,
+	}
+
+	metricsOnce sync.Once
+)
+
+func initMetrics() {
+	metricsOnce.Do(func() {
+		prometheus.MustRegister(secretsCollectors...)
+	})
+}
+
+// rejectTx records the rejection of a transaction for the given operation and reason.
+//
+// Rejections in CheckTx are recorded under a separate stage, as most transactions rejected
+// there never get delivered. Rejections of simulated transactions are not recorded.
+func rejectTx(ctx *tmapi.Context, op, reason string) {
+	stage := stageDeliver
+	switch {
+	case ctx.IsSimulation():
+		return
+	case ctx.IsCheckOnly():
+		stage = stageCheck
+	}
+	rejectedTxTotal.With(prometheus.Labels{
+		"operation": op,
+		"reason":    reason,
+		"stage":     stage,
+	}).Inc()
+}
+
+// verifyFailedReason returns the rejection reason for a secret that failed verification,
+// qualified by the verification failure reason code, if known. Secrets that the committee
+// cannot decrypt are rejected with dedicated reasons.
+func verifyFailedReason(err error) string {
+	switch {
+	case errors.Is(err, secrets.ErrNoREKs):
+		return reasonNoCommitteeREKs
+	case errors.Is(err, secrets.ErrUnknownREK):
+		return reasonUnknownREK
+	}
+
+	var verr *secrets.VerifyError
+	if !errors.As(err, &verr) {
+		return reasonVerifyFailed
+	}
+	return reasonVerifyFailed + "_" + verr.Reason.String()
+}
@@ -1,34 +1,112 @@
 package secrets
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"time"
 
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
 	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	beaconState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/beacon/state"
 	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
+	registryState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/registry/state"
 	"github.com/oasisprotocol/oasis-core/go/keymanager/secrets"
 )
 
 // Query is the key manager query interface.
 type Query interface {
 	Status(context.Context, common.Namespace) (*secrets.Status, error)
+	InitializedStatus(context.Context, common.Namespace) (*secrets.Status, error)
 	Statuses(context.Context) ([]*secrets.Status, error)
+	HealthSummary(context.Context) (*secrets.HealthSummary, error)
 	MasterSecret(context.Context, common.Namespace) (*secrets.SignedEncryptedMasterSecret, error)
 	EphemeralSecret(context.Context, common.Namespace) (*secrets.SignedEncryptedEphemeralSecret, error)
 	Genesis(context.Context) (*secrets.Genesis, error)
+	RuntimeEncryptionKeys(context.Context, common.Namespace) ([]*secrets.RuntimeEncryptionKey, error)
+	StatusProjection(context.Context, common.Namespace) (*secrets.StatusProjection, error)
+	Policy(context.Context, common.Namespace) (*secrets.SignedPolicySGX, error)
+	AdmissionPreview(context.Context, common.Namespace, []signature.PublicKey) ([]*secrets.AdmissionRecord, error)
+	NodePolicyStatus(context.Context, common.Namespace, signature.PublicKey) (*secrets.NodePolicyStatus, error)
+	AdmissionRecords(context.Context, common.Namespace) ([]*secrets.AdmissionRecord, error)
+	ReplicationFailures(context.Context, common.Namespace) (*secrets.ReplicationFailures, error)
+	ConsensusParameters(context.Context) (*secrets.ConsensusParameters, error)
+	EpochTransition(context.Context) (*secrets.EpochTransition, error)
 }
 
 type querier struct {
-	state *secretsState.ImmutableState
+	state       *secretsState.ImmutableState
+	regState    *registryState.ImmutableState
+	beaconState *beaconState.ImmutableState
+	height      int64
+}
+
+// projectionContext is the context used to generate status projections.
+type projectionContext struct {
+	logger *logging.Logger
+	now    time.Time
+	height int64
+}
+
+func (pc *projectionContext) Logger() *logging.Logger {
+	return pc.logger
+}
+
+func (pc *projectionContext) Now() time.Time {
+	return pc.now
+}
+
+func (pc *projectionContext) BlockHeight() int64 {
+	return pc.height
 }
 
 func (kq *querier) Status(ctx context.Context, id common.Namespace) (*secrets.Status, error) {
 	return kq.state.Status(ctx, id)
 }
 
+func (kq *querier) InitializedStatus(ctx context.Context, id common.Namespace) (*secrets.Status, error) {
+	status, err := kq.state.Status(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !status.IsInitialized {
+		return nil, secrets.ErrKeyManagerNotInitialized
+	}
+	return status, nil
+}
+
 func (kq *querier) Statuses(ctx context.Context) ([]*secrets.Status, error) {
 	return kq.state.Statuses(ctx)
 }
 
+func (kq *querier) HealthSummary(ctx context.Context) (*secrets.HealthSummary, error) {
+	statuses, err := kq.state.Statuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary secrets.HealthSummary
+	for i, status := range statuses {
+		summary.Runtimes++
+		if status.IsInitialized {
+			summary.Initialized++
+		}
+		if status.IsSecure {
+			summary.Secure++
+		}
+		if status.RotationPending {
+			summary.PendingRotations++
+		}
+		if size := uint64(len(status.Nodes)); i == 0 || size < summary.MinCommitteeSize {
+			summary.MinCommitteeSize = size
+		}
+	}
+
+	return &summary, nil
+}
+
 func (kq *querier) MasterSecret(ctx context.Context, id common.Namespace) (*secrets.SignedEncryptedMasterSecret, error) {
 	return kq.state.MasterSecret(ctx, id)
 }
@@ -37,21 +115,257 @@ func (kq *querier) EphemeralSecret(ctx context.Context, id common.Namespace) (*s
 	return kq.state.EphemeralSecret(ctx, id)
 }
 
+func (kq *querier) Policy(ctx context.Context, id common.Namespace) (*secrets.SignedPolicySGX, error) {
+	status, err := kq.state.Status(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if status.Policy == nil {
+		return nil, secrets.ErrNoSuchPolicy
+	}
+
+	// Restore the description, which is stored alongside the status.
+	policy := *status.Policy
+	policy.Description = status.PolicyDescription
+	return &policy, nil
+}
+
+func (kq *querier) NodePolicyStatus(ctx context.Context, id common.Namespace, nodeID signature.PublicKey) (*secrets.NodePolicyStatus, error) {
+	kmRt, err := keyManagerRuntime(ctx, kq.regState, id)
+	if err != nil {
+		return nil, err
+	}
+	n, err := kq.regState.Node(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if !n.HasRuntime(kmRt.ID) {
+		return nil, fmt.Errorf("%w: node is not running the key manager runtime", secrets.ErrInvalidArgument)
+	}
+
+	var policy *secrets.SignedPolicySGX
+	status, err := kq.state.Status(ctx, kmRt.ID)
+	switch err {
+	case nil:
+		policy = status.Policy
+	case secrets.ErrNoSuchStatus:
+		// This must be a new key manager runtime, without a policy.
+	default:
+		return nil, err
+	}
+
+	params, err := kq.regState.ConsensusParameters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	kmParams, err := kq.state.ConsensusParameters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare the checksums the same way as when generating the status, so that the answer
+	// matches the admission decision.
+	checksumFn, err := secrets.GetPolicyChecksumFunc(kmParams.PolicyChecksumAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	policyHash := secrets.ComputePolicyHash(checksumFn, policy)
+	logger := logging.GetLogger("cometbft/keymanager/secrets/query")
+	ts := time.Now()
+	height := uint64(kq.height)
+
+	ps := &secrets.NodePolicyStatus{
+		PolicyChecksum: policyHash[:],
+	}
+	for _, nodeRt := range n.Runtimes {
+		if !nodeRt.ID.Equal(&kmRt.ID) {
+			continue
+		}
+		rsp, err := VerifyExtraInfo(logger, n.ID, kmRt, nodeRt, ts, height, params)
+		if err == nil {
+			if nodePolicyHash, ok := nodePolicyChecksum(checksumFn, rsp); ok && nodePolicyHash == policyHash {
+				continue
+			}
+		}
+		ps.StaleVersions = append(ps.StaleVersions, nodeRt.Version)
+	}
+	ps.IsCurrent = len(ps.StaleVersions) == 0
+
+	return ps, nil
+}
+
+func (kq *querier) AdmissionRecords(ctx context.Context, id common.Namespace) ([]*secrets.AdmissionRecord, error) {
+	return kq.state.AdmissionRecords(ctx, id)
+}
+
+func (kq *querier) ReplicationFailures(ctx context.Context, id common.Namespace) (*secrets.ReplicationFailures, error) {
+	return kq.state.ReplicationFailures(ctx, id)
+}
+
+func (kq *querier) ConsensusParameters(ctx context.Context) (*secrets.ConsensusParameters, error) {
+	return kq.state.ConsensusParameters(ctx)
+}
+
+func (kq *querier) EpochTransition(ctx context.Context) (*secrets.EpochTransition, error) {
+	epoch, height, err := kq.beaconState.GetEpoch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get epoch: %w", err)
+	}
+	future, err := kq.beaconState.GetFutureEpoch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get future epoch: %w", err)
+	}
+
+	et := secrets.EpochTransition{
+		Epoch:  epoch,
+		Height: height,
+	}
+
+	// The next transition is only known once the beacon backend has scheduled it.
+	if future != nil {
+		et.NextHeight = future.Height
+		if future.Height > kq.height {
+			et.BlocksRemaining = future.Height - kq.height
+		}
+	}
+
+	return &et, nil
+}
+
+func (kq *querier) RuntimeEncryptionKeys(ctx context.Context, id common.Namespace) ([]*secrets.RuntimeEncryptionKey, error) {
+	kmRt, err := keyManagerRuntime(ctx, kq.regState, id)
+	if err != nil {
+		return nil, err
+	}
+	kmStatus, err := kq.state.Status(ctx, kmRt.ID)
+	if err != nil {
+		return nil, err
+	}
+	return committeeEncryptionKeys(ctx, kq.regState, kmRt, kmStatus), nil
+}
+
+func (kq *querier) StatusProjection(ctx context.Context, id common.Namespace) (*secrets.StatusProjection, error) {
+	oldStatus, status, _, epoch, err := kq.projectStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secrets.StatusProjection{
+		Epoch:            epoch,
+		Status:           status,
+		RotationAccepted: !bytes.Equal(status.Checksum, oldStatus.Checksum),
+	}, nil
+}
+
+func (kq *querier) AdmissionPreview(ctx context.Context, id common.Namespace, nodeIDs []signature.PublicKey) ([]*secrets.AdmissionRecord, error) {
+	if len(nodeIDs) > secrets.MaxAdmissionPreviewNodes {
+		return nil, fmt.Errorf("%w: too many nodes (max: %d, got: %d)", secrets.ErrInvalidArgument, secrets.MaxAdmissionPreviewNodes, len(nodeIDs))
+	}
+
+	_, _, records, epoch, err := kq.projectStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions := make(map[signature.PublicKey]*secrets.AdmissionRecord, len(records))
+	for _, r := range records {
+		decisions[r.NodeID] = r
+	}
+
+	preview := make([]*secrets.AdmissionRecord, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		r, ok := decisions[nodeID]
+		if !ok {
+			// The node was not considered for the committee at all.
+			r = &secrets.AdmissionRecord{
+				Epoch:  epoch,
+				NodeID: nodeID,
+				Reason: secrets.AdmissionReasonNotCandidate,
+			}
+		}
+		preview = append(preview, r)
+	}
+	return preview, nil
+}
+
+// projectStatus generates the key manager status for the next epoch, as if the epoch
+// transition happened now, together with the admission decisions. The status is not stored.
+func (kq *querier) projectStatus(ctx context.Context, id common.Namespace) (*secrets.Status, *secrets.Status, []*secrets.AdmissionRecord, beacon.EpochTime, error) {
+	kmRt, err := keyManagerRuntime(ctx, kq.regState, id)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+
+	oldStatus, err := kq.state.Status(ctx, kmRt.ID)
+	switch err {
+	case nil:
+	case secrets.ErrNoSuchStatus:
+		// This must be a new key manager runtime.
+		oldStatus = &secrets.Status{
+			ID: kmRt.ID,
+		}
+	default:
+		return nil, nil, nil, 0, err
+	}
+
+	secret, err := kq.state.MasterSecret(ctx, kmRt.ID)
+	if err != nil && err != secrets.ErrNoSuchMasterSecret {
+		return nil, nil, nil, 0, err
+	}
+
+	nodes, err := kq.regState.Nodes(ctx)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+
+	params, err := kq.regState.ConsensusParameters(ctx)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+
+	kmParams, err := kq.state.ConsensusParameters(ctx)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+
+	epoch, _, err := kq.beaconState.GetEpoch(ctx)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+	nextEpoch := epoch + 1
+
+	pctx := &projectionContext{
+		logger: logging.GetLogger("cometbft/keymanager/secrets/projection"),
+		now:    time.Now(),
+		height: kq.height,
+	}
+	status, records := generateStatus(pctx, kmRt, oldStatus, secret, nodes, params, kmParams, nextEpoch, false)
+
+	return oldStatus, status, records, nextEpoch, nil
+}
+
 func (kq *querier) Genesis(ctx context.Context) (*secrets.Genesis, error) {
 	statuses, err := kq.state.Statuses(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Remove the Nodes field of each Status.
+	// Remove the Nodes and StandbyNodes fields of each Status.
 	for _, status := range statuses {
 		status.Nodes = nil
+		status.StandbyNodes = nil
 	}
 
 	gen := secrets.Genesis{Statuses: statuses}
 	return &gen, nil
 }
 
-func NewQuery(state *secretsState.ImmutableState) Query {
-	return &querier{state}
+func NewQuery(
+	state *secretsState.ImmutableState,
+	regState *registryState.ImmutableState,
+	beaconState *beaconState.ImmutableState,
+	height int64,
+) Query {
+	return &querier{state, regState, beaconState, height}
 }
@@ -0,0 +1,546 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
+	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	beaconState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/beacon/state"
+	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
+	registryState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/registry/state"
+	"github.com/oasisprotocol/oasis-core/go/keymanager/api"
+	"github.com/oasisprotocol/oasis-core/go/keymanager/secrets"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+func TestStatusProjection(t *testing.T) {
+	// Prepare context.
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	regState := registryState.NewMutableState(ctx.State())
+	beaconState := beaconState.NewMutableState(ctx.State())
+	query := NewQuery(kmState.ImmutableState, regState.ImmutableState, beaconState.ImmutableState, ctx.BlockHeight())
+
+	epoch := beacon.EpochTime(10)
+	err := beaconState.SetEpoch(ctx, epoch, ctx.BlockHeight())
+	require.NoError(t, err, "SetEpoch")
+
+	err = regState.SetConsensusParameters(ctx, &registry.ConsensusParameters{})
+	require.NoError(t, err, "registry.SetConsensusParameters")
+
+	err = kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{})
+	require.NoError(t, err, "keymanager.SetConsensusParameters")
+
+	// Register a key manager runtime.
+	var kmID common.Namespace
+	err = kmID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, err, "failed to unmarshal keymanager id")
+	kmRt := registry.Runtime{
+		ID:          kmID,
+		Kind:        registry.KindKeyManager,
+		TEEHardware: node.TEEHardwareInvalid,
+	}
+	err = regState.SetRuntime(ctx, &kmRt, false)
+	require.NoError(t, err, "registry.SetRuntime")
+
+	// Set the key manager status.
+	policy := secrets.SignedPolicySGX{
+		Policy: secrets.PolicySGX{
+			Serial: 1,
+			ID:     kmID,
+		},
+	}
+	policyChecksum := sha3.Sum256(cbor.Marshal(policy))
+	checksum := []byte{1, 2, 3, 4, 5}
+	nextChecksum := []byte{6, 7, 8, 9, 10}
+	status := &secrets.Status{
+		ID:            kmID,
+		IsInitialized: true,
+		IsSecure:      true,
+		Checksum:      checksum,
+		Policy:        &policy,
+	}
+	err = kmState.SetStatus(ctx, status)
+	require.NoError(t, err, "keymanager.SetStatus")
+
+	// Register a node that has replicated the proposal for the next master secret.
+	initResponse := secrets.InitResponse{
+		IsSecure:       true,
+		Checksum:       checksum,
+		NextChecksum:   nextChecksum,
+		PolicyChecksum: policyChecksum[:],
+	}
+	sigInitResponse, err := secrets.SignInitResponse(api.TestSigners[0], &initResponse)
+	require.NoError(t, err, "SignInitResponse")
+
+	signer := memorySigner.NewTestSigner("node 0")
+	nod := &node.Node{
+		Versioned:  cbor.NewVersioned(node.LatestNodeDescriptorVersion),
+		ID:         signer.Public(),
+		Expiration: uint64(epoch) + 1,
+		Roles:      node.RoleKeyManager,
+		Consensus: node.ConsensusInfo{
+			ID: signer.Public(),
+		},
+		Runtimes: []*node.Runtime{
+			{
+				ID:        kmID,
+				Version:   version.Version{Major: 1, Minor: 0, Patch: 0},
+				ExtraInfo: cbor.Marshal(sigInitResponse),
+			},
+		},
+	}
+	sigNode, err := node.MultiSignNode([]signature.Signer{signer}, registry.RegisterNodeSignatureContext, nod)
+	require.NoError(t, err, "node.MultiSignNode")
+	err = regState.SetNode(ctx, nil, nod, sigNode)
+	require.NoError(t, err, "registry.SetNode")
+
+	t.Run("No proposal", func(t *testing.T) {
+		require := require.New(t)
+
+		projection, err := query.StatusProjection(ctx, kmID)
+		require.NoError(err, "StatusProjection")
+		require.Equal(epoch+1, projection.Epoch, "projection should be for the next epoch")
+		require.False(projection.RotationAccepted, "there should be no rotation")
+		require.Equal([]signature.PublicKey{nod.ID}, projection.Status.Nodes)
+		require.Equal(checksum, projection.Status.Checksum)
+	})
+
+	t.Run("Pending proposal", func(t *testing.T) {
+		require := require.New(t)
+
+		secret := &secrets.SignedEncryptedMasterSecret{
+			Secret: secrets.EncryptedMasterSecret{
+				ID:         kmID,
+				Generation: 1,
+				Epoch:      epoch + 1,
+				Secret: secrets.EncryptedSecret{
+					Checksum: nextChecksum,
+				},
+			},
+		}
+		err := kmState.SetMasterSecret(ctx, secret)
+		require.NoError(err, "keymanager.SetMasterSecret")
+
+		projection, err := query.StatusProjection(ctx, kmID)
+		require.NoError(err, "StatusProjection")
+		require.True(projection.RotationAccepted, "rotation should be accepted")
+		require.Equal(uint64(1), projection.Status.Generation)
+		require.Equal(epoch+1, projection.Status.RotationEpoch)
+		require.Equal(nextChecksum, projection.Status.Checksum)
+
+		// The projection should not be written to state.
+		current, err := query.Status(ctx, kmID)
+		require.NoError(err, "Status")
+		require.Equal(status, current, "status should not change")
+	})
+
+	t.Run("Admission preview", func(t *testing.T) {
+		require := require.New(t)
+
+		unknownID := memorySigner.NewTestSigner("node 1").Public()
+		preview, err := query.AdmissionPreview(ctx, kmID, []signature.PublicKey{unknownID, nod.ID})
+		require.NoError(err, "AdmissionPreview")
+		require.Equal([]*secrets.AdmissionRecord{
+			{
+				Epoch:  epoch + 1,
+				NodeID: unknownID,
+				Reason: secrets.AdmissionReasonNotCandidate,
+			},
+			{
+				Epoch:    epoch + 1,
+				NodeID:   nod.ID,
+				Admitted: true,
+			},
+		}, preview, "decisions should be ordered as the queried nodes")
+
+		nodeIDs := make([]signature.PublicKey, secrets.MaxAdmissionPreviewNodes+1)
+		_, err = query.AdmissionPreview(ctx, kmID, nodeIDs)
+		require.ErrorIs(err, secrets.ErrInvalidArgument, "AdmissionPreview should fail for too many nodes")
+	})
+
+	t.Run("Invalid runtime", func(t *testing.T) {
+		require := require.New(t)
+
+		_, err := query.StatusProjection(ctx, common.Namespace{})
+		require.Error(err, "StatusProjection should fail for unknown runtimes")
+
+		_, err = query.AdmissionPreview(ctx, common.Namespace{}, nil)
+		require.Error(err, "AdmissionPreview should fail for unknown runtimes")
+	})
+}
+
+func TestHealthSummaryQuery(t *testing.T) {
+	require := require.New(t)
+
+	// Prepare context.
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	query := NewQuery(kmState.ImmutableState, nil, nil, ctx.BlockHeight())
+
+	// No key managers.
+	summary, err := query.HealthSummary(ctx)
+	require.NoError(err, "HealthSummary")
+	require.Equal(&secrets.HealthSummary{}, summary)
+
+	nodeIDs := []signature.PublicKey{
+		memorySigner.NewTestSigner("node 0").Public(),
+		memorySigner.NewTestSigner("node 1").Public(),
+	}
+	runtimeIDs := make([]common.Namespace, 4)
+	for i := range runtimeIDs {
+		runtimeIDs[i][0] = 0x80
+		runtimeIDs[i][31] = byte(i)
+	}
+	for _, status := range []*secrets.Status{
+		// Initialized and secure, with a pending proposal for the next master secret.
+		{
+			ID:                runtimeIDs[0],
+			IsInitialized:     true,
+			IsSecure:          true,
+			Generation:        1,
+			Checksum:          []byte{1, 2, 3},
+			RotationPending:   true,
+			PendingGeneration: 2,
+			PendingEpoch:      5,
+			Nodes:             nodeIDs,
+		},
+		// Initialized and secure, with an already replicated master secret.
+		{
+			ID:            runtimeIDs[1],
+			IsInitialized: true,
+			IsSecure:      true,
+			Checksum:      []byte{4, 5, 6},
+			Nodes:         nodeIDs[:1],
+		},
+		// Not yet initialized.
+		{
+			ID:    runtimeIDs[2],
+			Nodes: nodeIDs,
+		},
+		// Initialized and secure, with an expired proposal for the next master secret.
+		{
+			ID:            runtimeIDs[3],
+			IsInitialized: true,
+			IsSecure:      true,
+			Generation:    1,
+			Checksum:      []byte{7, 8, 9},
+			Nodes:         nodeIDs,
+		},
+	} {
+		err = kmState.SetStatus(ctx, status)
+		require.NoError(err, "keymanager.SetStatus")
+	}
+	for id, generation := range map[common.Namespace]uint64{
+		runtimeIDs[0]: 2,
+		runtimeIDs[1]: 0,
+		runtimeIDs[3]: 2,
+	} {
+		err = kmState.SetMasterSecret(ctx, &secrets.SignedEncryptedMasterSecret{
+			Secret: secrets.EncryptedMasterSecret{
+				ID:         id,
+				Generation: generation,
+			},
+		})
+		require.NoError(err, "keymanager.SetMasterSecret")
+	}
+
+	summary, err = query.HealthSummary(ctx)
+	require.NoError(err, "HealthSummary")
+	require.Equal(&secrets.HealthSummary{
+		Runtimes:         4,
+		Initialized:      3,
+		Secure:           3,
+		PendingRotations: 1,
+		MinCommitteeSize: 1,
+	}, summary)
+}
+
+func TestConsensusParametersQuery(t *testing.T) {
+	require := require.New(t)
+
+	// Prepare context.
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	query := NewQuery(kmState.ImmutableState, nil, nil, ctx.BlockHeight())
+
+	params := secrets.ConsensusParameters{
+		GasCosts: transaction.Costs{
+			secrets.GasOpUpdatePolicy: 1000,
+		},
+		NodeExpirationGracePeriod:  2,
+		MaxAdmissionRecords:        10,
+		DisableInsecureKeyManagers: true,
+	}
+	err := kmState.SetConsensusParameters(ctx, &params)
+	require.NoError(err, "keymanager.SetConsensusParameters")
+
+	queried, err := query.ConsensusParameters(ctx)
+	require.NoError(err, "ConsensusParameters")
+	require.Equal(&params, queried)
+}
+
+func TestEpochTransitionQuery(t *testing.T) {
+	require := require.New(t)
+
+	// Prepare context.
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	beaconState := beaconState.NewMutableState(ctx.State())
+	query := NewQuery(kmState.ImmutableState, nil, beaconState.ImmutableState, ctx.BlockHeight())
+
+	epoch := beacon.EpochTime(10)
+	err := beaconState.SetEpoch(ctx, epoch, ctx.BlockHeight())
+	require.NoError(err, "SetEpoch")
+
+	// No transition scheduled.
+	et, err := query.EpochTransition(ctx)
+	require.NoError(err, "EpochTransition")
+	require.Equal(&secrets.EpochTransition{
+		Epoch:  epoch,
+		Height: ctx.BlockHeight(),
+	}, et)
+
+	// Transition scheduled.
+	err = beaconState.SetFutureEpoch(ctx, epoch+1, ctx.BlockHeight()+25)
+	require.NoError(err, "SetFutureEpoch")
+
+	et, err = query.EpochTransition(ctx)
+	require.NoError(err, "EpochTransition")
+	require.Equal(&secrets.EpochTransition{
+		Epoch:           epoch,
+		Height:          ctx.BlockHeight(),
+		NextHeight:      ctx.BlockHeight() + 25,
+		BlocksRemaining: 25,
+	}, et)
+}
+
+func TestPolicyQuery(t *testing.T) {
+	require := require.New(t)
+
+	// Prepare context.
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	query := NewQuery(kmState.ImmutableState, nil, nil, ctx.BlockHeight())
+
+	var kmID common.Namespace
+	err := kmID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(err, "failed to unmarshal keymanager id")
+
+	// Key managers without a status have no policy.
+	_, err = query.Policy(ctx, kmID)
+	require.ErrorIs(err, secrets.ErrNoSuchStatus)
+
+	// Key managers without a policy.
+	status := secrets.Status{ID: kmID}
+	err = kmState.SetStatus(ctx, &status)
+	require.NoError(err, "SetStatus")
+	_, err = query.Policy(ctx, kmID)
+	require.ErrorIs(err, secrets.ErrNoSuchPolicy)
+
+	// Key managers with a policy.
+	status.Policy = &secrets.SignedPolicySGX{
+		Policy: secrets.PolicySGX{
+			Serial: 1,
+			ID:     kmID,
+		},
+	}
+	status.PolicyDescription = "description"
+	err = kmState.SetStatus(ctx, &status)
+	require.NoError(err, "SetStatus")
+
+	policy, err := query.Policy(ctx, kmID)
+	require.NoError(err, "Policy")
+	require.Equal(status.Policy.Policy, policy.Policy)
+	require.Equal("description", policy.Description)
+}
+
+func TestInitializedStatusQuery(t *testing.T) {
+	require := require.New(t)
+
+	// Prepare context.
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	query := NewQuery(kmState.ImmutableState, nil, nil, ctx.BlockHeight())
+
+	var kmID common.Namespace
+	err := kmID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(err, "failed to unmarshal keymanager id")
+
+	// Missing key managers.
+	_, err = query.InitializedStatus(ctx, kmID)
+	require.ErrorIs(err, secrets.ErrNoSuchStatus)
+
+	// Key managers that have not been initialized yet.
+	status := secrets.Status{ID: kmID}
+	err = kmState.SetStatus(ctx, &status)
+	require.NoError(err, "SetStatus")
+	_, err = query.InitializedStatus(ctx, kmID)
+	require.ErrorIs(err, secrets.ErrKeyManagerNotInitialized)
+
+	// Initialized key managers.
+	status.IsInitialized = true
+	err = kmState.SetStatus(ctx, &status)
+	require.NoError(err, "SetStatus")
+	st, err := query.InitializedStatus(ctx, kmID)
+	require.NoError(err, "InitializedStatus")
+	require.Equal(kmID, st.ID)
+	require.True(st.IsInitialized)
+}
+
+func TestNodePolicyStatusQuery(t *testing.T) {
+	require := require.New(t)
+
+	// Prepare context.
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	regState := registryState.NewMutableState(ctx.State())
+	query := NewQuery(kmState.ImmutableState, regState.ImmutableState, nil, ctx.BlockHeight())
+
+	err := regState.SetConsensusParameters(ctx, &registry.ConsensusParameters{})
+	require.NoError(err, "registry.SetConsensusParameters")
+
+	err = kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{})
+	require.NoError(err, "keymanager.SetConsensusParameters")
+
+	// Register a key manager runtime.
+	var kmID common.Namespace
+	err = kmID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(err, "failed to unmarshal keymanager id")
+	kmRt := registry.Runtime{
+		ID:          kmID,
+		Kind:        registry.KindKeyManager,
+		TEEHardware: node.TEEHardwareInvalid,
+	}
+	err = regState.SetRuntime(ctx, &kmRt, false)
+	require.NoError(err, "registry.SetRuntime")
+
+	// Set the key manager status.
+	oldPolicy := secrets.SignedPolicySGX{
+		Policy: secrets.PolicySGX{
+			Serial: 1,
+			ID:     kmID,
+		},
+	}
+	policy := oldPolicy
+	policy.Policy.Serial = 2
+	err = kmState.SetStatus(ctx, &secrets.Status{
+		ID:     kmID,
+		Policy: &policy,
+	})
+	require.NoError(err, "keymanager.SetStatus")
+
+	// Register a node running two versions, one of which is not up to date.
+	signedInitResponse := func(policy *secrets.SignedPolicySGX) []byte {
+		policyChecksum := sha3.Sum256(cbor.Marshal(policy))
+		sigInitResponse, err := secrets.SignInitResponse(api.TestSigners[0], &secrets.InitResponse{
+			PolicyChecksum: policyChecksum[:],
+		})
+		require.NoError(err, "SignInitResponse")
+		return cbor.Marshal(sigInitResponse)
+	}
+	registerNode := func(name string, runtimes ...*node.Runtime) *node.Node {
+		signer := memorySigner.NewTestSigner(name)
+		nod := &node.Node{
+			Versioned: cbor.NewVersioned(node.LatestNodeDescriptorVersion),
+			ID:        signer.Public(),
+			Roles:     node.RoleKeyManager,
+			Consensus: node.ConsensusInfo{
+				ID: signer.Public(),
+			},
+			Runtimes: runtimes,
+		}
+		sigNode, err := node.MultiSignNode([]signature.Signer{signer}, registry.RegisterNodeSignatureContext, nod)
+		require.NoError(err, "node.MultiSignNode")
+		err = regState.SetNode(ctx, nil, nod, sigNode)
+		require.NoError(err, "registry.SetNode")
+		return nod
+	}
+	v1 := version.Version{Major: 1}
+	v2 := version.Version{Major: 2}
+	nod := registerNode("node 0",
+		&node.Runtime{ID: kmID, Version: v1, ExtraInfo: signedInitResponse(&oldPolicy)},
+		&node.Runtime{ID: kmID, Version: v2, ExtraInfo: signedInitResponse(&policy)},
+	)
+	expPolicyChecksum := sha3.Sum256(cbor.Marshal(&policy))
+
+	ps, err := query.NodePolicyStatus(ctx, kmID, nod.ID)
+	require.NoError(err, "NodePolicyStatus")
+	require.Equal(&secrets.NodePolicyStatus{
+		PolicyChecksum: expPolicyChecksum[:],
+		IsCurrent:      false,
+		StaleVersions:  []version.Version{v1},
+	}, ps, "outdated versions should be reported")
+
+	// Nodes that reported the active policy are up to date.
+	nod1 := registerNode("node 1",
+		&node.Runtime{ID: kmID, Version: v2, ExtraInfo: signedInitResponse(&policy)},
+	)
+	ps, err = query.NodePolicyStatus(ctx, kmID, nod1.ID)
+	require.NoError(err, "NodePolicyStatus")
+	require.True(ps.IsCurrent, "node should be up to date")
+	require.Empty(ps.StaleVersions)
+
+	// Nodes without a verifiable ExtraInfo are not up to date.
+	nod = registerNode("node 2",
+		&node.Runtime{ID: kmID, Version: v2},
+	)
+	ps, err = query.NodePolicyStatus(ctx, kmID, nod.ID)
+	require.NoError(err, "NodePolicyStatus")
+	require.False(ps.IsCurrent, "node without ExtraInfo should not be up to date")
+
+	// Nodes not running the key manager.
+	nod = registerNode("node 3")
+	_, err = query.NodePolicyStatus(ctx, kmID, nod.ID)
+	require.ErrorIs(err, secrets.ErrInvalidArgument)
+
+	_, err = query.NodePolicyStatus(ctx, kmID, memorySigner.NewTestSigner("node 4").Public())
+	require.ErrorIs(err, registry.ErrNoSuchNode)
+
+	// Unsupported checksum algorithms should be reported.
+	err = kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{
+		PolicyChecksumAlgorithm: "sha512-256",
+	})
+	require.NoError(err, "keymanager.SetConsensusParameters")
+
+	_, err = query.NodePolicyStatus(ctx, kmID, nod1.ID)
+	require.EqualError(err, "keymanager: unsupported policy checksum algorithm: sha512-256")
+}
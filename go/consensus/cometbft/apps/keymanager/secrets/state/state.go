@@ -3,6 +3,7 @@ package state
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
@@ -30,6 +31,14 @@ var (
 	//
 	// Value is CBOR-serialized key manager signed encrypted ephemeral secret.
 	ephemeralSecretKeyFmt = consensus.KeyFormat.New(0x73, keyformat.H(&common.Namespace{}))
+	// admissionRecordsKeyFmt is the key manager committee admission records key format.
+	//
+	// Value is CBOR-serialized list of key manager committee admission records.
+	admissionRecordsKeyFmt = consensus.KeyFormat.New(0x74, keyformat.H(&common.Namespace{}))
+	// replicationFailuresKeyFmt is the key manager replication failures key format.
+	//
+	// Value is CBOR-serialized key manager replication failures of the most recent rotation.
+	replicationFailuresKeyFmt = consensus.KeyFormat.New(0x75, keyformat.H(&common.Namespace{}))
 )
 
 // ImmutableState is the immutable key manager state wrapper.
@@ -137,6 +146,41 @@ func (st *ImmutableState) EphemeralSecret(ctx context.Context, id common.Namespa
 	return &secret, nil
 }
 
+// AdmissionRecords returns the committee admission records of the key manager, oldest first.
+func (st *ImmutableState) AdmissionRecords(ctx context.Context, id common.Namespace) ([]*secrets.AdmissionRecord, error) {
+	data, err := st.is.Get(ctx, admissionRecordsKeyFmt.Encode(&id))
+	if err != nil {
+		return nil, abciAPI.UnavailableStateError(err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var records []*secrets.AdmissionRecord
+	if err := cbor.Unmarshal(data, &records); err != nil {
+		return nil, abciAPI.UnavailableStateError(err)
+	}
+	return records, nil
+}
+
+// ReplicationFailures returns the committee members that failed to replicate the most recently
+// accepted master secret rotation of the key manager.
+func (st *ImmutableState) ReplicationFailures(ctx context.Context, id common.Namespace) (*secrets.ReplicationFailures, error) {
+	data, err := st.is.Get(ctx, replicationFailuresKeyFmt.Encode(&id))
+	if err != nil {
+		return nil, abciAPI.UnavailableStateError(err)
+	}
+	if data == nil {
+		return nil, secrets.ErrNoSuchReplicationFailures
+	}
+
+	var failures secrets.ReplicationFailures
+	if err := cbor.Unmarshal(data, &failures); err != nil {
+		return nil, abciAPI.UnavailableStateError(err)
+	}
+	return &failures, nil
+}
+
 func NewImmutableState(ctx context.Context, state abciAPI.ApplicationQueryState, version int64) (*ImmutableState, error) {
 	is, err := abciAPI.NewImmutableState(ctx, state, version)
 	if err != nil {
@@ -178,6 +222,48 @@ func (st *MutableState) SetEphemeralSecret(ctx context.Context, secret *secrets.
 	return abciAPI.UnavailableStateError(err)
 }
 
+// RemoveEphemeralSecret removes the ephemeral secret of the key manager.
+func (st *MutableState) RemoveEphemeralSecret(ctx context.Context, id common.Namespace) error {
+	err := st.ms.Remove(ctx, ephemeralSecretKeyFmt.Encode(&id))
+	return abciAPI.UnavailableStateError(err)
+}
+
+// AppendAdmissionRecords appends the given committee admission records of the key manager,
+// keeping at most maxRecords most recent records. Records replace the existing records
+// of the same node in the same epoch, so that regenerating the status within an epoch
+// doesn't duplicate them.
+//
+// If maxRecords is zero, all records of the key manager are removed.
+func (st *MutableState) AppendAdmissionRecords(ctx context.Context, id common.Namespace, records []*secrets.AdmissionRecord, maxRecords uint64) error {
+	if maxRecords == 0 {
+		err := st.ms.Remove(ctx, admissionRecordsKeyFmt.Encode(&id))
+		return abciAPI.UnavailableStateError(err)
+	}
+
+	existing, err := st.AdmissionRecords(ctx, id)
+	if err != nil {
+		return err
+	}
+	existing = slices.DeleteFunc(existing, func(e *secrets.AdmissionRecord) bool {
+		return slices.ContainsFunc(records, func(r *secrets.AdmissionRecord) bool {
+			return r.Epoch == e.Epoch && r.NodeID.Equal(e.NodeID)
+		})
+	})
+	existing = append(existing, records...)
+	if n := uint64(len(existing)); n > maxRecords {
+		existing = existing[n-maxRecords:]
+	}
+
+	err = st.ms.Insert(ctx, admissionRecordsKeyFmt.Encode(&id), cbor.Marshal(existing))
+	return abciAPI.UnavailableStateError(err)
+}
+
+// SetReplicationFailures replaces the replication failures of the key manager.
+func (st *MutableState) SetReplicationFailures(ctx context.Context, failures *secrets.ReplicationFailures) error {
+	err := st.ms.Insert(ctx, replicationFailuresKeyFmt.Encode(&failures.ID), cbor.Marshal(failures))
+	return abciAPI.UnavailableStateError(err)
+}
+
 // NewMutableState creates a new mutable key manager state wrapper.
 func NewMutableState(tree mkvs.KeyValueTree) *MutableState {
 	return &MutableState{
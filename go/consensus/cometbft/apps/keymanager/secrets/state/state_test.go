@@ -1,6 +1,7 @@
 package state
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -91,4 +92,83 @@ func TestEphemeralSecret(t *testing.T) {
 	}
 	_, err := s.EphemeralSecret(ctx, common.Namespace{1, 2, 3})
 	require.EqualError(err, secrets.ErrNoSuchEphemeralSecret.Error(), "EphemeralSecret should error for non-existing secrets")
+
+	// Test removing secrets.
+	err = s.RemoveEphemeralSecret(ctx, runtimes[0])
+	require.NoError(err, "RemoveEphemeralSecret()")
+	_, err = s.EphemeralSecret(ctx, runtimes[0])
+	require.EqualError(err, secrets.ErrNoSuchEphemeralSecret.Error(), "EphemeralSecret should error for removed secrets")
+	secret, err := s.EphemeralSecret(ctx, runtimes[1])
+	require.NoError(err, "EphemeralSecret()")
+	require.Equal(masterSecrets[9], secret, "secrets of other runtimes should be kept")
+}
+
+func TestAdmissionRecords(t *testing.T) {
+	require := require.New(t)
+
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextBeginBlock)
+	defer ctx.Close()
+
+	s := NewMutableState(ctx.State())
+
+	// Prepare data.
+	runtimes := []common.Namespace{
+		common.NewTestNamespaceFromSeed([]byte("runtime 1"), common.NamespaceKeyManager),
+		common.NewTestNamespaceFromSeed([]byte("runtime 2"), common.NamespaceKeyManager),
+	}
+	records := make([]*secrets.AdmissionRecord, 0, 10)
+	for i := 0; i < cap(records); i++ {
+		records = append(records, &secrets.AdmissionRecord{
+			Epoch:    beacon.EpochTime(i),
+			Admitted: i%2 == 0,
+		})
+	}
+
+	// Test querying missing records.
+	stored, err := s.AdmissionRecords(ctx, runtimes[0])
+	require.NoError(err, "AdmissionRecords()")
+	require.Empty(stored, "there should be no records")
+
+	// Test appending records.
+	err = s.AppendAdmissionRecords(ctx, runtimes[0], records[:4], 5)
+	require.NoError(err, "AppendAdmissionRecords()")
+	err = s.AppendAdmissionRecords(ctx, runtimes[1], records[:1], 5)
+	require.NoError(err, "AppendAdmissionRecords()")
+
+	stored, err = s.AdmissionRecords(ctx, runtimes[0])
+	require.NoError(err, "AdmissionRecords()")
+	require.Equal(records[:4], stored, "all records should be kept")
+
+	// Test that only the most recent records are kept.
+	err = s.AppendAdmissionRecords(ctx, runtimes[0], records[4:], 5)
+	require.NoError(err, "AppendAdmissionRecords()")
+
+	stored, err = s.AdmissionRecords(ctx, runtimes[0])
+	require.NoError(err, "AdmissionRecords()")
+	require.Equal(records[5:], stored, "only the most recent records should be kept")
+
+	stored, err = s.AdmissionRecords(ctx, runtimes[1])
+	require.NoError(err, "AdmissionRecords()")
+	require.Equal(records[:1], stored, "records of other runtimes should not be affected")
+
+	// Test that records replace the records of the same node in the same epoch.
+	replaced := &secrets.AdmissionRecord{
+		Epoch:  records[9].Epoch,
+		Reason: secrets.AdmissionReasonExpired,
+	}
+	err = s.AppendAdmissionRecords(ctx, runtimes[0], []*secrets.AdmissionRecord{replaced}, 5)
+	require.NoError(err, "AppendAdmissionRecords()")
+
+	stored, err = s.AdmissionRecords(ctx, runtimes[0])
+	require.NoError(err, "AdmissionRecords()")
+	require.Equal(append(slices.Clone(records[5:9]), replaced), stored, "records of the same node and epoch should be replaced")
+
+	// Test that disabling the records removes them.
+	err = s.AppendAdmissionRecords(ctx, runtimes[0], records, 0)
+	require.NoError(err, "AppendAdmissionRecords()")
+
+	stored, err = s.AdmissionRecords(ctx, runtimes[0])
+	require.NoError(err, "AdmissionRecords()")
+	require.Empty(stored, "records should be removed")
 }
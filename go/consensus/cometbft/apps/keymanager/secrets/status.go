@@ -2,17 +2,20 @@ package secrets
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/hex"
 	"fmt"
+	"maps"
+	"slices"
 	"time"
 
-	"golang.org/x/crypto/sha3"
-
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
 	tmapi "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
 	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
 	registryState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/registry/state"
@@ -25,7 +28,36 @@ import (
 // that must replicate the proposal for the next master secret before it is accepted.
 const minProposalReplicationPercent = 66
 
-var emptyHashSha3 = sha3.Sum256(nil)
+// lastSeenRetentionEpochs is the number of epochs for which nodes that dropped out of the key
+// manager committee are retained in the last seen epochs of the status.
+const lastSeenRetentionEpochs = 5
+
+// statusContext is the context needed to generate a key manager status.
+type statusContext interface {
+	// Logger returns the logger used to report rejected nodes.
+	Logger() *logging.Logger
+
+	// Now returns the time used to verify node attestations.
+	Now() time.Time
+
+	// BlockHeight returns the height used to verify node attestations.
+	BlockHeight() int64
+}
+
+// nodeAdmission is the state of a key manager node accumulated while verifying the versions
+// of the key manager runtime the node is running.
+type nodeAdmission struct {
+	isInitialized    bool
+	isSecure         bool
+	rsk              *signature.PublicKey
+	nextRSK          *signature.PublicKey
+	secretReplicated bool
+	rejectReason     string
+}
+
+// versionVerifier verifies the given version of the key manager runtime run by a node
+// and updates the node's admission state accordingly.
+type versionVerifier func(n *node.Node, nodeRt *node.Runtime, ns *nodeAdmission) bool
 
 func (ext *secretsExt) onEpochChange(ctx *tmapi.Context, epoch beacon.EpochTime) error {
 	// Query the runtime and node lists.
@@ -42,59 +74,26 @@ func (ext *secretsExt) onEpochChange(ctx *tmapi.Context, epoch beacon.EpochTime)
 	// Recalculate all the key manager statuses.
 	//
 	// Note: This assumes that once a runtime is registered, it never expires.
-	var toEmit []*secrets.Status
+	var oldStatuses, newStatuses []*secrets.Status
 	state := secretsState.NewMutableState(ctx.State())
+
+	kmParams, err := state.ConsensusParameters(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get key manager consensus parameters: %w", err)
+	}
+
 	for _, rt := range runtimes {
 		if rt.Kind != registry.KindKeyManager {
 			continue
 		}
 
-		var forceEmit bool
-		oldStatus, err := state.Status(ctx, rt.ID)
-		switch err {
-		case nil:
-		case secrets.ErrNoSuchStatus:
-			// This must be a new key manager runtime.
-			forceEmit = true
-			oldStatus = &secrets.Status{
-				ID: rt.ID,
-			}
-		default:
-			// This is fatal, as it suggests state corruption.
-			ctx.Logger().Error("failed to query key manager status",
-				"id", rt.ID,
-				"err", err,
-			)
-			return fmt.Errorf("failed to query key manager status: %w", err)
+		oldStatus, newStatus, err := ext.updateStatus(ctx, state, rt, nodes, params, kmParams, epoch)
+		if err != nil {
+			return err
 		}
-
-		secret, err := state.MasterSecret(ctx, rt.ID)
-		if err != nil && err != secrets.ErrNoSuchMasterSecret {
-			ctx.Logger().Error("failed to query key manager master secret",
-				"id", rt.ID,
-				"err", err,
-			)
-			return fmt.Errorf("failed to query key manager master secret: %w", err)
-		}
-
-		newStatus := generateStatus(ctx, rt, oldStatus, secret, nodes, params, epoch)
-		if forceEmit || !bytes.Equal(cbor.Marshal(oldStatus), cbor.Marshal(newStatus)) {
-			ctx.Logger().Debug("status updated",
-				"id", newStatus.ID,
-				"is_initialized", newStatus.IsInitialized,
-				"is_secure", newStatus.IsSecure,
-				"generation", newStatus.Generation,
-				"rotation_epoch", newStatus.RotationEpoch,
-				"checksum", hex.EncodeToString(newStatus.Checksum),
-				"rsk", newStatus.RSK,
-				"nodes", newStatus.Nodes,
-			)
-
-			// Set, enqueue for emit.
-			if err = state.SetStatus(ctx, newStatus); err != nil {
-				return fmt.Errorf("failed to set key manager status: %w", err)
-			}
-			toEmit = append(toEmit, newStatus)
+		if newStatus != nil {
+			oldStatuses = append(oldStatuses, oldStatus)
+			newStatuses = append(newStatuses, newStatus)
 		}
 	}
 
@@ -102,32 +101,277 @@ func (ext *secretsExt) onEpochChange(ctx *tmapi.Context, epoch beacon.EpochTime)
 	// but as runtime registrations last forever, so this shouldn't be possible.
 
 	// Emit the update event if required.
-	if len(toEmit) > 0 {
-		ctx.EmitEvent(tmapi.NewEventBuilder(ext.appName).TypedAttribute(&secrets.StatusUpdateEvent{
-			Statuses: toEmit,
-		}))
-	}
+	emitStatusUpdateEvent(ctx, ext.appName, oldStatuses, newStatuses)
 
 	return nil
 }
 
-func generateStatus( // nolint: gocyclo
+// updateStatus regenerates the status of the key manager from the given node list, stores it
+// together with the admission records, and emits the initialization and replication events.
+// It returns the old and the new status if the status should be included in a status
+// update event, or nils otherwise.
+//
+// Statuses are updated the same way on epoch transitions and on refresh requests, so that
+// refreshing the status never diverges from the next epoch transition.
+func (ext *secretsExt) updateStatus(
 	ctx *tmapi.Context,
+	state *secretsState.MutableState,
+	rt *registry.Runtime,
+	nodes []*node.Node,
+	params *registry.ConsensusParameters,
+	kmParams *secrets.ConsensusParameters,
+	epoch beacon.EpochTime,
+) (*secrets.Status, *secrets.Status, error) {
+	var forceEmit bool
+	oldStatus, err := state.Status(ctx, rt.ID)
+	switch err {
+	case nil:
+	case secrets.ErrNoSuchStatus:
+		// This must be a new key manager runtime.
+		forceEmit = true
+		oldStatus = &secrets.Status{
+			ID: rt.ID,
+		}
+	default:
+		// This is fatal, as it suggests state corruption.
+		ctx.Logger().Error("failed to query key manager status",
+			"id", rt.ID,
+			"err", err,
+		)
+		return nil, nil, fmt.Errorf("failed to query key manager status: %w", err)
+	}
+
+	secret, err := state.MasterSecret(ctx, rt.ID)
+	if err != nil && err != secrets.ErrNoSuchMasterSecret {
+		ctx.Logger().Error("failed to query key manager master secret",
+			"id", rt.ID,
+			"err", err,
+		)
+		return nil, nil, fmt.Errorf("failed to query key manager master secret: %w", err)
+	}
+
+	newStatus, records := generateStatus(ctx, rt, oldStatus, secret, nodes, params, kmParams, epoch, false)
+	if err = state.AppendAdmissionRecords(ctx, rt.ID, notableAdmissionRecords(oldStatus, records), kmParams.MaxAdmissionRecords); err != nil {
+		return nil, nil, fmt.Errorf("failed to append key manager admission records: %w", err)
+	}
+
+	var emitted *secrets.Status
+	changed := newStatus.ChangedFields(oldStatus)
+	switch {
+	case forceEmit || len(changed) > 0:
+		ctx.Logger().Debug("status updated",
+			"id", newStatus.ID,
+			"changed", changed,
+			"is_initialized", newStatus.IsInitialized,
+			"is_secure", newStatus.IsSecure,
+			"generation", newStatus.Generation,
+			"rotation_epoch", newStatus.RotationEpoch,
+			"checksum", hex.EncodeToString(newStatus.Checksum),
+			"rsk", newStatus.RSK,
+			"nodes", newStatus.Nodes,
+		)
+
+		// Set, enqueue for emit.
+		if err = state.SetStatus(ctx, newStatus); err != nil {
+			return nil, nil, fmt.Errorf("failed to set key manager status: %w", err)
+		}
+		emitted = newStatus
+	case !maps.Equal(newStatus.LastSeenEpochs, oldStatus.LastSeenEpochs):
+		// Keep the last seen epochs up to date without emitting an update.
+		if err = state.SetStatus(ctx, newStatus); err != nil {
+			return nil, nil, fmt.Errorf("failed to set key manager status: %w", err)
+		}
+	}
+
+	emitInitializedEvent(ctx, ext.appName, oldStatus, newStatus)
+	emitReplicationQuorumReachedEvent(ctx, ext.appName, oldStatus, newStatus)
+	emitGenerationLimitExceededEvent(ctx, ext.appName, oldStatus, newStatus, kmParams.MaxRetainedGenerations)
+
+	if err = setReplicationFailures(ctx, state, oldStatus, newStatus, records); err != nil {
+		return nil, nil, fmt.Errorf("failed to set key manager replication failures: %w", err)
+	}
+	if err = pruneEphemeralSecret(ctx, state, newStatus, epoch); err != nil {
+		return nil, nil, fmt.Errorf("failed to prune key manager ephemeral secret: %w", err)
+	}
+
+	if emitted == nil {
+		return nil, nil, nil
+	}
+	return oldStatus, emitted, nil
+}
+
+// emitStatusUpdateEvent emits the status update event for the given updated statuses,
+// together with their changes relative to the given old statuses, if any.
+func emitStatusUpdateEvent(ctx *tmapi.Context, appName string, oldStatuses, newStatuses []*secrets.Status) {
+	if len(newStatuses) == 0 {
+		return
+	}
+
+	changes := make(map[common.Namespace][]secrets.StatusChange)
+	for i, newStatus := range newStatuses {
+		changes[newStatus.ID] = newStatus.Diff(oldStatuses[i])
+	}
+
+	ctx.EmitEvent(tmapi.NewEventBuilder(appName).TypedAttribute(&secrets.StatusUpdateEvent{
+		Statuses: newStatuses,
+		Changes:  changes,
+	}))
+}
+
+// emitInitializedEvent emits the initialized event if the key manager has just been initialized.
+func emitInitializedEvent(ctx *tmapi.Context, appName string, oldStatus, newStatus *secrets.Status) {
+	if oldStatus.IsInitialized || !newStatus.IsInitialized {
+		return
+	}
+	ctx.EmitEvent(tmapi.NewEventBuilder(appName).TypedAttribute(&secrets.InitializedEvent{
+		ID:       newStatus.ID,
+		IsSecure: newStatus.IsSecure,
+	}))
+}
+
+// emitReplicationQuorumReachedEvent emits the replication quorum reached event if the proposal
+// for the next master secret has been accepted.
+func emitReplicationQuorumReachedEvent(ctx *tmapi.Context, appName string, oldStatus, newStatus *secrets.Status) {
+	if bytes.Equal(oldStatus.Checksum, newStatus.Checksum) {
+		return
+	}
+	ctx.EmitEvent(tmapi.NewEventBuilder(appName).TypedAttribute(&secrets.ReplicationQuorumReachedEvent{
+		ID:              newStatus.ID,
+		Generation:      newStatus.Generation,
+		ReplicatedNodes: uint64(len(newStatus.Nodes) + len(newStatus.StandbyNodes)),
+	}))
+}
+
+// notableAdmissionRecords returns the admission records worth persisting, i.e. rejections and
+// admissions of nodes that were not yet members of the committee, as the routine admissions
+// of the existing members would quickly push the other records out of state.
+func notableAdmissionRecords(oldStatus *secrets.Status, records []*secrets.AdmissionRecord) []*secrets.AdmissionRecord {
+	return slices.DeleteFunc(slices.Clone(records), func(r *secrets.AdmissionRecord) bool {
+		return r.Admitted && slices.ContainsFunc(oldStatus.Nodes, r.NodeID.Equal)
+	})
+}
+
+// setReplicationFailures records the committee members that were dropped from the committee
+// because they failed to replicate the accepted proposal for the next master secret.
+// The record is kept until the next rotation is accepted.
+func setReplicationFailures(ctx *tmapi.Context, state *secretsState.MutableState, oldStatus, newStatus *secrets.Status, records []*secrets.AdmissionRecord) error {
+	if bytes.Equal(oldStatus.Checksum, newStatus.Checksum) {
+		return nil
+	}
+
+	failures := &secrets.ReplicationFailures{
+		ID:         newStatus.ID,
+		Generation: newStatus.Generation,
+		Epoch:      newStatus.RotationEpoch,
+	}
+	for _, r := range records {
+		if r.Reason != secrets.AdmissionReasonNotReplicated {
+			continue
+		}
+		if !slices.ContainsFunc(oldStatus.Nodes, r.NodeID.Equal) {
+			continue
+		}
+		failures.Nodes = append(failures.Nodes, r.NodeID)
+	}
+
+	return state.SetReplicationFailures(ctx, failures)
+}
+
+// emitGenerationLimitExceededEvent emits the generation limit exceeded event if the proposal
+// for the next master secret has been accepted and the number of retained generations exceeds
+// the given limit, where zero means unlimited.
+func emitGenerationLimitExceededEvent(ctx *tmapi.Context, appName string, oldStatus, newStatus *secrets.Status, limit uint64) {
+	if limit == 0 || bytes.Equal(oldStatus.Checksum, newStatus.Checksum) {
+		return
+	}
+	generations := newStatus.Generation + 1
+	if generations <= limit {
+		return
+	}
+
+	ctx.Logger().Warn("key manager exceeds the retained master secret generation limit",
+		"id", newStatus.ID,
+		"generations", generations,
+		"limit", limit,
+	)
+
+	ctx.EmitEvent(tmapi.NewEventBuilder(appName).TypedAttribute(&secrets.GenerationLimitExceededEvent{
+		ID:          newStatus.ID,
+		Generations: generations,
+		Limit:       limit,
+	}))
+}
+
+// pruneEphemeralSecret removes the ephemeral secret of the key manager once it is older than
+// the maximum ephemeral secret age defined in the policy, so that stale secrets don't remain
+// in the state forever. Secrets are never removed if the policy doesn't define the age.
+func pruneEphemeralSecret(ctx *tmapi.Context, state *secretsState.MutableState, status *secrets.Status, epoch beacon.EpochTime) error {
+	if status.Policy == nil || status.Policy.Policy.MaxEphemeralSecretAge == 0 {
+		return nil
+	}
+	maxAge := status.Policy.Policy.MaxEphemeralSecretAge
+
+	secret, err := state.EphemeralSecret(ctx, status.ID)
+	switch err {
+	case nil:
+	case secrets.ErrNoSuchEphemeralSecret:
+		return nil
+	default:
+		return err
+	}
+	if secret.Secret.Epoch >= epoch || epoch-secret.Secret.Epoch <= maxAge {
+		return nil
+	}
+
+	ctx.Logger().Debug("pruning ephemeral secret",
+		"id", status.ID,
+		"epoch", secret.Secret.Epoch,
+	)
+
+	return state.RemoveEphemeralSecret(ctx, status.ID)
+}
+
+func generateStatus(
+	ctx statusContext,
 	kmrt *registry.Runtime,
 	oldStatus *secrets.Status,
 	secret *secrets.SignedEncryptedMasterSecret,
 	nodes []*node.Node,
 	params *registry.ConsensusParameters,
+	kmParams *secrets.ConsensusParameters,
 	epoch beacon.EpochTime,
-) *secrets.Status {
+	breakFreeze bool,
+) (*secrets.Status, []*secrets.AdmissionRecord) {
+	return generateStatusWithVerifier(ctx, kmrt, oldStatus, secret, nodes, params, kmParams, epoch, breakFreeze, nil)
+}
+
+// generateStatusWithVerifier is generateStatus with the verification of key manager runtime
+// versions replaced by the given verifier, if not nil, so that tests can control the admission
+// of nodes deterministically.
+func generateStatusWithVerifier( // nolint: gocyclo
+	ctx statusContext,
+	kmrt *registry.Runtime,
+	oldStatus *secrets.Status,
+	secret *secrets.SignedEncryptedMasterSecret,
+	nodes []*node.Node,
+	params *registry.ConsensusParameters,
+	kmParams *secrets.ConsensusParameters,
+	epoch beacon.EpochTime,
+	breakFreeze bool,
+	verifier versionVerifier,
+) (*secrets.Status, []*secrets.AdmissionRecord) {
 	status := &secrets.Status{
-		ID:            kmrt.ID,
-		IsInitialized: oldStatus.IsInitialized,
-		IsSecure:      oldStatus.IsSecure,
-		Generation:    oldStatus.Generation,
-		RotationEpoch: oldStatus.RotationEpoch,
-		Checksum:      oldStatus.Checksum,
-		Policy:        oldStatus.Policy,
+		ID:                kmrt.ID,
+		IsInitialized:     oldStatus.IsInitialized,
+		IsSecure:          oldStatus.IsSecure,
+		Generation:        oldStatus.Generation,
+		RotationEpoch:     oldStatus.RotationEpoch,
+		Checksum:          oldStatus.Checksum,
+		Policy:            oldStatus.Policy,
+		PolicyDescription: oldStatus.PolicyDescription,
+
+		CompromisedGenerations: oldStatus.CompromisedGenerations,
+		CommitteeChanges:       oldStatus.CommitteeChanges,
 	}
 
 	// Data needed to count the nodes that have replicated the proposal for the next master secret.
@@ -137,143 +381,317 @@ func generateStatus( // nolint: gocyclo
 		nextRSK        *signature.PublicKey
 		updatedNodes   []signature.PublicKey
 	)
+
+	// Number of nodes that reported a verifiable ExtraInfo, used to track the progress
+	// of the initialization.
+	var (
+		numReported uint64
+		reported    bool
+	)
 	nextGeneration = status.NextGeneration()
 	if secret != nil && secret.Secret.Generation == nextGeneration && secret.Secret.Epoch == epoch {
 		nextChecksum = secret.Secret.Secret.Checksum
 	}
 
-	// Compute the policy hash to reject nodes that are not up-to-date.
-	var rawPolicy []byte
-	if status.Policy != nil {
-		rawPolicy = cbor.Marshal(status.Policy)
+	// Compute the policy hash to reject nodes that are not up-to-date. The algorithm is
+	// validated when the parameters are set, so an unsupported algorithm suggests state
+	// corruption, in which case no node can be verified.
+	var policyHash [secrets.ChecksumSize]byte
+	checksumFn, err := secrets.GetPolicyChecksumFunc(kmParams.PolicyChecksumAlgorithm)
+	if err != nil {
+		ctx.Logger().Error("failed to get policy checksum function",
+			"id", kmrt.ID,
+			"err", err,
+		)
+	} else {
+		policyHash = secrets.ComputePolicyHash(checksumFn, status.Policy)
 	}
-	policyHash := sha3.Sum256(rawPolicy)
 
 	ts := ctx.Now()
 	height := uint64(ctx.BlockHeight())
 
-	// Construct a key manager committee. A node is added to the committee if it supports
-	// at least one version of the key manager runtime and if all supported versions conform
-	// to the key manager status fields.
-nextNode:
-	for _, n := range nodes {
-		if n.IsExpired(uint64(epoch)) {
-			continue
+	// Nodes running multiple versions of the key manager runtime can be admitted as long as
+	// one of the versions conforms, if the policy allows it.
+	var anyVersion bool
+	if status.Policy != nil {
+		anyVersion = status.Policy.Policy.AdmitAnyConformingVersion
+	}
+
+	// verifyVersion verifies that the given version of the key manager runtime conforms
+	// to the key manager status fields and updates the node's admission state accordingly.
+	var verifyVersion versionVerifier = func(n *node.Node, nodeRt *node.Runtime, ns *nodeAdmission) bool {
+		vars := []interface{}{
+			"id", kmrt.ID,
+			"node_id", n.ID,
+			"version", nodeRt.Version.String(),
 		}
-		if !n.HasRoles(node.RoleKeyManager) {
-			continue
+
+		if err := verifyKeyManagerSecurity(kmrt, kmParams); err != nil {
+			ctx.Logger().Error("insecure key managers are disabled", vars...)
+			ns.rejectReason = secrets.AdmissionReasonInsecureDisabled
+			return false
 		}
 
-		secretReplicated := true
-		isInitialized := status.IsInitialized
-		isSecure := status.IsSecure
-		RSK := status.RSK
-		nRSK := nextRSK
+		var teeOk bool
+		if nodeRt.Capabilities.TEE == nil {
+			teeOk = kmrt.TEEHardware == node.TEEHardwareInvalid
+		} else {
+			teeOk = kmrt.TEEHardware == nodeRt.Capabilities.TEE.Hardware
+		}
+		if !teeOk {
+			ctx.Logger().Error("TEE hardware mismatch", vars...)
+			ns.rejectReason = secrets.AdmissionReasonTEEMismatch
+			return false
+		}
 
-		var numVersions int
-		for _, nodeRt := range n.Runtimes {
-			if !nodeRt.ID.Equal(&kmrt.ID) {
-				continue
+		// Skip versions other than the active deployment, if enforced. Note that nodes
+		// upgrading to the next deployment need the policy to admit any conforming version.
+		if kmParams.EnforceActiveDeployment {
+			if active := kmrt.ActiveDeployment(epoch); active == nil || active.Version != nodeRt.Version {
+				ctx.Logger().Error("runtime version is not the active deployment", vars...)
+				ns.rejectReason = secrets.AdmissionReasonStaleVersion
+				return false
 			}
+		}
 
-			vars := []interface{}{
-				"id", kmrt.ID,
-				"node_id", n.ID,
-				"version", nodeRt.Version,
-			}
+		// Skip versions below the minimum required by the policy.
+		if status.Policy != nil && !status.Policy.Policy.IsVersionAllowed(nodeRt.Version) {
+			ctx.Logger().Error("runtime version is below the policy minimum", vars...)
+			ns.rejectReason = secrets.AdmissionReasonVersionTooOld
+			return false
+		}
 
-			var teeOk bool
-			if nodeRt.Capabilities.TEE == nil {
-				teeOk = kmrt.TEEHardware == node.TEEHardwareInvalid
-			} else {
-				teeOk = kmrt.TEEHardware == nodeRt.Capabilities.TEE.Hardware
-			}
-			if !teeOk {
-				ctx.Logger().Error("TEE hardware mismatch", vars...)
-				continue nextNode
-			}
+		// Skip secure nodes that cannot receive encrypted secrets.
+		if kmrt.TEEHardware != node.TEEHardwareInvalid && nodeRt.Capabilities.TEE.REK == nil {
+			ctx.Logger().Error("missing runtime encryption key", vars...)
+			ns.rejectReason = secrets.AdmissionReasonMissingREK
+			return false
+		}
 
-			initResponse, err := VerifyExtraInfo(ctx.Logger(), n.ID, kmrt, nodeRt, ts, height, params)
-			if err != nil {
-				ctx.Logger().Error("failed to validate ExtraInfo", append(vars, "err", err)...)
-				continue nextNode
-			}
+		initResponse, err := VerifyExtraInfo(ctx.Logger(), n.ID, kmrt, nodeRt, ts, height, params)
+		if err != nil {
+			ctx.Logger().Error("failed to validate ExtraInfo", append(vars, "err", err)...)
+			ns.rejectReason = secrets.AdmissionReasonInvalidExtraInfo
+			return false
+		}
+		reported = true
 
-			// Skip nodes with mismatched policy.
-			var nodePolicyHash [secrets.ChecksumSize]byte
-			switch len(initResponse.PolicyChecksum) {
-			case 0:
-				nodePolicyHash = emptyHashSha3
-			case secrets.ChecksumSize:
-				copy(nodePolicyHash[:], initResponse.PolicyChecksum)
-			default:
-				ctx.Logger().Error("failed to parse policy checksum", append(vars, "err", err)...)
-				continue nextNode
-			}
-			if policyHash != nodePolicyHash {
-				ctx.Logger().Error("Policy checksum mismatch for runtime", vars...)
-				continue nextNode
-			}
+		// Skip nodes with mismatched policy.
+		if checksumFn == nil {
+			ns.rejectReason = secrets.AdmissionReasonPolicyMismatch
+			return false
+		}
+		nodePolicyHash, ok := nodePolicyChecksum(checksumFn, initResponse)
+		if !ok {
+			ctx.Logger().Error("failed to parse policy checksum",
+				append(vars, "policy_checksum", hex.EncodeToString(initResponse.PolicyChecksum))...,
+			)
+			ns.rejectReason = secrets.AdmissionReasonPolicyMismatch
+			return false
+		}
+		if policyHash != nodePolicyHash {
+			ctx.Logger().Error("Policy checksum mismatch for runtime", vars...)
+			ns.rejectReason = secrets.AdmissionReasonPolicyMismatch
+			return false
+		}
 
-			// Set immutable status fields that cannot change after initialization.
-			if !isInitialized {
-				// The first version gets to be the source of truth.
-				isInitialized = true
-				isSecure = initResponse.IsSecure
-			}
+		// Set immutable status fields that cannot change after initialization.
+		if !ns.isInitialized {
+			// The first version gets to be the source of truth.
+			ns.isInitialized = true
+			ns.isSecure = initResponse.IsSecure
+		}
 
-			// Skip nodes with mismatched status fields.
-			if initResponse.IsSecure != isSecure {
-				ctx.Logger().Error("Security status mismatch for runtime", vars...)
-				continue nextNode
-			}
+		// Skip nodes with mismatched status fields.
+		if initResponse.IsSecure != ns.isSecure {
+			ctx.Logger().Error("Security status mismatch for runtime", vars...)
+			ns.rejectReason = secrets.AdmissionReasonSecurityMismatch
+			return false
+		}
 
-			// Skip nodes with mismatched checksum.
-			// Note that a node needs to register with an empty checksum if no master secrets
-			// have been generated so far. Otherwise, if secrets have been generated, the node
-			// needs to register with a checksum computed over all the secrets generated so far
-			// since the key manager's checksum is updated after every master secret rotation.
-			if !bytes.Equal(initResponse.Checksum, status.Checksum) {
-				ctx.Logger().Error("Checksum mismatch for runtime", vars...)
-				continue nextNode
-			}
+		// Skip nodes with mismatched checksum.
+		// Note that a node needs to register with an empty checksum if no master secrets
+		// have been generated so far. Otherwise, if secrets have been generated, the node
+		// needs to register with a checksum computed over all the secrets generated so far
+		// since the key manager's checksum is updated after every master secret rotation.
+		if !bytes.Equal(initResponse.Checksum, status.Checksum) {
+			ctx.Logger().Error("Checksum mismatch for runtime", vars...)
+			ns.rejectReason = secrets.AdmissionReasonChecksumMismatch
+			return false
+		}
 
-			// Update mutable status fields that can change on epoch transitions.
-			if RSK == nil {
-				// The first version with non-nil runtime signing key gets to be the source of truth.
-				RSK = initResponse.RSK
-			}
+		// Update mutable status fields that can change on epoch transitions.
+		if ns.rsk == nil {
+			// The first version with non-nil runtime signing key gets to be the source of truth.
+			ns.rsk = initResponse.RSK
+		}
 
-			// Skip nodes with mismatched runtime signing key.
-			// For backward compatibility we always allow nodes without runtime signing key.
-			if initResponse.RSK != nil && !initResponse.RSK.Equal(*RSK) {
-				ctx.Logger().Error("Runtime signing key mismatch for runtime", vars)
-				continue nextNode
-			}
+		// Skip nodes with mismatched runtime signing key.
+		// For backward compatibility we always allow nodes without runtime signing key.
+		if initResponse.RSK != nil && !initResponse.RSK.Equal(*ns.rsk) {
+			ctx.Logger().Error("Runtime signing key mismatch for runtime", vars...)
+			ns.rejectReason = secrets.AdmissionReasonRSKMismatch
+			return false
+		}
 
-			// Check if all versions have replicated the last master secret,
-			// derived the same RSK and are ready to move to the next generation.
-			if !bytes.Equal(initResponse.NextChecksum, nextChecksum) {
-				secretReplicated = false
+		// Check if all versions have replicated the last master secret,
+		// derived the same RSK and are ready to move to the next generation.
+		if !bytes.Equal(initResponse.NextChecksum, nextChecksum) {
+			ns.secretReplicated = false
+		}
+		if ns.nextRSK == nil {
+			ns.nextRSK = initResponse.NextRSK
+		}
+		if initResponse.NextRSK != nil && !initResponse.NextRSK.Equal(*ns.nextRSK) {
+			ns.secretReplicated = false
+		}
+
+		return true
+	}
+	if verifier != nil {
+		verifyVersion = verifier
+	}
+
+	// Conforming versions of the key manager runtime run by the committee nodes.
+	nodeVersions := make(map[signature.PublicKey][]version.Version)
+
+	// Admission decisions for the nodes running the key manager runtime.
+	var records []*secrets.AdmissionRecord
+	recordAdmission := func(id signature.PublicKey, reason string) *secrets.AdmissionRecord {
+		r := &secrets.AdmissionRecord{
+			Epoch:    epoch,
+			NodeID:   id,
+			Admitted: reason == "",
+			Reason:   reason,
+		}
+		records = append(records, r)
+		return r
+	}
+	admitted := make(map[signature.PublicKey]*secrets.AdmissionRecord)
+
+	// Frozen committees don't admit new nodes and retain their members despite registration
+	// gaps, unless the freeze is broken, e.g. by a policy update.
+	frozen := !breakFreeze && oldStatus.IsCommitteeFrozen(epoch)
+
+	// Number of registered non-shadow nodes eligible for the committee, used to determine
+	// whether enough of them passed secure verification.
+	var numEligible int
+
+	// Construct a key manager committee. A node is added to the committee if it supports
+	// at least one version of the key manager runtime and if all supported versions conform
+	// to the key manager status fields (or at least one, if the policy allows it).
+	for _, n := range nodes {
+		if !n.HasRoles(node.RoleKeyManager) {
+			continue
+		}
+		if !n.HasRuntime(kmrt.ID) {
+			continue
+		}
+		if !n.IsExpired(uint64(epoch)) && slices.ContainsFunc(n.Runtimes, func(rt *node.Runtime) bool {
+			return rt.ID.Equal(&kmrt.ID) && !rt.Shadow
+		}) {
+			numEligible++
+		}
+		if frozen && !slices.ContainsFunc(oldStatus.Nodes, n.ID.Equal) {
+			recordAdmission(n.ID, secrets.AdmissionReasonCommitteeFrozen)
+			continue
+		}
+		if n.IsExpired(uint64(epoch)) {
+			// Expired committee members remain in the committee during the grace period
+			// to give them time to re-register.
+			if epoch > beacon.EpochTime(n.Expiration)+kmParams.NodeExpirationGracePeriod ||
+				!slices.ContainsFunc(oldStatus.Nodes, n.ID.Equal) {
+				recordAdmission(n.ID, secrets.AdmissionReasonExpired)
+				continue
 			}
-			if nRSK == nil {
-				nRSK = initResponse.NextRSK
+		}
+
+		// Reject nodes whose runtime entries disagree on the TEE hardware, as the outcome
+		// of the verification would otherwise depend on the order of the entries.
+		if hasConflictingTEEHardware(n, kmrt.ID) {
+			ctx.Logger().Error("conflicting TEE hardware for runtime",
+				"id", kmrt.ID,
+				"node_id", n.ID,
+			)
+			recordAdmission(n.ID, secrets.AdmissionReasonTEEConflict)
+			continue
+		}
+
+		ns := nodeAdmission{
+			isInitialized:    status.IsInitialized,
+			isSecure:         status.IsSecure,
+			rsk:              status.RSK,
+			nextRSK:          nextRSK,
+			secretReplicated: true,
+		}
+
+		var (
+			versions     []version.Version
+			isShadow     bool
+			rejectReason string
+		)
+		reported = false
+		for _, nodeRt := range n.Runtimes {
+			if !nodeRt.ID.Equal(&kmrt.ID) {
+				continue
 			}
-			if initResponse.NextRSK != nil && !initResponse.NextRSK.Equal(*nRSK) {
-				secretReplicated = false
+			isShadow = isShadow || nodeRt.Shadow
+
+			// Verify the version against a copy of the admission state so that a rejected
+			// version doesn't affect the state of the conforming ones.
+			vs := ns
+			if !verifyVersion(n, nodeRt, &vs) {
+				rejectReason = vs.rejectReason
+				if rejectReason == "" {
+					rejectReason = secrets.AdmissionReasonVersionRejected
+				}
+				if anyVersion {
+					continue
+				}
+				// Reject the node, but still count it as reported.
+				versions = nil
+				break
 			}
+			ns = vs
 
-			numVersions++
+			versions = append(versions, nodeRt.Version)
 		}
 
-		if numVersions == 0 {
+		if reported && !isShadow {
+			numReported++
+		}
+		if len(versions) == 0 {
+			recordAdmission(n.ID, rejectReason)
 			continue
 		}
-		if !isInitialized {
+		if !ns.isInitialized {
 			panic("the key manager must be initialized")
 		}
-		if secretReplicated {
-			nextRSK = nRSK
+
+		// Skip nodes of entities that are not allowed by the policy.
+		if status.Policy != nil && !status.Policy.Policy.IsEntityAllowed(n.EntityID) {
+			ctx.Logger().Error("entity not allowed by the policy",
+				"id", kmrt.ID,
+				"node_id", n.ID,
+				"entity_id", n.EntityID,
+			)
+			recordAdmission(n.ID, secrets.AdmissionReasonEntityNotAllowed)
+			continue
+		}
+
+		// Shadow nodes are only verified, they never join the committee nor affect
+		// the status fields and the replication of the next master secret.
+		if isShadow {
+			ctx.Logger().Info("shadow key manager node would be admitted to the committee",
+				"id", kmrt.ID,
+				"node_id", n.ID,
+				"secret_replicated", ns.secretReplicated,
+			)
+			recordAdmission(n.ID, secrets.AdmissionReasonShadow)
+			continue
+		}
+		if ns.secretReplicated {
+			nextRSK = ns.nextRSK
 			updatedNodes = append(updatedNodes, n.ID)
 		}
 
@@ -281,10 +699,63 @@ nextNode:
 		// of truth, every other node will sync off it.
 		if !status.IsInitialized {
 			status.IsInitialized = true
-			status.IsSecure = isSecure
+			status.IsSecure = ns.isSecure
 		}
-		status.RSK = RSK
+		status.RSK = ns.rsk
 		status.Nodes = append(status.Nodes, n.ID)
+		nodeVersions[n.ID] = versions
+		admitted[n.ID] = recordAdmission(n.ID, "")
+	}
+
+	if frozen {
+		// Members that failed verification are dropped, as opposed to the expired ones.
+		failed := make(map[signature.PublicKey]bool)
+		for _, r := range records {
+			if !r.Admitted && r.Reason != secrets.AdmissionReasonExpired {
+				failed[r.NodeID] = true
+			}
+		}
+		status.Nodes = slices.DeleteFunc(slices.Clone(oldStatus.Nodes), func(id signature.PublicKey) bool {
+			return failed[id]
+		})
+		if status.RSK == nil {
+			status.RSK = oldStatus.RSK
+		}
+	}
+
+	// Limit the number of committee changes per epoch, including the changes made when
+	// the status was previously generated in the same epoch. Deferred nodes join or leave
+	// the committee in subsequent epochs.
+	if !frozen {
+		changes := secrets.CommitteeChanges{Epoch: epoch}
+		if oldStatus.CommitteeChanges != nil && oldStatus.CommitteeChanges.Epoch == epoch {
+			changes = *oldStatus.CommitteeChanges
+		}
+
+		var deferredAdditions, deferredRemovals []signature.PublicKey
+		status.Nodes, deferredAdditions, deferredRemovals = limitCommitteeChanges(
+			oldStatus.Nodes, status.Nodes, kmParams.MaxCommitteeAdditions, kmParams.MaxCommitteeRemovals, &changes,
+		)
+		limited := kmParams.MaxCommitteeAdditions > 0 || kmParams.MaxCommitteeRemovals > 0
+		if limited && (changes.Additions > 0 || changes.Removals > 0) {
+			status.CommitteeChanges = &changes
+		}
+		if len(deferredAdditions) > 0 || len(deferredRemovals) > 0 {
+			ctx.Logger().Info("committee changes deferred due to the change limits",
+				"id", kmrt.ID,
+				"deferred_additions", deferredAdditions,
+				"deferred_removals", deferredRemovals,
+			)
+		}
+		for _, id := range deferredAdditions {
+			admitted[id].Admitted = false
+			admitted[id].Reason = secrets.AdmissionReasonChangeDeferred
+			delete(admitted, id)
+			delete(nodeVersions, id)
+		}
+		updatedNodes = slices.DeleteFunc(updatedNodes, func(id signature.PublicKey) bool {
+			return slices.ContainsFunc(deferredAdditions, id.Equal)
+		})
 	}
 
 	// Accept the proposal if the majority of the nodes have replicated
@@ -297,10 +768,241 @@ nextNode:
 			status.Checksum = nextChecksum
 			status.RSK = nextRSK
 			status.Nodes = updatedNodes
+
+			// Nodes that haven't replicated the proposal are dropped from the committee.
+			for _, id := range updatedNodes {
+				delete(admitted, id)
+			}
+			for _, r := range admitted {
+				r.Admitted = false
+				r.Reason = secrets.AdmissionReasonNotReplicated
+			}
 		}
 	}
 
-	return status
+	// Report the proposal as pending until it is accepted. Proposals are only valid in
+	// the epoch they were published for, after which they expire.
+	if nextChecksum != nil && status.Generation != nextGeneration {
+		status.RotationPending = true
+		status.PendingGeneration = nextGeneration
+		status.PendingEpoch = epoch
+	}
+
+	// Keep the nodes exceeding the active committee size limit on standby, unless the committee
+	// is frozen, in which case the standby nodes are retained as well.
+	if frozen {
+		status.StandbyNodes = slices.Clone(oldStatus.StandbyNodes)
+	} else {
+		status.Nodes, status.StandbyNodes = splitStandbyNodes(oldStatus.Nodes, status.Nodes, kmParams.MaxActiveCommitteeSize)
+	}
+
+	// Mark the status as degraded if too few of the eligible nodes passed secure verification.
+	status.IsDegraded = isDegraded(status, numEligible, kmParams.MinSecureNodePercent)
+	if status.IsDegraded && !oldStatus.IsDegraded {
+		ctx.Logger().Warn("too few secure key manager nodes, status degraded",
+			"id", kmrt.ID,
+			"eligible_nodes", numEligible,
+			"min_secure_node_percent", kmParams.MinSecureNodePercent,
+		)
+	}
+
+	// Aggregate the versions run by the committee.
+	status.SupportedVersions = supportedVersions(status.Nodes, nodeVersions)
+	status.LastSeenEpochs = lastSeenEpochs(oldStatus.LastSeenEpochs, status.Nodes, epoch)
+
+	// Report the initialization progress until the key manager is initialized.
+	if !status.IsInitialized {
+		status.ReportedNodes = numReported
+	}
+
+	return status, records
+}
+
+// hasConflictingTEEHardware returns true iff the runtime entries of the node for the given
+// runtime don't all declare the same TEE hardware.
+func hasConflictingTEEHardware(n *node.Node, id common.Namespace) bool {
+	hardware := make(map[node.TEEHardware]struct{})
+	for _, nodeRt := range n.Runtimes {
+		if !nodeRt.ID.Equal(&id) {
+			continue
+		}
+		if nodeRt.Capabilities.TEE == nil {
+			hardware[node.TEEHardwareInvalid] = struct{}{}
+		} else {
+			hardware[nodeRt.Capabilities.TEE.Hardware] = struct{}{}
+		}
+	}
+	return len(hardware) > 1
+}
+
+// isDegraded returns true iff fewer than the given percentage of the eligible key manager nodes
+// passed secure verification, i.e. are active or standby members of a secure committee.
+// Zero percentage disables the check.
+func isDegraded(status *secrets.Status, numEligible int, minPercent uint8) bool {
+	if minPercent == 0 || !status.IsInitialized {
+		return false
+	}
+
+	var numSecure int
+	if status.IsSecure {
+		numSecure = len(status.Nodes) + len(status.StandbyNodes)
+	}
+	return numEligible == 0 || numSecure*100 < int(minPercent)*numEligible
+}
+
+// supportedVersions counts the committee nodes running each key manager runtime version.
+func supportedVersions(nodes []signature.PublicKey, nodeVersions map[signature.PublicKey][]version.Version) []secrets.VersionCount {
+	counts := make(map[version.Version]uint64)
+	for _, id := range nodes {
+		for _, v := range nodeVersions[id] {
+			counts[v]++
+		}
+	}
+
+	var vcs []secrets.VersionCount
+	for v, n := range counts {
+		vcs = append(vcs, secrets.VersionCount{
+			Version: v,
+			Nodes:   n,
+		})
+	}
+	slices.SortFunc(vcs, func(a, b secrets.VersionCount) int {
+		return cmp.Compare(a.Version.ToU64(), b.Version.ToU64())
+	})
+
+	return vcs
+}
+
+// limitCommitteeChanges limits the number of nodes joining and leaving the committee
+// in a single epoch, where zero means unlimited. The given changes already made in the epoch
+// count towards the limits and are updated with the changes made. Changes exceeding the limits
+// are deferred in the order of node IDs, so that the nodes with the lowest IDs join or leave
+// first.
+//
+// Returns the limited committee and the nodes whose addition and removal were deferred.
+func limitCommitteeChanges(oldNodes, newNodes []signature.PublicKey, maxAdditions, maxRemovals uint64, changes *secrets.CommitteeChanges) ([]signature.PublicKey, []signature.PublicKey, []signature.PublicKey) {
+	var additions, removals []signature.PublicKey
+	for _, id := range newNodes {
+		if !slices.ContainsFunc(oldNodes, id.Equal) {
+			additions = append(additions, id)
+		}
+	}
+	for _, id := range oldNodes {
+		if !slices.ContainsFunc(newNodes, id.Equal) {
+			removals = append(removals, id)
+		}
+	}
+
+	compareIDs := func(a, b signature.PublicKey) int {
+		return bytes.Compare(a[:], b[:])
+	}
+
+	var deferredAdditions, deferredRemovals []signature.PublicKey
+	if allowed := remainingChanges(maxAdditions, changes.Additions); maxAdditions > 0 && uint64(len(additions)) > allowed {
+		slices.SortFunc(additions, compareIDs)
+		deferredAdditions = additions[allowed:]
+	}
+	if allowed := remainingChanges(maxRemovals, changes.Removals); maxRemovals > 0 && uint64(len(removals)) > allowed {
+		slices.SortFunc(removals, compareIDs)
+		deferredRemovals = removals[allowed:]
+	}
+	changes.Additions += uint64(len(additions) - len(deferredAdditions))
+	changes.Removals += uint64(len(removals) - len(deferredRemovals))
+	if len(deferredAdditions) == 0 && len(deferredRemovals) == 0 {
+		return newNodes, nil, nil
+	}
+
+	nodes := make([]signature.PublicKey, 0, len(newNodes)+len(deferredRemovals))
+	for _, id := range newNodes {
+		if !slices.ContainsFunc(deferredAdditions, id.Equal) {
+			nodes = append(nodes, id)
+		}
+	}
+	nodes = append(nodes, deferredRemovals...)
+
+	return nodes, deferredAdditions, deferredRemovals
+}
+
+// remainingChanges returns the number of committee changes that can still be made given
+// the limit and the number of changes already made.
+func remainingChanges(limit, made uint64) uint64 {
+	if made >= limit {
+		return 0
+	}
+	return limit - made
+}
+
+// splitStandbyNodes splits the nodes that qualify for the committee into the active committee
+// of at most maxActive nodes and the standby nodes. Members of the current active committee
+// keep their places, free places are taken by the remaining nodes in the given order.
+//
+// If maxActive is zero, all nodes are active.
+func splitStandbyNodes(oldNodes, nodes []signature.PublicKey, maxActive uint64) ([]signature.PublicKey, []signature.PublicKey) {
+	if maxActive == 0 || uint64(len(nodes)) <= maxActive {
+		return nodes, nil
+	}
+
+	isActive := make(map[signature.PublicKey]bool, maxActive)
+	for _, id := range nodes {
+		if uint64(len(isActive)) < maxActive && slices.ContainsFunc(oldNodes, id.Equal) {
+			isActive[id] = true
+		}
+	}
+	for _, id := range nodes {
+		if uint64(len(isActive)) < maxActive {
+			isActive[id] = true
+		}
+	}
+
+	var active, standby []signature.PublicKey
+	for _, id := range nodes {
+		if isActive[id] {
+			active = append(active, id)
+			continue
+		}
+		standby = append(standby, id)
+	}
+	return active, standby
+}
+
+// lastSeenEpochs records the given epoch as the last seen epoch of the committee nodes, and
+// prunes the nodes that have not been seen for more than lastSeenRetentionEpochs.
+func lastSeenEpochs(old map[signature.PublicKey]beacon.EpochTime, nodes []signature.PublicKey, epoch beacon.EpochTime) map[signature.PublicKey]beacon.EpochTime {
+	var seen map[signature.PublicKey]beacon.EpochTime
+	for id, last := range old {
+		if epoch > last+lastSeenRetentionEpochs {
+			continue
+		}
+		if seen == nil {
+			seen = make(map[signature.PublicKey]beacon.EpochTime)
+		}
+		seen[id] = last
+	}
+	for _, id := range nodes {
+		if seen == nil {
+			seen = make(map[signature.PublicKey]beacon.EpochTime)
+		}
+		seen[id] = epoch
+	}
+	return seen
+}
+
+// nodePolicyChecksum returns the policy checksum reported in the init response,
+// or false if the checksum is malformed.
+//
+// Nodes without a policy report an empty checksum, which stands for the checksum
+// of an empty policy.
+func nodePolicyChecksum(checksumFn func([]byte) [secrets.ChecksumSize]byte, rsp *secrets.InitResponse) ([secrets.ChecksumSize]byte, bool) {
+	var checksum [secrets.ChecksumSize]byte
+	switch len(rsp.PolicyChecksum) {
+	case 0:
+		checksum = checksumFn(nil)
+	case secrets.ChecksumSize:
+		copy(checksum[:], rsp.PolicyChecksum)
+	default:
+		return checksum, false
+	}
+	return checksum, true
 }
 
 // VerifyExtraInfo verifies and parses the per-node + per-runtime ExtraInfo
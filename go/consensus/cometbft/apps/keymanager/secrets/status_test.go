@@ -1,6 +1,9 @@
 package secrets
 
 import (
+	"bytes"
+	"fmt"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -14,6 +17,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/node"
 	"github.com/oasisprotocol/oasis-core/go/common/version"
 	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
 	"github.com/oasisprotocol/oasis-core/go/keymanager/api"
 	"github.com/oasisprotocol/oasis-core/go/keymanager/secrets"
 	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
@@ -27,6 +31,20 @@ func TestGenerateStatus(t *testing.T) {
 
 	// Prepare vars.
 	params := &registry.ConsensusParameters{}
+	kmParams := &secrets.ConsensusParameters{}
+	versionCount := func(major uint16, n uint64) secrets.VersionCount {
+		return secrets.VersionCount{
+			Version: version.Version{Major: major},
+			Nodes:   n,
+		}
+	}
+	lastSeen := func(ids ...signature.PublicKey) map[signature.PublicKey]beacon.EpochTime {
+		seen := make(map[signature.PublicKey]beacon.EpochTime)
+		for _, id := range ids {
+			seen[id] = epoch
+		}
+		return seen
+	}
 	policy := secrets.SignedPolicySGX{
 		Policy: secrets.PolicySGX{
 			Serial: 1,
@@ -207,10 +225,13 @@ func TestGenerateStatus(t *testing.T) {
 	t.Run("No nodes", func(t *testing.T) {
 		require := require.New(t)
 
-		newStatus := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, nodes[0:6], params, epoch)
-		require.Equal(uninitializedStatus, newStatus, "key manager committee should be empty")
+		// Node 5 reports in, but it cannot initialize the key manager as its versions differ.
+		expStatus := *uninitializedStatus
+		expStatus.ReportedNodes = 1
+		newStatus, _ := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, nodes[0:6], params, kmParams, epoch, false)
+		require.Equal(&expStatus, newStatus, "key manager committee should be empty")
 
-		newStatus = generateStatus(ctx, runtimes[0], initializedStatus, nil, nodes[0:6], params, epoch)
+		newStatus, _ = generateStatus(ctx, runtimes[0], initializedStatus, nil, nodes[0:6], params, kmParams, epoch, false)
 		require.Equal(initializedStatus, newStatus, "key manager committee should be empty")
 	})
 
@@ -219,22 +240,26 @@ func TestGenerateStatus(t *testing.T) {
 
 		// Node 6 (secure = false)
 		expStatus := &secrets.Status{
-			ID:            runtimeIDs[0],
-			IsInitialized: true,
-			IsSecure:      false,
-			Policy:        &policy,
-			Nodes:         []signature.PublicKey{nodes[6].ID},
+			ID:                runtimeIDs[0],
+			IsInitialized:     true,
+			IsSecure:          false,
+			Policy:            &policy,
+			Nodes:             []signature.PublicKey{nodes[6].ID},
+			SupportedVersions: []secrets.VersionCount{versionCount(1, 1)},
+			LastSeenEpochs:    lastSeen(nodes[6].ID),
 		}
-		newStatus := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, nodes[6:7], params, epoch)
+		newStatus, _ := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, nodes[6:7], params, kmParams, epoch, false)
 		require.Equal(expStatus, newStatus, "node 6 should form the committee if key manager not initialized")
 
-		newStatus = generateStatus(ctx, runtimes[0], expStatus, nil, nodes[6:7], params, epoch)
+		newStatus, _ = generateStatus(ctx, runtimes[0], expStatus, nil, nodes[6:7], params, kmParams, epoch, false)
 		require.Equal(expStatus, newStatus, "node 6 should form the committee if key manager is not secure")
 
 		expStatus.IsSecure = true
 		expStatus.Checksum = checksum
 		expStatus.Nodes = nil
-		newStatus = generateStatus(ctx, runtimes[0], initializedStatus, nil, nodes[6:7], params, epoch)
+		expStatus.SupportedVersions = nil
+		expStatus.LastSeenEpochs = nil
+		newStatus, _ = generateStatus(ctx, runtimes[0], initializedStatus, nil, nodes[6:7], params, kmParams, epoch, false)
 		require.Equal(expStatus, newStatus, "node 6 should not be added to the committee if key manager is secure or checksum differs")
 	})
 
@@ -245,41 +270,1019 @@ func TestGenerateStatus(t *testing.T) {
 		// If the node 6 is processed before node 7, the latter won't be accepted as it is secure.
 		// Nodes 8 and 9 cannot be a part of the committee as their checksum differs.
 		expStatus := &secrets.Status{
-			ID:            runtimeIDs[0],
-			IsInitialized: true,
-			IsSecure:      false,
-			Policy:        &policy,
-			Nodes:         []signature.PublicKey{nodes[6].ID},
+			ID:                runtimeIDs[0],
+			IsInitialized:     true,
+			IsSecure:          false,
+			Policy:            &policy,
+			Nodes:             []signature.PublicKey{nodes[6].ID},
+			SupportedVersions: []secrets.VersionCount{versionCount(1, 1)},
+			LastSeenEpochs:    lastSeen(nodes[6].ID),
 		}
-		newStatus := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, nodes, params, epoch)
+		newStatus, _ := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, nodes, params, kmParams, epoch, false)
 		require.Equal(expStatus, newStatus, "node 6 should be the source of truth and form the committee")
 
 		// If the order is reversed, it should be the other way around.
 		expStatus.IsSecure = true
 		expStatus.Nodes = []signature.PublicKey{nodes[7].ID}
-		newStatus = generateStatus(ctx, runtimes[0], uninitializedStatus, nil, reverse(nodes), params, epoch)
+		expStatus.SupportedVersions = []secrets.VersionCount{versionCount(2, 1)}
+		expStatus.LastSeenEpochs = lastSeen(nodes[7].ID)
+		newStatus, _ = generateStatus(ctx, runtimes[0], uninitializedStatus, nil, reverse(nodes), params, kmParams, epoch, false)
 		require.Equal(expStatus, newStatus, "node 7 should be the source of truth and form the committee")
 
 		// If the key manager is already initialized as secure with a checksum, then all nodes
 		// except 8 and 9 are ignored.
 		expStatus.Checksum = checksum
 		expStatus.Nodes = []signature.PublicKey{nodes[8].ID, nodes[9].ID}
-		newStatus = generateStatus(ctx, runtimes[0], initializedStatus, nil, nodes, params, epoch)
+		expStatus.SupportedVersions = []secrets.VersionCount{versionCount(3, 2), versionCount(4, 2)}
+		expStatus.LastSeenEpochs = lastSeen(nodes[8].ID, nodes[9].ID)
+		newStatus, _ = generateStatus(ctx, runtimes[0], initializedStatus, nil, nodes, params, kmParams, epoch, false)
 		require.Equal(expStatus, newStatus, "node 7 and 8 should form the committee if key manager is initialized as secure")
 
 		// The second key manager.
 		expStatus = &secrets.Status{
-			ID:            runtimeIDs[1],
+			ID:                runtimeIDs[1],
+			IsInitialized:     true,
+			IsSecure:          true,
+			Policy:            &policy,
+			Checksum:          checksum,
+			Nodes:             []signature.PublicKey{nodes[4].ID, nodes[9].ID},
+			SupportedVersions: []secrets.VersionCount{versionCount(1, 2), versionCount(2, 2)},
+			LastSeenEpochs:    lastSeen(nodes[4].ID, nodes[9].ID),
+		}
+		initializedStatus.ID = runtimeIDs[1]
+		newStatus, _ = generateStatus(ctx, runtimes[1], initializedStatus, nil, nodes, params, kmParams, epoch, false)
+		require.Equal(expStatus, newStatus, "node 4 and 9 should form the committee")
+	})
+
+	t.Run("Initialization progress", func(t *testing.T) {
+		require := require.New(t)
+
+		// Prepare nodes that report a verifiable ExtraInfo for a policy the key manager
+		// doesn't have yet, so that none of them can initialize the key manager.
+		pendingPolicy := secrets.SignedPolicySGX{
+			Policy: secrets.PolicySGX{
+				Serial: 2,
+			},
+		}
+		pendingPolicyChecksum := sha3.Sum256(cbor.Marshal(pendingPolicy))
+		pendingResponse := secrets.InitResponse{
+			IsSecure:       true,
+			PolicyChecksum: pendingPolicyChecksum[:],
+		}
+		sigPendingResponse, err := secrets.SignInitResponse(rakSigner, &pendingResponse)
+		require.NoError(err, "SignInitResponse")
+
+		pendingNodes := make([]*node.Node, 0, 3)
+		for i := 0; i < cap(pendingNodes); i++ {
+			pendingNodes = append(pendingNodes, &node.Node{
+				ID:         memorySigner.NewTestSigner(fmt.Sprintf("pending node %d", i)).Public(),
+				Expiration: uint64(epoch),
+				Roles:      node.RoleKeyManager,
+				Runtimes: []*node.Runtime{
+					{
+						ID:        runtimeIDs[0],
+						Version:   version.Version{Major: 1, Minor: 0, Patch: 0},
+						ExtraInfo: cbor.Marshal(sigPendingResponse),
+					},
+				},
+			})
+		}
+
+		// Nodes that reported in should be counted while the key manager is initializing.
+		for i := 1; i <= len(pendingNodes); i++ {
+			newStatus, _ := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, pendingNodes[:i], params, kmParams, epoch, false)
+			require.False(newStatus.IsInitialized, "key manager should not be initialized")
+			require.True(newStatus.IsInitializing(), "key manager should be initializing")
+			require.EqualValues(i, newStatus.ReportedNodes, "all reported nodes should be counted")
+			require.Empty(newStatus.Nodes, "key manager committee should be empty")
+		}
+
+		// Nodes with an invalid ExtraInfo should not be counted.
+		invalidNode := *pendingNodes[0]
+		invalidNode.Runtimes = []*node.Runtime{
+			{
+				ID:        runtimeIDs[0],
+				Version:   version.Version{Major: 1, Minor: 0, Patch: 0},
+				ExtraInfo: []byte{1, 2, 3},
+			},
+		}
+		newStatus, _ := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, []*node.Node{&invalidNode}, params, kmParams, epoch, false)
+		require.Equal(uninitializedStatus, newStatus, "nodes with invalid ExtraInfo should not be counted")
+		require.False(newStatus.IsInitializing(), "key manager should not be initializing")
+
+		// The progress should be cleared once the key manager is initialized.
+		newStatus, _ = generateStatus(ctx, runtimes[0], uninitializedStatus, nil, append(pendingNodes, nodes[7]), params, kmParams, epoch, false)
+		require.True(newStatus.IsInitialized, "key manager should be initialized")
+		require.False(newStatus.IsInitializing(), "key manager should not be initializing")
+		require.Zero(newStatus.ReportedNodes, "progress should be cleared")
+	})
+
+	t.Run("Any conforming version", func(t *testing.T) {
+		require := require.New(t)
+
+		anyPolicy := secrets.SignedPolicySGX{
+			Policy: secrets.PolicySGX{
+				Serial:                    2,
+				AdmitAnyConformingVersion: true,
+			},
+		}
+		strictPolicy := anyPolicy
+		strictPolicy.Policy.AdmitAnyConformingVersion = false
+		nextChecksum := []byte{6, 7, 8, 9, 10}
+
+		// Prepare a node running two versions, one conforms and has replicated the next
+		// master secret, the other doesn't.
+		newUpgradingNode := func(policy *secrets.SignedPolicySGX) *node.Node {
+			policyChecksum := sha3.Sum256(cbor.Marshal(policy))
+
+			conforming := secrets.InitResponse{
+				IsSecure:       true,
+				Checksum:       checksum,
+				NextChecksum:   nextChecksum,
+				PolicyChecksum: policyChecksum[:],
+			}
+			sigConforming, err := secrets.SignInitResponse(rakSigner, &conforming)
+			require.NoError(err, "SignInitResponse")
+
+			stale := secrets.InitResponse{
+				IsSecure:       true,
+				PolicyChecksum: policyChecksum[:],
+			}
+			sigStale, err := secrets.SignInitResponse(rakSigner, &stale)
+			require.NoError(err, "SignInitResponse")
+
+			return &node.Node{
+				ID:         memorySigner.NewTestSigner("node 10").Public(),
+				Expiration: uint64(epoch),
+				Roles:      node.RoleKeyManager,
+				Runtimes: []*node.Runtime{
+					{
+						ID:        runtimeIDs[0],
+						Version:   version.Version{Major: 1, Minor: 0, Patch: 0},
+						ExtraInfo: cbor.Marshal(sigStale),
+					},
+					{
+						ID:        runtimeIDs[0],
+						Version:   version.Version{Major: 2, Minor: 0, Patch: 0},
+						ExtraInfo: cbor.Marshal(sigConforming),
+					},
+				},
+			}
+		}
+		upgradingNode := newUpgradingNode(&anyPolicy)
+
+		status := &secrets.Status{
+			ID:            runtimeIDs[0],
 			IsInitialized: true,
 			IsSecure:      true,
-			Policy:        &policy,
 			Checksum:      checksum,
-			Nodes:         []signature.PublicKey{nodes[4].ID, nodes[9].ID},
+			Policy:        &anyPolicy,
 		}
-		initializedStatus.ID = runtimeIDs[1]
-		newStatus = generateStatus(ctx, runtimes[1], initializedStatus, nil, nodes, params, epoch)
-		require.Equal(expStatus, newStatus, "node 4 and 9 should form the committee")
+
+		// The node should be admitted based on the conforming version.
+		expStatus := &secrets.Status{
+			ID:                runtimeIDs[0],
+			IsInitialized:     true,
+			IsSecure:          true,
+			Checksum:          checksum,
+			Policy:            &anyPolicy,
+			Nodes:             []signature.PublicKey{upgradingNode.ID},
+			SupportedVersions: []secrets.VersionCount{versionCount(2, 1)},
+			LastSeenEpochs:    lastSeen(upgradingNode.ID),
+		}
+		newStatus, _ := generateStatus(ctx, runtimes[0], status, nil, []*node.Node{upgradingNode}, params, kmParams, epoch, false)
+		require.Equal(expStatus, newStatus, "node 10 should be admitted based on the conforming version")
+
+		// Replication should only consider the conforming version.
+		secret := &secrets.SignedEncryptedMasterSecret{
+			Secret: secrets.EncryptedMasterSecret{
+				ID:         runtimeIDs[0],
+				Generation: 1,
+				Epoch:      epoch,
+				Secret: secrets.EncryptedSecret{
+					Checksum: nextChecksum,
+				},
+			},
+		}
+		expStatus.Generation = 1
+		expStatus.RotationEpoch = epoch
+		expStatus.Checksum = nextChecksum
+		newStatus, _ = generateStatus(ctx, runtimes[0], status, secret, []*node.Node{upgradingNode}, params, kmParams, epoch, false)
+		require.Equal(expStatus, newStatus, "conforming version should replicate the proposal")
+
+		// Without the policy option, the node should be rejected.
+		status.Policy = &strictPolicy
+		upgradingNode = newUpgradingNode(&strictPolicy)
+		newStatus, _ = generateStatus(ctx, runtimes[0], status, nil, []*node.Node{upgradingNode}, params, kmParams, epoch, false)
+		require.Empty(newStatus.Nodes, "node 10 should be rejected if all versions need to conform")
+	})
+
+	t.Run("Shadow nodes", func(t *testing.T) {
+		require := require.New(t)
+
+		// Shadow copies of nodes 6 (secure = false), 7 and 8 (secure = true).
+		shadowNodes := make([]*node.Node, 0, 3)
+		for _, n := range nodes[6:9] {
+			sn := *n
+			sn.Runtimes = make([]*node.Runtime, 0, len(n.Runtimes))
+			for _, rt := range n.Runtimes {
+				srt := *rt
+				srt.Shadow = true
+				sn.Runtimes = append(sn.Runtimes, &srt)
+			}
+			shadowNodes = append(shadowNodes, &sn)
+		}
+
+		// Shadow nodes alone should never initialize the key manager.
+		newStatus, _ := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, shadowNodes, params, kmParams, epoch, false)
+		require.Equal(uninitializedStatus, newStatus, "shadow nodes should not form the committee")
+
+		// Shadow nodes should not be the source of truth.
+		expStatus := &secrets.Status{
+			ID:                runtimeIDs[0],
+			IsInitialized:     true,
+			IsSecure:          true,
+			Policy:            &policy,
+			Nodes:             []signature.PublicKey{nodes[7].ID},
+			SupportedVersions: []secrets.VersionCount{versionCount(2, 1)},
+			LastSeenEpochs:    lastSeen(nodes[7].ID),
+		}
+		newStatus, _ = generateStatus(ctx, runtimes[0], uninitializedStatus, nil, append(shadowNodes[:1:1], nodes[7]), params, kmParams, epoch, false)
+		require.Equal(expStatus, newStatus, "node 7 should form the committee even if processed after a shadow node")
+
+		// Shadow nodes should never join an initialized committee.
+		expStatus = &secrets.Status{
+			ID:                runtimeIDs[0],
+			IsInitialized:     true,
+			IsSecure:          true,
+			Checksum:          checksum,
+			Policy:            &policy,
+			Nodes:             []signature.PublicKey{nodes[9].ID},
+			SupportedVersions: []secrets.VersionCount{versionCount(3, 1), versionCount(4, 1)},
+			LastSeenEpochs:    lastSeen(nodes[9].ID),
+		}
+		initializedStatus.ID = runtimeIDs[0]
+		newStatus, _ = generateStatus(ctx, runtimes[0], initializedStatus, nil, append(shadowNodes, nodes[9]), params, kmParams, epoch, false)
+		require.Equal(expStatus, newStatus, "shadow node 8 should not join the committee")
+	})
+
+	t.Run("Admission records", func(t *testing.T) {
+		require := require.New(t)
+
+		expRecords := []*secrets.AdmissionRecord{
+			{Epoch: epoch, NodeID: nodes[1].ID, Reason: secrets.AdmissionReasonExpired},
+			{Epoch: epoch, NodeID: nodes[5].ID, Reason: secrets.AdmissionReasonSecurityMismatch},
+			{Epoch: epoch, NodeID: nodes[6].ID, Reason: secrets.AdmissionReasonSecurityMismatch},
+			{Epoch: epoch, NodeID: nodes[7].ID, Reason: secrets.AdmissionReasonChecksumMismatch},
+			{Epoch: epoch, NodeID: nodes[8].ID, Admitted: true},
+			{Epoch: epoch, NodeID: nodes[9].ID, Admitted: true},
+		}
+		_, records := generateStatus(ctx, runtimes[0], initializedStatus, nil, nodes, params, kmParams, epoch, false)
+		require.Equal(expRecords, records, "admission decisions should be recorded for key manager nodes")
+	})
+
+	t.Run("Unsupported policy checksum algorithm", func(t *testing.T) {
+		require := require.New(t)
+
+		unsupportedParams := &secrets.ConsensusParameters{PolicyChecksumAlgorithm: "md5"}
+
+		// Nodes cannot be verified without the policy checksum.
+		newStatus, records := generateStatus(ctx, runtimes[0], initializedStatus, nil, nodes[8:9], params, unsupportedParams, epoch, false)
+		require.Empty(newStatus.Nodes, "node 8 should not be admitted")
+		require.Equal([]*secrets.AdmissionRecord{
+			{Epoch: epoch, NodeID: nodes[8].ID, Reason: secrets.AdmissionReasonPolicyMismatch},
+		}, records, "node 8 should be rejected as the policy checksum cannot be computed")
 	})
+
+	t.Run("Insecure key managers disabled", func(t *testing.T) {
+		require := require.New(t)
+
+		secureParams := &secrets.ConsensusParameters{DisableInsecureKeyManagers: true}
+
+		// Nodes of insecure key managers should be rejected.
+		newStatus, records := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, nodes[6:7], params, secureParams, epoch, false)
+		require.Equal(uninitializedStatus, newStatus, "node 6 should not form the committee")
+		require.Equal([]*secrets.AdmissionRecord{
+			{Epoch: epoch, NodeID: nodes[6].ID, Reason: secrets.AdmissionReasonInsecureDisabled},
+		}, records, "node 6 should be rejected as insecure key managers are disabled")
+	})
+
+	t.Run("Active deployment enforced", func(t *testing.T) {
+		require := require.New(t)
+
+		enforceParams := &secrets.ConsensusParameters{EnforceActiveDeployment: true}
+		v3, v4 := version.Version{Major: 3}, version.Version{Major: 4}
+		deployedRuntime := *runtimes[0]
+		deployedRuntime.Deployments = []*registry.VersionInfo{
+			{Version: v3, ValidFrom: 0},
+			{Version: v4, ValidFrom: epoch + 1},
+		}
+
+		// Nodes running the active deployment should be admitted.
+		activeNode := *nodes[8]
+		activeNode.Expiration = uint64(epoch) + 1
+		activeNode.Runtimes = nodeRuntimes[2:3]
+		newStatus, _ := generateStatus(ctx, &deployedRuntime, initializedStatus, nil, []*node.Node{&activeNode}, params, enforceParams, epoch, false)
+		require.Equal([]signature.PublicKey{activeNode.ID}, newStatus.Nodes, "node running the active deployment should be admitted")
+
+		// Nodes running other versions should be rejected, unless not enforced.
+		_, records := generateStatus(ctx, &deployedRuntime, initializedStatus, nil, nodes[8:9], params, enforceParams, epoch, false)
+		require.Equal([]*secrets.AdmissionRecord{
+			{Epoch: epoch, NodeID: nodes[8].ID, Reason: secrets.AdmissionReasonStaleVersion},
+		}, records, "node running a version other than the active deployment should be rejected")
+
+		newStatus, _ = generateStatus(ctx, &deployedRuntime, initializedStatus, nil, nodes[8:9], params, kmParams, epoch, false)
+		require.Equal([]signature.PublicKey{nodes[8].ID}, newStatus.Nodes, "versions should not be checked by default")
+
+		// Once the next deployment becomes active, the previous version becomes stale.
+		_, records = generateStatus(ctx, &deployedRuntime, initializedStatus, nil, []*node.Node{&activeNode}, params, enforceParams, epoch+1, false)
+		require.Equal([]*secrets.AdmissionRecord{
+			{Epoch: epoch + 1, NodeID: activeNode.ID, Reason: secrets.AdmissionReasonStaleVersion},
+		}, records, "node running the previous deployment should be rejected")
+	})
+
+	t.Run("Minimum enclave version", func(t *testing.T) {
+		require := require.New(t)
+
+		v3, v4 := version.Version{Major: 3}, version.Version{Major: 4}
+		minPolicy := policy
+		minPolicy.Policy.MinEnclaveVersion = &v4
+		minPolicyChecksum := sha3.Sum256(cbor.Marshal(minPolicy))
+
+		sigInitResponse, err := secrets.SignInitResponse(rakSigner, &secrets.InitResponse{
+			IsSecure:       true,
+			Checksum:       checksum,
+			PolicyChecksum: minPolicyChecksum[:],
+		})
+		require.NoError(err, "SignInitResponse")
+
+		newNode := func(name string, versions ...version.Version) *node.Node {
+			n := &node.Node{
+				ID:         memorySigner.NewTestSigner(name).Public(),
+				Expiration: uint64(epoch),
+				Roles:      node.RoleKeyManager,
+			}
+			for _, v := range versions {
+				n.Runtimes = append(n.Runtimes, &node.Runtime{
+					ID:        runtimeIDs[0],
+					Version:   v,
+					ExtraInfo: cbor.Marshal(sigInitResponse),
+				})
+			}
+			return n
+		}
+		oldNode := newNode("old node", v3)
+		upgradingNode := newNode("upgrading node", v3, v4)
+		upgradedNode := newNode("upgraded node", v4)
+		registered := []*node.Node{oldNode, upgradingNode, upgradedNode}
+
+		status := *initializedStatus
+		status.ID = runtimeIDs[0]
+		status.Policy = &minPolicy
+
+		// Nodes running versions below the floor should be rejected.
+		newStatus, records := generateStatus(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, false)
+		require.Equal([]signature.PublicKey{upgradedNode.ID}, newStatus.Nodes, "only nodes above the floor should be admitted")
+		require.Equal([]*secrets.AdmissionRecord{
+			{Epoch: epoch, NodeID: oldNode.ID, Reason: secrets.AdmissionReasonVersionTooOld},
+			{Epoch: epoch, NodeID: upgradingNode.ID, Reason: secrets.AdmissionReasonVersionTooOld},
+			{Epoch: epoch, NodeID: upgradedNode.ID, Admitted: true},
+		}, records, "nodes running versions below the floor should be rejected")
+
+		// Nodes in the middle of an upgrade can be admitted if any conforming version is allowed.
+		minPolicy.Policy.AdmitAnyConformingVersion = true
+		minPolicyChecksum = sha3.Sum256(cbor.Marshal(minPolicy))
+		sigInitResponse, err = secrets.SignInitResponse(rakSigner, &secrets.InitResponse{
+			IsSecure:       true,
+			Checksum:       checksum,
+			PolicyChecksum: minPolicyChecksum[:],
+		})
+		require.NoError(err, "SignInitResponse")
+		registered = []*node.Node{newNode("old node", v3), newNode("upgrading node", v3, v4)}
+		newStatus, _ = generateStatus(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, false)
+		require.Equal([]signature.PublicKey{upgradingNode.ID}, newStatus.Nodes, "upgrading node should be admitted")
+		require.Equal([]secrets.VersionCount{versionCount(4, 1)}, newStatus.SupportedVersions)
+	})
+
+	t.Run("Missing REK", func(t *testing.T) {
+		require := require.New(t)
+
+		sgxRuntime := *runtimes[0]
+		sgxRuntime.TEEHardware = node.TEEHardwareIntelSGX
+
+		// Secure nodes without a runtime encryption key should be rejected.
+		sgxNode := &node.Node{
+			ID:         memorySigner.NewTestSigner("node sgx").Public(),
+			Expiration: uint64(epoch),
+			Roles:      node.RoleKeyManager,
+			Runtimes: []*node.Runtime{
+				{
+					ID:      runtimeIDs[0],
+					Version: version.Version{Major: 1},
+					Capabilities: node.Capabilities{
+						TEE: &node.CapabilityTEE{
+							Hardware: node.TEEHardwareIntelSGX,
+							RAK:      api.TestSigners[0].Public(),
+						},
+					},
+				},
+			},
+		}
+		newStatus, records := generateStatus(ctx, &sgxRuntime, uninitializedStatus, nil, []*node.Node{sgxNode}, params, kmParams, epoch, false)
+		require.Equal(uninitializedStatus, newStatus, "node without REK should not form the committee")
+		require.Equal([]*secrets.AdmissionRecord{
+			{Epoch: epoch, NodeID: sgxNode.ID, Reason: secrets.AdmissionReasonMissingREK},
+		}, records, "node without REK should be rejected")
+	})
+
+	t.Run("Conflicting TEE hardware", func(t *testing.T) {
+		require := require.New(t)
+
+		// Nodes whose entries for the same runtime disagree on the TEE hardware should be
+		// rejected, even though one of the entries matches the runtime.
+		conflictNode := &node.Node{
+			ID:         memorySigner.NewTestSigner("node conflict").Public(),
+			Expiration: uint64(epoch),
+			Roles:      node.RoleKeyManager,
+			Runtimes: []*node.Runtime{
+				nodeRuntimes[0],
+				{
+					ID:      runtimeIDs[0],
+					Version: version.Version{Major: 2},
+					Capabilities: node.Capabilities{
+						TEE: &node.CapabilityTEE{
+							Hardware: node.TEEHardwareIntelSGX,
+							RAK:      api.TestSigners[0].Public(),
+						},
+					},
+				},
+			},
+		}
+		newStatus, records := generateStatus(ctx, runtimes[0], uninitializedStatus, nil, []*node.Node{conflictNode}, params, kmParams, epoch, false)
+		require.Equal(uninitializedStatus, newStatus, "node with conflicting TEE hardware should not form the committee")
+		require.Equal([]*secrets.AdmissionRecord{
+			{Epoch: epoch, NodeID: conflictNode.ID, Reason: secrets.AdmissionReasonTEEConflict},
+		}, records, "node with conflicting TEE hardware should be rejected")
+
+		// Nodes with consistent entries should not be affected.
+		require.False(hasConflictingTEEHardware(nodes[5], runtimeIDs[0]))
+		require.True(hasConflictingTEEHardware(conflictNode, runtimeIDs[0]))
+		require.False(hasConflictingTEEHardware(conflictNode, runtimeIDs[1]))
+	})
+
+	t.Run("Expiration grace period", func(t *testing.T) {
+		require := require.New(t)
+
+		// Node 9 expired two epochs ago.
+		expiredNode := *nodes[9]
+		expiredNode.Expiration = uint64(epoch - 2)
+
+		status := *initializedStatus
+		status.ID = runtimeIDs[0]
+		status.Nodes = []signature.PublicKey{expiredNode.ID}
+		status.SupportedVersions = []secrets.VersionCount{versionCount(3, 1), versionCount(4, 1)}
+
+		expStatus := status
+		expStatus.Nodes = nil
+		expStatus.SupportedVersions = nil
+
+		// Expired nodes should be dropped immediately by default.
+		newStatus, _ := generateStatus(ctx, runtimes[0], &status, nil, []*node.Node{&expiredNode}, params, kmParams, epoch, false)
+		require.Equal(&expStatus, newStatus, "expired node 9 should be dropped without a grace period")
+
+		// Expired nodes should be dropped once the grace period is over.
+		graceParams := &secrets.ConsensusParameters{NodeExpirationGracePeriod: 1}
+		newStatus, _ = generateStatus(ctx, runtimes[0], &status, nil, []*node.Node{&expiredNode}, params, graceParams, epoch, false)
+		require.Equal(&expStatus, newStatus, "expired node 9 should be dropped after the grace period")
+
+		// Expired committee members should remain in the committee during the grace period.
+		graceParams.NodeExpirationGracePeriod = 2
+		retainedStatus := status
+		retainedStatus.LastSeenEpochs = lastSeen(expiredNode.ID)
+		newStatus, _ = generateStatus(ctx, runtimes[0], &status, nil, []*node.Node{&expiredNode}, params, graceParams, epoch, false)
+		require.Equal(&retainedStatus, newStatus, "expired node 9 should remain in the committee during the grace period")
+
+		// Expired nodes should never join the committee.
+		newStatus, _ = generateStatus(ctx, runtimes[0], &expStatus, nil, []*node.Node{&expiredNode}, params, graceParams, epoch, false)
+		require.Equal(&expStatus, newStatus, "expired node 9 should not join the committee")
+	})
+
+	t.Run("Committee freeze", func(t *testing.T) {
+		require := require.New(t)
+
+		// Admit all nodes, as the policy checksum of the nodes differs from the frozen policy.
+		verifier := func(*node.Node, *node.Runtime, *nodeAdmission) bool {
+			return true
+		}
+
+		frozenPolicy := policy
+		frozenPolicy.Policy.CommitteeFreezePeriod = 5
+
+		// Node 1 has a registration gap, node 9 is not a member of the committee.
+		status := *initializedStatus
+		status.ID = runtimeIDs[0]
+		status.Policy = &frozenPolicy
+		status.RotationEpoch = epoch - 2
+		status.Nodes = []signature.PublicKey{nodes[8].ID, nodes[1].ID}
+		registered := []*node.Node{nodes[8], nodes[9]}
+
+		// Frozen committees should retain their members and reject new nodes.
+		expStatus := status
+		expStatus.SupportedVersions = []secrets.VersionCount{versionCount(3, 1), versionCount(4, 1)}
+		expStatus.LastSeenEpochs = lastSeen(nodes[8].ID, nodes[1].ID)
+		newStatus, records := generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, false, verifier)
+		require.Equal(&expStatus, newStatus, "frozen committee should not change")
+		require.Equal([]*secrets.AdmissionRecord{
+			{Epoch: epoch, NodeID: nodes[8].ID, Admitted: true},
+			{Epoch: epoch, NodeID: nodes[9].ID, Reason: secrets.AdmissionReasonCommitteeFrozen},
+		}, records, "node 9 should be rejected as the committee is frozen")
+
+		// The committee should be re-evaluated once the freeze expires.
+		status.RotationEpoch = epoch - 5
+		expStatus.RotationEpoch = status.RotationEpoch
+		expStatus.Nodes = []signature.PublicKey{nodes[8].ID, nodes[9].ID}
+		expStatus.SupportedVersions = []secrets.VersionCount{versionCount(3, 2), versionCount(4, 2)}
+		expStatus.LastSeenEpochs = lastSeen(nodes[8].ID, nodes[9].ID)
+		newStatus, _ = generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, false, verifier)
+		require.Equal(&expStatus, newStatus, "committee should be re-evaluated after the freeze")
+
+		// Policy updates break the freeze.
+		status.RotationEpoch = epoch - 2
+		expStatus.RotationEpoch = status.RotationEpoch
+		newStatus, _ = generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, true, verifier)
+		require.Equal(&expStatus, newStatus, "committee should be re-evaluated after a policy update")
+
+		// Members that fail verification should be dropped from frozen committees.
+		verifier = func(n *node.Node, _ *node.Runtime, _ *nodeAdmission) bool {
+			return !n.ID.Equal(nodes[8].ID)
+		}
+		expStatus = status
+		expStatus.Nodes = []signature.PublicKey{nodes[1].ID}
+		expStatus.LastSeenEpochs = lastSeen(nodes[1].ID)
+		newStatus, records = generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, false, verifier)
+		require.Equal(&expStatus, newStatus, "node 8 should be dropped from the frozen committee")
+		require.Equal([]*secrets.AdmissionRecord{
+			{Epoch: epoch, NodeID: nodes[8].ID, Reason: secrets.AdmissionReasonVersionRejected},
+			{Epoch: epoch, NodeID: nodes[9].ID, Reason: secrets.AdmissionReasonCommitteeFrozen},
+		}, records, "node 8 should be rejected as it failed verification")
+	})
+
+	t.Run("Allowed entities", func(t *testing.T) {
+		require := require.New(t)
+
+		// Admit all nodes, as the policy checksum of the nodes differs from the restricted policy.
+		verifier := func(*node.Node, *node.Runtime, *nodeAdmission) bool {
+			return true
+		}
+
+		allowedEntity := memorySigner.NewTestSigner("allowed entity").Public()
+		otherEntity := memorySigner.NewTestSigner("other entity").Public()
+
+		node8, node9 := *nodes[8], *nodes[9]
+		node8.EntityID = allowedEntity
+		node9.EntityID = otherEntity
+		registered := []*node.Node{&node8, &node9}
+
+		restrictedPolicy := policy
+		restrictedPolicy.Policy.AllowedEntities = []signature.PublicKey{allowedEntity}
+
+		status := *initializedStatus
+		status.Policy = &restrictedPolicy
+
+		// Nodes of entities that are not on the list should be rejected.
+		expStatus := status
+		expStatus.Nodes = []signature.PublicKey{node8.ID}
+		expStatus.SupportedVersions = []secrets.VersionCount{versionCount(3, 1), versionCount(4, 1)}
+		expStatus.LastSeenEpochs = lastSeen(node8.ID)
+		newStatus, records := generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, false, verifier)
+		require.Equal(&expStatus, newStatus, "only nodes of allowed entities should be admitted")
+		require.Equal([]*secrets.AdmissionRecord{
+			{Epoch: epoch, NodeID: node8.ID, Admitted: true},
+			{Epoch: epoch, NodeID: node9.ID, Reason: secrets.AdmissionReasonEntityNotAllowed},
+		}, records, "node 9 should be rejected as its entity is not allowed")
+
+		// An empty list should not restrict the committee.
+		restrictedPolicy.Policy.AllowedEntities = nil
+		expStatus.Nodes = []signature.PublicKey{node8.ID, node9.ID}
+		expStatus.SupportedVersions = []secrets.VersionCount{versionCount(3, 2), versionCount(4, 2)}
+		expStatus.LastSeenEpochs = lastSeen(node8.ID, node9.ID)
+		newStatus, _ = generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, false, verifier)
+		require.Equal(&expStatus, newStatus, "all nodes should be admitted")
+	})
+
+	t.Run("Committee change limits", func(t *testing.T) {
+		require := require.New(t)
+
+		// Admit all nodes, as the policy checksum of the nodes differs from the status policy.
+		verifier := func(*node.Node, *node.Runtime, *nodeAdmission) bool {
+			return true
+		}
+
+		compareIDs := func(a, b signature.PublicKey) int {
+			return bytes.Compare(a[:], b[:])
+		}
+
+		// Nodes 1, 2 and 3 are leaving the committee, nodes 8 and 9 are joining it.
+		status := *initializedStatus
+		status.ID = runtimeIDs[0]
+		status.Nodes = []signature.PublicKey{nodes[1].ID, nodes[2].ID, nodes[3].ID}
+		registered := []*node.Node{nodes[8], nodes[9]}
+
+		additions := []signature.PublicKey{nodes[8].ID, nodes[9].ID}
+		removals := slices.Clone(status.Nodes)
+		slices.SortFunc(additions, compareIDs)
+		slices.SortFunc(removals, compareIDs)
+
+		// Changes should not be limited by default.
+		newStatus, _ := generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, false, verifier)
+		require.Equal([]signature.PublicKey{nodes[8].ID, nodes[9].ID}, newStatus.Nodes, "all changes should be applied")
+		require.Nil(newStatus.CommitteeChanges, "changes should not be tracked without limits")
+
+		// Changes exceeding the limits should be deferred in the order of node IDs.
+		limitParams := &secrets.ConsensusParameters{
+			MaxCommitteeAdditions: 1,
+			MaxCommitteeRemovals:  1,
+		}
+		newStatus, records := generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, limitParams, epoch, false, verifier)
+		require.Equal([]signature.PublicKey{additions[0], removals[1], removals[2]}, newStatus.Nodes, "excess changes should be deferred")
+		require.Equal([]secrets.VersionCount{versionCount(3, 1), versionCount(4, 1)}, newStatus.SupportedVersions)
+		for _, r := range records {
+			switch r.NodeID {
+			case additions[0]:
+				require.True(r.Admitted, "first addition should be admitted")
+			case additions[1]:
+				require.False(r.Admitted, "second addition should be deferred")
+				require.Equal(secrets.AdmissionReasonChangeDeferred, r.Reason)
+			}
+		}
+
+		require.Equal(&secrets.CommitteeChanges{Epoch: epoch, Additions: 1, Removals: 1}, newStatus.CommitteeChanges, "changes should be tracked")
+
+		// Changes made when regenerating the status in the same epoch should count towards
+		// the limits.
+		limitedStatus, _ := generateStatusWithVerifier(ctx, runtimes[0], newStatus, nil, registered, params, limitParams, epoch, false, verifier)
+		require.Equal(newStatus.Nodes, limitedStatus.Nodes, "no further changes should be made in the same epoch")
+
+		// Deferred changes should be applied in subsequent epochs.
+		newStatus, _ = generateStatusWithVerifier(ctx, runtimes[0], newStatus, nil, registered, params, limitParams, epoch+1, false, verifier)
+		require.Equal([]signature.PublicKey{nodes[8].ID, nodes[9].ID, removals[2]}, newStatus.Nodes, "deferred changes should be applied")
+		require.Equal(&secrets.CommitteeChanges{Epoch: epoch + 1, Additions: 1, Removals: 1}, newStatus.CommitteeChanges, "changes should be tracked per epoch")
+	})
+
+	t.Run("Standby committee", func(t *testing.T) {
+		require := require.New(t)
+
+		// Admit all nodes, as the policy checksum of the nodes differs from the status policy.
+		verifier := func(*node.Node, *node.Runtime, *nodeAdmission) bool {
+			return true
+		}
+
+		status := *initializedStatus
+		status.ID = runtimeIDs[0]
+		status.Nodes = []signature.PublicKey{nodes[8].ID, nodes[9].ID}
+		registered := nodes[5:10]
+
+		// All nodes should be active by default.
+		newStatus, _ := generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, false, verifier)
+		require.Len(newStatus.Nodes, 5, "all nodes should be active")
+		require.Empty(newStatus.StandbyNodes, "no nodes should be on standby")
+
+		// Members of the active committee should keep their places, excess nodes should be
+		// kept on standby.
+		standbyParams := &secrets.ConsensusParameters{
+			MaxActiveCommitteeSize: 3,
+		}
+		newStatus, _ = generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, standbyParams, epoch, false, verifier)
+		require.Equal([]signature.PublicKey{nodes[5].ID, nodes[8].ID, nodes[9].ID}, newStatus.Nodes)
+		require.Equal([]signature.PublicKey{nodes[6].ID, nodes[7].ID}, newStatus.StandbyNodes)
+		require.Contains(newStatus.ChangedFields(&status), "standby_nodes", "standby nodes should be reported as changed")
+
+		// Standby nodes should be promoted once the active committee has free places.
+		registered = []*node.Node{nodes[5], nodes[6], nodes[7], nodes[9]}
+		newStatus, _ = generateStatusWithVerifier(ctx, runtimes[0], newStatus, nil, registered, params, standbyParams, epoch, false, verifier)
+		require.Equal([]signature.PublicKey{nodes[5].ID, nodes[6].ID, nodes[9].ID}, newStatus.Nodes)
+		require.Equal([]signature.PublicKey{nodes[7].ID}, newStatus.StandbyNodes)
+	})
+
+	t.Run("Minimum secure nodes", func(t *testing.T) {
+		require := require.New(t)
+
+		// Admit only nodes 8 and 9.
+		verifier := func(n *node.Node, _ *node.Runtime, _ *nodeAdmission) bool {
+			return n.ID.Equal(nodes[8].ID) || n.ID.Equal(nodes[9].ID)
+		}
+
+		status := *initializedStatus
+		status.ID = runtimeIDs[0]
+		status.Nodes = []signature.PublicKey{nodes[8].ID, nodes[9].ID}
+		registered := nodes[5:10]
+
+		// The check should be disabled by default.
+		newStatus, _ := generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, kmParams, epoch, false, verifier)
+		require.False(newStatus.IsDegraded, "status should not be degraded by default")
+
+		// Two out of five eligible nodes are secure.
+		minParams := &secrets.ConsensusParameters{
+			MinSecureNodePercent: 40,
+		}
+		newStatus, _ = generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, minParams, epoch, false, verifier)
+		require.False(newStatus.IsDegraded, "status should not be degraded at the minimum")
+
+		minParams.MinSecureNodePercent = 41
+		newStatus, _ = generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, minParams, epoch, false, verifier)
+		require.True(newStatus.IsDegraded, "status should be degraded below the minimum")
+		require.Contains(newStatus.ChangedFields(&status), "is_degraded", "degradation should be reported as changed")
+
+		// Insecure key managers have no secure nodes.
+		status.IsSecure = false
+		minParams.MinSecureNodePercent = 1
+		newStatus, _ = generateStatusWithVerifier(ctx, runtimes[0], &status, nil, registered, params, minParams, epoch, false, verifier)
+		require.True(newStatus.IsDegraded, "insecure key manager should be degraded")
+	})
+
+	t.Run("Last seen epochs", func(t *testing.T) {
+		require := require.New(t)
+
+		// Node 9 dropped out of the committee in this epoch, node 1 a while ago and node 2
+		// long enough ago to be pruned.
+		status := *initializedStatus
+		status.ID = runtimeIDs[0]
+		status.Nodes = []signature.PublicKey{nodes[8].ID, nodes[9].ID}
+		status.LastSeenEpochs = map[signature.PublicKey]beacon.EpochTime{
+			nodes[8].ID: epoch - 1,
+			nodes[9].ID: epoch - 1,
+			nodes[1].ID: epoch - lastSeenRetentionEpochs,
+			nodes[2].ID: epoch - lastSeenRetentionEpochs - 1,
+		}
+
+		newStatus, _ := generateStatus(ctx, runtimes[0], &status, nil, nodes[8:9], params, kmParams, epoch, false)
+		require.Equal([]signature.PublicKey{nodes[8].ID}, newStatus.Nodes)
+		require.Equal(map[signature.PublicKey]beacon.EpochTime{
+			nodes[8].ID: epoch,
+			nodes[9].ID: epoch - 1,
+			nodes[1].ID: epoch - lastSeenRetentionEpochs,
+		}, newStatus.LastSeenEpochs, "recently dropped nodes should be retained")
+	})
+}
+
+func TestGenerateStatusReplicationThreshold(t *testing.T) {
+	// Prepare context.
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Prepare vars.
+	params := &registry.ConsensusParameters{}
+	kmParams := &secrets.ConsensusParameters{}
+	epoch := beacon.EpochTime(10)
+	checksum := []byte{1, 2, 3, 4, 5}
+	nextChecksum := []byte{6, 7, 8, 9, 10}
+
+	var runtimeID common.Namespace
+	require.NoError(t, runtimeID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000000"), "runtime id")
+	runtime := &registry.Runtime{
+		ID:          runtimeID,
+		TEEHardware: node.TEEHardwareInvalid,
+	}
+	status := &secrets.Status{
+		ID:            runtimeID,
+		IsInitialized: true,
+		IsSecure:      true,
+		Checksum:      checksum,
+	}
+	secret := &secrets.SignedEncryptedMasterSecret{
+		Secret: secrets.EncryptedMasterSecret{
+			ID:         runtimeID,
+			Generation: 1,
+			Epoch:      epoch,
+			Secret: secrets.EncryptedSecret{
+				Checksum: nextChecksum,
+			},
+		},
+	}
+
+	// Admit all nodes, only the replicated ones have replicated the proposal.
+	replicated := make(map[signature.PublicKey]bool)
+	verifier := func(n *node.Node, _ *node.Runtime, ns *nodeAdmission) bool {
+		ns.secretReplicated = ns.secretReplicated && replicated[n.ID]
+		return true
+	}
+
+	newNodes := func(numNodes, numReplicated int) []*node.Node {
+		nodes := make([]*node.Node, 0, numNodes)
+		for i := 0; i < numNodes; i++ {
+			n := &node.Node{
+				ID:         memorySigner.NewTestSigner(fmt.Sprintf("node %d", i)).Public(),
+				Expiration: uint64(epoch),
+				Roles:      node.RoleKeyManager,
+				Runtimes: []*node.Runtime{
+					{
+						ID: runtimeID,
+					},
+				},
+			}
+			replicated[n.ID] = i < numReplicated
+			nodes = append(nodes, n)
+		}
+		return nodes
+	}
+
+	for _, tc := range []struct {
+		numNodes      int
+		numReplicated int
+		accepted      bool
+	}{
+		{1, 0, false},
+		{1, 1, true},
+		{2, 1, false},
+		{3, 1, false},
+		{3, 2, true},
+		{50, 32, false},
+		{50, 33, true},
+		{100, 65, false},
+		{100, 66, true},
+	} {
+		t.Run(fmt.Sprintf("%d of %d", tc.numReplicated, tc.numNodes), func(t *testing.T) {
+			require := require.New(t)
+
+			nodes := newNodes(tc.numNodes, tc.numReplicated)
+			newStatus, _ := generateStatusWithVerifier(ctx, runtime, status, secret, nodes, params, kmParams, epoch, false, verifier)
+
+			if !tc.accepted {
+				require.Equal(uint64(0), newStatus.Generation, "proposal should be rejected")
+				require.Equal(checksum, newStatus.Checksum, "checksum should not change")
+				require.Len(newStatus.Nodes, tc.numNodes, "all nodes should form the committee")
+				require.True(newStatus.RotationPending, "rotation should be pending")
+				require.Equal(uint64(1), newStatus.PendingGeneration)
+				require.Equal(epoch, newStatus.PendingEpoch)
+
+				// The proposal expires once the epoch is over.
+				newStatus, _ = generateStatusWithVerifier(ctx, runtime, newStatus, secret, nodes, params, kmParams, epoch+1, false, verifier)
+				require.False(newStatus.RotationPending, "expired proposal should not be pending")
+				require.Zero(newStatus.PendingGeneration)
+				require.Zero(newStatus.PendingEpoch)
+				return
+			}
+
+			require.False(newStatus.RotationPending, "accepted rotation should not be pending")
+			require.Equal(uint64(1), newStatus.Generation, "proposal should be accepted")
+			require.Equal(epoch, newStatus.RotationEpoch, "rotation epoch should be updated")
+			require.Equal(nextChecksum, newStatus.Checksum, "checksum should be updated")
+			require.Len(newStatus.Nodes, tc.numReplicated, "only nodes that replicated should form the committee")
+		})
+	}
+}
+
+func TestGenerateStatusDeterminism(t *testing.T) {
+	require := require.New(t)
+
+	// Prepare context.
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Prepare vars.
+	params := &registry.ConsensusParameters{}
+	kmParams := &secrets.ConsensusParameters{
+		NodeExpirationGracePeriod: 2,
+		MaxCommitteeAdditions:     4,
+	}
+	epoch := beacon.EpochTime(10)
+	checksum := []byte{1, 2, 3, 4, 5}
+	nextChecksum := []byte{6, 7, 8, 9, 10}
+	rsk := memorySigner.NewTestSigner("rsk").Public()
+	nextRSK := memorySigner.NewTestSigner("next rsk").Public()
+
+	var runtimeID common.Namespace
+	require.NoError(runtimeID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000000"), "runtime id")
+	runtime := &registry.Runtime{
+		ID:          runtimeID,
+		TEEHardware: node.TEEHardwareInvalid,
+	}
+	policy := secrets.SignedPolicySGX{
+		Policy: secrets.PolicySGX{
+			Serial: 1,
+			ID:     runtimeID,
+		},
+	}
+	policyChecksum := sha3.Sum256(cbor.Marshal(policy))
+	secret := &secrets.SignedEncryptedMasterSecret{
+		Secret: secrets.EncryptedMasterSecret{
+			ID:         runtimeID,
+			Generation: 1,
+			Epoch:      epoch,
+			Secret: secrets.EncryptedSecret{
+				Checksum: nextChecksum,
+			},
+		},
+	}
+
+	// Nodes that replicated the proposal, nodes that didn't and nodes with a stale checksum,
+	// running different versions of the key manager runtime.
+	signInitResponse := func(rsp secrets.InitResponse) []byte {
+		rsp.IsSecure = true
+		rsp.PolicyChecksum = policyChecksum[:]
+		sigRsp, err := secrets.SignInitResponse(api.TestSigners[0], &rsp)
+		require.NoError(err, "SignInitResponse")
+		return cbor.Marshal(sigRsp)
+	}
+	extraInfos := [][]byte{
+		signInitResponse(secrets.InitResponse{Checksum: checksum, RSK: &rsk, NextChecksum: nextChecksum, NextRSK: &nextRSK}),
+		signInitResponse(secrets.InitResponse{Checksum: checksum, RSK: &rsk}),
+		signInitResponse(secrets.InitResponse{Checksum: nextChecksum, RSK: &rsk}),
+	}
+	var nodes []*node.Node
+	for i := 0; i < 24; i++ {
+		nodes = append(nodes, &node.Node{
+			ID:         memorySigner.NewTestSigner(fmt.Sprintf("node %d", i)).Public(),
+			Expiration: uint64(epoch),
+			Roles:      node.RoleKeyManager,
+			Runtimes: []*node.Runtime{
+				{
+					ID:        runtimeID,
+					Version:   version.Version{Major: uint16(i % 4)},
+					ExtraInfo: extraInfos[i%len(extraInfos)],
+				},
+			},
+		})
+	}
+
+	// The current committee, including members that are no longer registered.
+	var committee []signature.PublicKey
+	for i := 0; i < 12; i += 2 {
+		committee = append(committee, nodes[i].ID)
+	}
+	for i := 0; i < 3; i++ {
+		committee = append(committee, memorySigner.NewTestSigner(fmt.Sprintf("expired node %d", i)).Public())
+	}
+	status := &secrets.Status{
+		ID:             runtimeID,
+		IsInitialized:  true,
+		IsSecure:       true,
+		Checksum:       checksum,
+		RSK:            &rsk,
+		Policy:         &policy,
+		Nodes:          committee,
+		LastSeenEpochs: lastSeenEpochs(nil, committee, epoch-1),
+	}
+
+	// Without the proposal.
+	newStatus := requireDeterministicStatus(t, ctx, runtime, status, nil, nodes, params, kmParams, epoch)
+	require.NotEmpty(newStatus.Nodes, "committee should not be empty")
+
+	// With the proposal.
+	newStatus = requireDeterministicStatus(t, ctx, runtime, status, secret, nodes, params, kmParams, epoch)
+	require.NotEmpty(newStatus.Nodes, "committee should not be empty")
+}
+
+// requireDeterministicStatus requires generateStatus to produce the same status and admission
+// records, down to the serialized bytes, every time it is run with the same inputs.
+//
+// The nodes are shuffled with a fixed set of beacons, as the order of the nodes matters, and
+// the status is generated repeatedly for each permutation in order to catch dependencies on
+// map iteration order or unstable sorts. It returns the status generated for the nodes in
+// the given order.
+func requireDeterministicStatus(
+	t *testing.T,
+	ctx statusContext,
+	kmrt *registry.Runtime,
+	oldStatus *secrets.Status,
+	secret *secrets.SignedEncryptedMasterSecret,
+	nodes []*node.Node,
+	params *registry.ConsensusParameters,
+	kmParams *secrets.ConsensusParameters,
+	epoch beacon.EpochTime,
+) *secrets.Status {
+	t.Helper()
+
+	const numRuns = 20
+
+	var status *secrets.Status
+	for i, b := range []string{"", "shuffle 1", "shuffle 2", "shuffle 3"} {
+		shuffled := nodes
+		if b != "" {
+			shuffled = make([]*node.Node, 0, len(nodes))
+			for _, idx := range beacon.ShuffleWithBeacon([]byte(b), len(nodes)) {
+				shuffled = append(shuffled, nodes[idx])
+			}
+		}
+
+		var expected []byte
+		for run := 0; run < numRuns; run++ {
+			newStatus, records := generateStatus(ctx, kmrt, oldStatus, secret, shuffled, params, kmParams, epoch, false)
+			if i == 0 && run == 0 {
+				status = newStatus
+			}
+
+			raw := cbor.Marshal([]interface{}{newStatus, records})
+			if run == 0 {
+				expected = raw
+				continue
+			}
+			require.Equal(t, expected, raw, "generated status should be deterministic (permutation %d, run %d)", i, run)
+		}
+	}
+	return status
 }
 
 func reverse(nodes []*node.Node) []*node.Node {
@@ -289,3 +1292,277 @@ func reverse(nodes []*node.Node) []*node.Node {
 	}
 	return reversed
 }
+
+func TestEmitInitializedEvent(t *testing.T) {
+	require := require.New(t)
+
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	const appName = "keymanager"
+
+	uninitialized := &secrets.Status{}
+	initialized := &secrets.Status{IsInitialized: true, IsSecure: true}
+
+	// The event should not be emitted if the key manager is not initialized,
+	// or has already been initialized before.
+	for _, tc := range []struct {
+		old, new *secrets.Status
+	}{
+		{uninitialized, uninitialized},
+		{initialized, initialized},
+	} {
+		ctx := appState.NewContext(abciAPI.ContextEndBlock)
+		emitInitializedEvent(ctx, appName, tc.old, tc.new)
+		require.False(ctx.HasEvent(appName, &secrets.InitializedEvent{}), "initialized event should not be emitted")
+		ctx.Close()
+	}
+
+	// The event should be emitted on the first initialization.
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+	emitInitializedEvent(ctx, appName, uninitialized, initialized)
+	require.True(ctx.HasEvent(appName, &secrets.InitializedEvent{}), "initialized event should be emitted")
+
+	var ev secrets.InitializedEvent
+	require.NoError(ctx.DecodeEvent(0, &ev), "DecodeEvent")
+	require.Equal(secrets.InitializedEvent{ID: initialized.ID, IsSecure: true}, ev)
+}
+
+func TestEmitReplicationQuorumReachedEvent(t *testing.T) {
+	require := require.New(t)
+
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	const appName = "keymanager"
+
+	nodes := []signature.PublicKey{
+		memorySigner.NewTestSigner("node 0").Public(),
+		memorySigner.NewTestSigner("node 1").Public(),
+	}
+	oldStatus := &secrets.Status{
+		Generation: 1,
+		Checksum:   []byte{1},
+		Nodes:      append(nodes, memorySigner.NewTestSigner("node 2").Public()),
+	}
+
+	// The event should not be emitted if the threshold hasn't been met.
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	emitReplicationQuorumReachedEvent(ctx, appName, oldStatus, oldStatus)
+	require.False(ctx.HasEvent(appName, &secrets.ReplicationQuorumReachedEvent{}), "quorum reached event should not be emitted")
+	ctx.Close()
+
+	// The event should be emitted once the rotation is accepted.
+	newStatus := &secrets.Status{
+		Generation: 2,
+		Checksum:   []byte{2},
+		Nodes:      nodes,
+	}
+	ctx = appState.NewContext(abciAPI.ContextEndBlock)
+	emitReplicationQuorumReachedEvent(ctx, appName, oldStatus, newStatus)
+	require.True(ctx.HasEvent(appName, &secrets.ReplicationQuorumReachedEvent{}), "quorum reached event should be emitted")
+
+	var ev secrets.ReplicationQuorumReachedEvent
+	require.NoError(ctx.DecodeEvent(0, &ev), "DecodeEvent")
+	require.Equal(secrets.ReplicationQuorumReachedEvent{Generation: 2, ReplicatedNodes: 2}, ev)
+	ctx.Close()
+
+	// Standby nodes should be counted as well.
+	newStatus.Nodes = nodes[:1]
+	newStatus.StandbyNodes = nodes[1:]
+	ctx = appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+	emitReplicationQuorumReachedEvent(ctx, appName, oldStatus, newStatus)
+	require.NoError(ctx.DecodeEvent(0, &ev), "DecodeEvent")
+	require.Equal(secrets.ReplicationQuorumReachedEvent{Generation: 2, ReplicatedNodes: 2}, ev, "standby nodes should be counted")
+}
+
+func TestEmitGenerationLimitExceededEvent(t *testing.T) {
+	require := require.New(t)
+
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	const appName = "keymanager"
+
+	oldStatus := &secrets.Status{
+		Generation: 2,
+		Checksum:   []byte{2},
+	}
+	newStatus := &secrets.Status{
+		Generation: 3,
+		Checksum:   []byte{3},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		oldStatus *secrets.Status
+		limit     uint64
+		emitted   bool
+	}{
+		{"unlimited", oldStatus, 0, false},
+		{"below limit", oldStatus, 5, false},
+		{"at limit", oldStatus, 4, false},
+		{"no rotation", newStatus, 3, false},
+		{"above limit", oldStatus, 3, true},
+	} {
+		ctx := appState.NewContext(abciAPI.ContextEndBlock)
+		emitGenerationLimitExceededEvent(ctx, appName, tc.oldStatus, newStatus, tc.limit)
+		require.Equal(tc.emitted, ctx.HasEvent(appName, &secrets.GenerationLimitExceededEvent{}), tc.name)
+		if tc.emitted {
+			var ev secrets.GenerationLimitExceededEvent
+			require.NoError(ctx.DecodeEvent(0, &ev), "DecodeEvent")
+			require.Equal(secrets.GenerationLimitExceededEvent{Generations: 4, Limit: 3}, ev)
+		}
+		ctx.Close()
+	}
+}
+
+func TestNotableAdmissionRecords(t *testing.T) {
+	require := require.New(t)
+
+	member := memorySigner.NewTestSigner("member").Public()
+	candidate := memorySigner.NewTestSigner("candidate").Public()
+	rejected := memorySigner.NewTestSigner("rejected").Public()
+	dropped := memorySigner.NewTestSigner("dropped").Public()
+
+	oldStatus := &secrets.Status{
+		Nodes: []signature.PublicKey{member, dropped},
+	}
+	records := []*secrets.AdmissionRecord{
+		{Epoch: 5, NodeID: member, Admitted: true},
+		{Epoch: 5, NodeID: candidate, Admitted: true},
+		{Epoch: 5, NodeID: rejected, Reason: secrets.AdmissionReasonVersionRejected},
+		{Epoch: 5, NodeID: dropped, Reason: secrets.AdmissionReasonNotReplicated},
+	}
+
+	// Routine admissions of the existing members should not be persisted.
+	require.Equal(records[1:], notableAdmissionRecords(oldStatus, records))
+	require.Len(records, 4, "records should not be modified")
+}
+
+func TestSetReplicationFailures(t *testing.T) {
+	require := require.New(t)
+
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	state := secretsState.NewMutableState(ctx.State())
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("runtime"), common.NamespaceKeyManager)
+
+	nodes := []signature.PublicKey{
+		memorySigner.NewTestSigner("node 0").Public(),
+		memorySigner.NewTestSigner("node 1").Public(),
+		memorySigner.NewTestSigner("node 2").Public(),
+	}
+	oldStatus := &secrets.Status{
+		ID:         runtimeID,
+		Generation: 1,
+		Checksum:   []byte{1},
+		Nodes:      nodes[:2],
+	}
+	newStatus := &secrets.Status{
+		ID:            runtimeID,
+		Generation:    2,
+		RotationEpoch: 10,
+		Checksum:      []byte{2},
+		Nodes:         nodes[:1],
+	}
+
+	// Node 1 was dropped from the committee, node 2 was never a member.
+	records := []*secrets.AdmissionRecord{
+		{Epoch: 10, NodeID: nodes[0], Admitted: true},
+		{Epoch: 10, NodeID: nodes[1], Reason: secrets.AdmissionReasonNotReplicated},
+		{Epoch: 10, NodeID: nodes[2], Reason: secrets.AdmissionReasonNotReplicated},
+	}
+
+	// Nothing should be recorded until a rotation is accepted.
+	err := setReplicationFailures(ctx, state, oldStatus, oldStatus, records)
+	require.NoError(err, "setReplicationFailures")
+	_, err = state.ReplicationFailures(ctx, runtimeID)
+	require.ErrorIs(err, secrets.ErrNoSuchReplicationFailures, "replication failures should not be recorded")
+
+	// Previous committee members that failed to replicate should be recorded.
+	err = setReplicationFailures(ctx, state, oldStatus, newStatus, records)
+	require.NoError(err, "setReplicationFailures")
+	failures, err := state.ReplicationFailures(ctx, runtimeID)
+	require.NoError(err, "ReplicationFailures")
+	require.Equal(&secrets.ReplicationFailures{
+		ID:         runtimeID,
+		Generation: 2,
+		Epoch:      10,
+		Nodes:      nodes[1:2],
+	}, failures)
+
+	// Failures should be kept while the rotation is pending.
+	err = setReplicationFailures(ctx, state, newStatus, newStatus, nil)
+	require.NoError(err, "setReplicationFailures")
+	_, err = state.ReplicationFailures(ctx, runtimeID)
+	require.NoError(err, "replication failures should be kept")
+
+	// Failures should be replaced once the next rotation is accepted.
+	nextStatus := &secrets.Status{
+		ID:            runtimeID,
+		Generation:    3,
+		RotationEpoch: 12,
+		Checksum:      []byte{3},
+		Nodes:         nodes[:1],
+	}
+	err = setReplicationFailures(ctx, state, newStatus, nextStatus, nil)
+	require.NoError(err, "setReplicationFailures")
+	failures, err = state.ReplicationFailures(ctx, runtimeID)
+	require.NoError(err, "ReplicationFailures")
+	require.Equal(&secrets.ReplicationFailures{
+		ID:         runtimeID,
+		Generation: 3,
+		Epoch:      12,
+	}, failures)
+}
+
+func TestPruneEphemeralSecret(t *testing.T) {
+	require := require.New(t)
+
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	state := secretsState.NewMutableState(ctx.State())
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("runtime"), common.NamespaceKeyManager)
+
+	status := &secrets.Status{
+		ID: runtimeID,
+		Policy: &secrets.SignedPolicySGX{
+			Policy: secrets.PolicySGX{
+				MaxEphemeralSecretAge: 3,
+			},
+		},
+	}
+	secret := &secrets.SignedEncryptedEphemeralSecret{
+		Secret: secrets.EncryptedEphemeralSecret{
+			ID:    runtimeID,
+			Epoch: 10,
+		},
+	}
+	err := state.SetEphemeralSecret(ctx, secret)
+	require.NoError(err, "SetEphemeralSecret")
+
+	// Secrets within the retention window should be kept.
+	for epoch := beacon.EpochTime(9); epoch <= 13; epoch++ {
+		err = pruneEphemeralSecret(ctx, state, status, epoch)
+		require.NoError(err, "pruneEphemeralSecret")
+		_, err = state.EphemeralSecret(ctx, runtimeID)
+		require.NoError(err, "ephemeral secret should be kept in epoch %d", epoch)
+	}
+
+	// Secrets should be kept if the policy doesn't define the maximum age.
+	err = pruneEphemeralSecret(ctx, state, &secrets.Status{ID: runtimeID}, 20)
+	require.NoError(err, "pruneEphemeralSecret")
+	_, err = state.EphemeralSecret(ctx, runtimeID)
+	require.NoError(err, "ephemeral secret should be kept without a policy")
+
+	// Secrets outside the retention window should be removed.
+	err = pruneEphemeralSecret(ctx, state, status, 14)
+	require.NoError(err, "pruneEphemeralSecret")
+	_, err = state.EphemeralSecret(ctx, runtimeID)
+	require.ErrorIs(err, secrets.ErrNoSuchEphemeralSecret, "ephemeral secret should be removed")
+
+	// Pruning missing secrets should succeed.
+	err = pruneEphemeralSecret(ctx, state, status, 15)
+	require.NoError(err, "pruneEphemeralSecret")
+}
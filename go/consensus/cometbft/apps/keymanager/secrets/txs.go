@@ -1,12 +1,15 @@
 package secrets
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"slices"
 
 	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
 	tmapi "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
@@ -24,16 +27,29 @@ func (ext *secretsExt) updatePolicy(
 ) error {
 	// Ensure that the runtime exists and is a key manager.
 	regState := registryState.NewMutableState(ctx.State())
-	kmRt, err := keyManagerRuntime(ctx, regState, sigPol.Policy.ID)
+	kmRt, err := keyManagerRuntime(ctx, regState.ImmutableState, sigPol.Policy.ID)
 	if err != nil {
+		rejectTx(ctx, opUpdatePolicy, reasonInvalidRuntime)
 		return err
 	}
 
 	// Ensure that the tx signer is the key manager owner.
 	if !kmRt.EntityID.Equal(ctx.TxSigner()) {
+		rejectTx(ctx, opUpdatePolicy, reasonInvalidSigner)
 		return fmt.Errorf("keymanager: invalid update signer: %s", sigPol.Policy.ID)
 	}
 
+	// Reject oversized policies before doing any expensive verification.
+	kmParams, err := state.ConsensusParameters(ctx)
+	if err != nil {
+		return err
+	}
+	size := uint64(len(cbor.Marshal(sigPol)))
+	if kmParams.MaxPolicySize > 0 && size > kmParams.MaxPolicySize {
+		rejectTx(ctx, opUpdatePolicy, reasonPolicyTooLarge)
+		return fmt.Errorf("keymanager: policy too large (max: %d, got: %d)", kmParams.MaxPolicySize, size)
+	}
+
 	// Get the existing policy document, if one exists.
 	oldStatus, err := state.Status(ctx, kmRt.ID)
 	switch err {
@@ -49,6 +65,7 @@ func (ext *secretsExt) updatePolicy(
 
 	// Validate the tx.
 	if err = secrets.SanityCheckSignedPolicySGX(oldStatus.Policy, sigPol); err != nil {
+		rejectTx(ctx, opUpdatePolicy, reasonVerifyFailed)
 		return err
 	}
 
@@ -57,10 +74,6 @@ func (ext *secretsExt) updatePolicy(
 	}
 
 	// Charge gas for this operation.
-	kmParams, err := state.ConsensusParameters(ctx)
-	if err != nil {
-		return err
-	}
 	if err = ctx.Gas().UseGas(1, secrets.GasOpUpdatePolicy, kmParams.GasCosts); err != nil {
 		return err
 	}
@@ -93,8 +106,16 @@ func (ext *secretsExt) updatePolicy(
 
 	nodes, _ := regState.Nodes(ctx)
 	registry.SortNodeList(nodes)
-	oldStatus.Policy = sigPol
-	newStatus := generateStatus(ctx, kmRt, oldStatus, nil, nodes, regParams, epoch)
+
+	// Store the description alongside the status, so that it doesn't affect the policy checksum.
+	policy := *sigPol
+	policy.Description = ""
+	status := *oldStatus
+	status.Policy = &policy
+	status.PolicyDescription = sigPol.Description
+
+	// Policy updates always re-evaluate the committee, breaking the committee freeze.
+	newStatus, _ := generateStatus(ctx, kmRt, &status, nil, nodes, regParams, kmParams, epoch, true)
 	if err := state.SetStatus(ctx, newStatus); err != nil {
 		ctx.Logger().Error("keymanager: failed to set key manager status",
 			"err", err,
@@ -102,9 +123,76 @@ func (ext *secretsExt) updatePolicy(
 		return fmt.Errorf("keymanager: failed to set key manager status: %w", err)
 	}
 
-	ctx.EmitEvent(tmapi.NewEventBuilder(ext.appName).TypedAttribute(&secrets.StatusUpdateEvent{
-		Statuses: []*secrets.Status{newStatus},
-	}))
+	emitStatusUpdateEvent(ctx, ext.appName, []*secrets.Status{oldStatus}, []*secrets.Status{newStatus})
+	emitInitializedEvent(ctx, ext.appName, oldStatus, newStatus)
+
+	return nil
+}
+
+// refreshStatus regenerates the key manager status from the current node registrations
+// and emits the status update event immediately, instead of waiting for the next epoch
+// transition, e.g. after an emergency policy update or node re-registrations.
+//
+// This is available to the key manager owner.
+func (ext *secretsExt) refreshStatus(
+	ctx *tmapi.Context,
+	state *secretsState.MutableState,
+	sr *secrets.StatusRefresh,
+) error {
+	// Ensure that the runtime exists and is a key manager.
+	regState := registryState.NewMutableState(ctx.State())
+	kmRt, err := keyManagerRuntime(ctx, regState.ImmutableState, sr.ID)
+	if err != nil {
+		rejectTx(ctx, opRefreshStatus, reasonInvalidRuntime)
+		return err
+	}
+
+	// Ensure that the tx signer is the key manager owner.
+	if !kmRt.EntityID.Equal(ctx.TxSigner()) {
+		rejectTx(ctx, opRefreshStatus, reasonInvalidSigner)
+		return fmt.Errorf("keymanager: invalid refresh status signer: %s", sr.ID)
+	}
+
+	if ctx.IsCheckOnly() {
+		return nil
+	}
+
+	// Charge gas for this operation.
+	kmParams, err := state.ConsensusParameters(ctx)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Gas().UseGas(1, secrets.GasOpRefreshStatus, kmParams.GasCosts); err != nil {
+		return err
+	}
+
+	// Return early if simulating since this is just estimating gas.
+	if ctx.IsSimulation() {
+		return nil
+	}
+
+	epoch, err := ext.state.GetCurrentEpoch(ctx)
+	if err != nil {
+		return err
+	}
+
+	regParams, err := regState.ConsensusParameters(ctx)
+	if err != nil {
+		return err
+	}
+
+	nodes, _ := regState.Nodes(ctx)
+	registry.SortNodeList(nodes)
+
+	oldStatus, newStatus, err := ext.updateStatus(ctx, state, kmRt, nodes, regParams, kmParams, epoch)
+	if err != nil {
+		return fmt.Errorf("keymanager: %w", err)
+	}
+	if newStatus == nil {
+		return nil
+	}
+
+	emitStatusUpdateEvent(ctx, ext.appName, []*secrets.Status{oldStatus}, []*secrets.Status{newStatus})
 
 	return nil
 }
@@ -135,17 +223,33 @@ func (ext *secretsExt) publishMasterSecret(
 ) error {
 	// Ensure that the runtime exists and is a key manager.
 	regState := registryState.NewMutableState(ctx.State())
-	kmRt, err := keyManagerRuntime(ctx, regState, secret.Secret.ID)
+	kmRt, err := keyManagerRuntime(ctx, regState.ImmutableState, secret.Secret.ID)
+	if err != nil {
+		rejectTx(ctx, opPublishMasterSecret, reasonInvalidRuntime)
+		return err
+	}
+
+	// Reject if insecure key managers are disabled.
+	kmParams, err := state.ConsensusParameters(ctx)
 	if err != nil {
 		return err
 	}
+	if err = verifyKeyManagerSecurity(kmRt, kmParams); err != nil {
+		rejectTx(ctx, opPublishMasterSecret, reasonInsecureDisabled)
+		return err
+	}
 
 	// Reject if the signer is not in the key manager committee.
 	kmStatus, err := state.Status(ctx, kmRt.ID)
 	if err != nil {
 		return err
 	}
+	if len(kmStatus.Nodes) == 0 {
+		rejectTx(ctx, opPublishMasterSecret, reasonNoCommittee)
+		return fmt.Errorf("keymanager: master secret cannot be published as the key manager committee is empty")
+	}
 	if !slices.Contains(kmStatus.Nodes, ctx.TxSigner()) {
+		rejectTx(ctx, opPublishMasterSecret, reasonNotCommittee)
 		return fmt.Errorf("keymanager: master secret can be published only by the key manager committee")
 	}
 
@@ -154,18 +258,46 @@ func (ext *secretsExt) publishMasterSecret(
 	if err != nil && err != secrets.ErrNoSuchMasterSecret {
 		return err
 	}
+	// Resubmissions of the published secret are processed as usual, but not stored again,
+	// so that retries are safe.
+	var resubmitted bool
 	if lastSecret != nil && secret.Secret.Epoch == lastSecret.Secret.Epoch {
-		return fmt.Errorf("keymanager: master secret can be proposed once per epoch")
+		if secret.IdempotencyKey() != lastSecret.IdempotencyKey() {
+			rejectTx(ctx, opPublishMasterSecret, reasonAlreadyPublished)
+			return fmt.Errorf("keymanager: master secret can be proposed once per epoch")
+		}
+		resubmitted = true
+	}
+
+	// Reject if the proposal cooldown has not expired.
+	if lastSecret != nil && !resubmitted {
+		if err = kmStatus.VerifyProposalEpoch(lastSecret.Secret.Epoch, secret.Secret.Epoch); err != nil {
+			rejectTx(ctx, opPublishMasterSecret, reasonProposalCooldown)
+			return fmt.Errorf("keymanager: master secret proposal not allowed: %w", err)
+		}
 	}
 
 	// Reject if rotation is not allowed.
 	if err = kmStatus.VerifyRotationEpoch(secret.Secret.Epoch); err != nil {
+		rejectTx(ctx, opPublishMasterSecret, reasonRotationNotAllowed)
 		return fmt.Errorf("keymanager: master secret rotation not allowed: %w", err)
 	}
 
+	// Reject if the secret is not proposed for the next generation. Master secrets are
+	// generated in sequence, so skipping or repeating a generation is never allowed.
+	nextGen := kmStatus.NextGeneration()
+	if secret.Secret.Generation != nextGen {
+		ctx.Logger().Error("master secret generation out of sequence",
+			"id", kmRt.ID,
+			"expected", nextGen,
+			"generation", secret.Secret.Generation,
+		)
+		rejectTx(ctx, opPublishMasterSecret, reasonInvalidGeneration)
+		return fmt.Errorf("%w: (expected: %d, got: %d)", secrets.ErrInvalidGeneration, nextGen, secret.Secret.Generation)
+	}
+
 	// Verify the secret. Master secrets can be published for the next epoch and for
 	// the next generation only.
-	nextGen := kmStatus.NextGeneration()
 	epoch, err := ext.state.GetCurrentEpoch(ctx)
 	if err != nil {
 		return err
@@ -175,9 +307,16 @@ func (ext *secretsExt) publishMasterSecret(
 	if err != nil {
 		return err
 	}
-	reks := runtimeEncryptionKeys(ctx, regState, kmRt, kmStatus)
+	reks := runtimeEncryptionKeys(ctx, regState.ImmutableState, kmRt, kmStatus)
 
 	if err = secret.Verify(nextGen, nextEpoch, reks, rak); err != nil {
+		reason := verifyFailedReason(err)
+		ctx.Logger().Error("failed to verify master secret",
+			"id", kmRt.ID,
+			"reason", reason,
+			"err", err,
+		)
+		rejectTx(ctx, opPublishMasterSecret, reason)
 		return err
 	}
 
@@ -187,10 +326,6 @@ func (ext *secretsExt) publishMasterSecret(
 	}
 
 	// Charge gas for this operation.
-	kmParams, err := state.ConsensusParameters(ctx)
-	if err != nil {
-		return err
-	}
 	if err = ctx.Gas().UseGas(1, secrets.GasOpPublishMasterSecret, kmParams.GasCosts); err != nil {
 		return err
 	}
@@ -200,6 +335,11 @@ func (ext *secretsExt) publishMasterSecret(
 		return nil
 	}
 
+	// The secret has already been saved.
+	if resubmitted {
+		return nil
+	}
+
 	// Ok, as far as we can tell the secret is valid, save it.
 	if err := state.SetMasterSecret(ctx, secret); err != nil {
 		ctx.Logger().Error("keymanager: failed to set key manager master secret",
@@ -235,17 +375,33 @@ func (ext *secretsExt) publishEphemeralSecret(
 ) error {
 	// Ensure that the runtime exists and is a key manager.
 	regState := registryState.NewMutableState(ctx.State())
-	kmRt, err := keyManagerRuntime(ctx, regState, secret.Secret.ID)
+	kmRt, err := keyManagerRuntime(ctx, regState.ImmutableState, secret.Secret.ID)
+	if err != nil {
+		rejectTx(ctx, opPublishEphemeralSecret, reasonInvalidRuntime)
+		return err
+	}
+
+	// Reject if insecure key managers are disabled.
+	kmParams, err := state.ConsensusParameters(ctx)
 	if err != nil {
 		return err
 	}
+	if err = verifyKeyManagerSecurity(kmRt, kmParams); err != nil {
+		rejectTx(ctx, opPublishEphemeralSecret, reasonInsecureDisabled)
+		return err
+	}
 
 	// Reject if the signer is not in the key manager committee.
 	kmStatus, err := state.Status(ctx, kmRt.ID)
 	if err != nil {
 		return err
 	}
+	if len(kmStatus.Nodes) == 0 {
+		rejectTx(ctx, opPublishEphemeralSecret, reasonNoCommittee)
+		return fmt.Errorf("keymanager: ephemeral secret cannot be published as the key manager committee is empty")
+	}
 	if !slices.Contains(kmStatus.Nodes, ctx.TxSigner()) {
+		rejectTx(ctx, opPublishEphemeralSecret, reasonNotCommittee)
 		return fmt.Errorf("keymanager: ephemeral secret can be published only by the key manager committee")
 	}
 
@@ -254,8 +410,15 @@ func (ext *secretsExt) publishEphemeralSecret(
 	if err != nil && err != secrets.ErrNoSuchEphemeralSecret {
 		return err
 	}
+	// Resubmissions of the published secret are processed as usual, but not stored again,
+	// so that retries are safe.
+	var resubmitted bool
 	if lastSecret != nil && secret.Secret.Epoch == lastSecret.Secret.Epoch {
-		return fmt.Errorf("keymanager: ephemeral secret can be proposed once per epoch")
+		if secret.IdempotencyKey() != lastSecret.IdempotencyKey() {
+			rejectTx(ctx, opPublishEphemeralSecret, reasonAlreadyPublished)
+			return fmt.Errorf("keymanager: ephemeral secret can be proposed once per epoch")
+		}
+		resubmitted = true
 	}
 
 	// Verify the secret. Ephemeral secrets can be published for the next epoch only.
@@ -268,9 +431,16 @@ func (ext *secretsExt) publishEphemeralSecret(
 	if err != nil {
 		return err
 	}
-	reks := runtimeEncryptionKeys(ctx, regState, kmRt, kmStatus)
+	reks := runtimeEncryptionKeys(ctx, regState.ImmutableState, kmRt, kmStatus)
 
 	if err = secret.Verify(nextEpoch, reks, rak); err != nil {
+		reason := verifyFailedReason(err)
+		ctx.Logger().Error("failed to verify ephemeral secret",
+			"id", kmRt.ID,
+			"reason", reason,
+			"err", err,
+		)
+		rejectTx(ctx, opPublishEphemeralSecret, reason)
 		return err
 	}
 
@@ -280,10 +450,6 @@ func (ext *secretsExt) publishEphemeralSecret(
 	}
 
 	// Charge gas for this operation.
-	kmParams, err := state.ConsensusParameters(ctx)
-	if err != nil {
-		return err
-	}
 	if err = ctx.Gas().UseGas(1, secrets.GasOpPublishEphemeralSecret, kmParams.GasCosts); err != nil {
 		return err
 	}
@@ -293,6 +459,11 @@ func (ext *secretsExt) publishEphemeralSecret(
 		return nil
 	}
 
+	// The secret has already been saved.
+	if resubmitted {
+		return nil
+	}
+
 	// Ok, as far as we can tell the secret is valid, save it.
 	if err := state.SetEphemeralSecret(ctx, secret); err != nil {
 		ctx.Logger().Error("keymanager: failed to set key manager ephemeral secret",
@@ -308,7 +479,7 @@ func (ext *secretsExt) publishEphemeralSecret(
 	return nil
 }
 
-func keyManagerRuntime(ctx *tmapi.Context, regState *registryState.MutableState, id common.Namespace) (*registry.Runtime, error) {
+func keyManagerRuntime(ctx context.Context, regState *registryState.ImmutableState, id common.Namespace) (*registry.Runtime, error) {
 	// Ensure that the runtime exists and is a key manager.
 	rt, err := regState.Runtime(ctx, id)
 	if err != nil {
@@ -320,6 +491,15 @@ func keyManagerRuntime(ctx *tmapi.Context, regState *registryState.MutableState,
 	return rt, nil
 }
 
+// verifyKeyManagerSecurity verifies that the key manager runs in a TEE if insecure key managers
+// are disabled.
+func verifyKeyManagerSecurity(kmRt *registry.Runtime, kmParams *secrets.ConsensusParameters) error {
+	if kmParams.DisableInsecureKeyManagers && kmRt.TEEHardware == node.TEEHardwareInvalid {
+		return fmt.Errorf("keymanager: insecure key managers are disabled")
+	}
+	return nil
+}
+
 func runtimeAttestationKey(ctx *tmapi.Context, regState *registryState.MutableState, kmRt *registry.Runtime) (*signature.PublicKey, error) {
 	// Ensure that the signer is a key manager.
 	n, err := regState.Node(ctx, ctx.TxSigner())
@@ -354,10 +534,21 @@ func runtimeAttestationKey(ctx *tmapi.Context, regState *registryState.MutableSt
 	return rak, nil
 }
 
-func runtimeEncryptionKeys(ctx *tmapi.Context, regState *registryState.MutableState, kmRt *registry.Runtime, kmStatus *secrets.Status) map[x25519.PublicKey]struct{} {
-	// Fetch REKs of the key manager committee.
+func runtimeEncryptionKeys(ctx context.Context, regState *registryState.ImmutableState, kmRt *registry.Runtime, kmStatus *secrets.Status) map[x25519.PublicKey]struct{} {
 	reks := make(map[x25519.PublicKey]struct{})
-	for _, id := range kmStatus.Nodes {
+	for _, k := range committeeEncryptionKeys(ctx, regState, kmRt, kmStatus) {
+		reks[k.REK] = struct{}{}
+	}
+	return reks
+}
+
+// committeeEncryptionKeys returns the REKs of the key manager committee, sorted by node ID.
+//
+// Standby nodes are included, so that they keep replicating the secrets.
+func committeeEncryptionKeys(ctx context.Context, regState *registryState.ImmutableState, kmRt *registry.Runtime, kmStatus *secrets.Status) []*secrets.RuntimeEncryptionKey {
+	// Fetch REKs of the key manager committee.
+	var reks []*secrets.RuntimeEncryptionKey
+	for _, id := range append(slices.Clone(kmStatus.Nodes), kmStatus.StandbyNodes...) {
 		n, err := regState.Node(ctx, id)
 		if err != nil {
 			continue
@@ -386,8 +577,15 @@ func runtimeEncryptionKeys(ctx *tmapi.Context, regState *registryState.MutableSt
 			continue
 		}
 
-		reks[rek] = struct{}{}
+		reks = append(reks, &secrets.RuntimeEncryptionKey{
+			NodeID: n.ID,
+			REK:    rek,
+		})
 	}
 
+	slices.SortFunc(reks, func(a, b *secrets.RuntimeEncryptionKey) int {
+		return bytes.Compare(a.NodeID[:], b.NodeID[:])
+	})
+
 	return reks
 }
@@ -1,8 +1,11 @@
 package secrets
 
 import (
+	"bytes"
 	"crypto/sha512"
 	"fmt"
+	"math"
+	"slices"
 	"testing"
 
 	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
@@ -15,6 +18,7 @@ import (
 	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
 	"github.com/oasisprotocol/oasis-core/go/common/entity"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
 	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
 	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
 	registryState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/registry/state"
@@ -203,10 +207,54 @@ func TestPublishEphemeralSecret(t *testing.T) {
 		require.EqualError(t, err, "keymanager: runtime is not a key manager: 8000000000000000000000000000000000000000000000000000000000000000")
 	})
 
-	t.Run("node not in the key manager committee", func(t *testing.T) {
+	t.Run("insecure key managers disabled", func(t *testing.T) {
+		var insecureKmID common.Namespace
+		err := insecureKmID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000003")
+		require.NoError(t, err, "failed to unmarshal keymanager id")
+		insecureKmRt := registryAPI.Runtime{
+			ID:          insecureKmID,
+			Kind:        registryAPI.KindKeyManager,
+			TEEHardware: node.TEEHardwareInvalid,
+		}
+		err = regState.SetRuntime(ctx, &insecureKmRt, false)
+		require.NoError(t, err, "registry.SetRuntime")
+
+		err = kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{
+			DisableInsecureKeyManagers: true,
+		})
+		require.NoError(t, err, "api.SetConsensusParameters")
+
+		sigSecret := newSignedSecret()
+		sigSecret.Secret.ID = insecureKmID
+		err = ext.publishEphemeralSecret(txCtx, kmState, sigSecret)
+		require.EqualError(t, err, "keymanager: insecure key managers are disabled")
+
+		// Secure key managers should not be affected.
+		sigSecret = newSignedSecret()
+		sigSecret.Signature = signature.RawSignature{1, 2, 3, 4, 5}
+		err = ext.publishEphemeralSecret(txCtx, kmState, sigSecret)
+		require.EqualError(t, err, "keymanager: sanity check failed: ephemeral secret contains an invalid signature")
+
+		err = kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{})
+		require.NoError(t, err, "api.SetConsensusParameters")
+	})
+
+	t.Run("no key manager committee", func(t *testing.T) {
 		err := kmState.SetStatus(ctx, &secrets.Status{ID: firstKmID})
 		require.NoError(t, err, "SetStatus")
 
+		sigSecret := newSignedSecret()
+		err = ext.publishEphemeralSecret(txCtx, kmState, sigSecret)
+		require.EqualError(t, err, "keymanager: ephemeral secret cannot be published as the key manager committee is empty")
+
+		err = kmState.SetStatus(ctx, &firstKmStatus)
+		require.NoError(t, err, "SetStatus")
+	})
+
+	t.Run("node not in the key manager committee", func(t *testing.T) {
+		err := kmState.SetStatus(ctx, &secrets.Status{ID: firstKmID, Nodes: nodes[1:]})
+		require.NoError(t, err, "SetStatus")
+
 		sigSecret := newSignedSecret()
 		err = ext.publishEphemeralSecret(txCtx, kmState, sigSecret)
 		require.EqualError(t, err, "keymanager: ephemeral secret can be published only by the key manager committee")
@@ -231,6 +279,21 @@ func TestPublishEphemeralSecret(t *testing.T) {
 		require.EqualError(t, err, "keymanager: sanity check failed: secret has to be encrypted with at least one key")
 	})
 
+	t.Run("unknown runtime encryption key", func(t *testing.T) {
+		// Node 2 is not in the committee, so its REK is unknown.
+		err := kmState.SetStatus(ctx, &secrets.Status{ID: firstKmID, Nodes: nodes[:2]})
+		require.NoError(t, err, "SetStatus")
+
+		sigSecret := newSignedSecret()
+		sigSecret.Secret.Secret.Ciphertexts[*reks[2].Public()] = []byte{7, 8, 9}
+
+		err = ext.publishEphemeralSecret(txCtx, kmState, sigSecret)
+		require.EqualError(t, err, "keymanager: sanity check failed: secret is encrypted with an unknown key")
+
+		err = kmState.SetStatus(ctx, &firstKmStatus)
+		require.NoError(t, err, "SetStatus")
+	})
+
 	t.Run("invalid signature", func(t *testing.T) {
 		sigSecret := newSignedSecret()
 		sigSecret.Signature = signature.RawSignature{1, 2, 3, 4, 5}
@@ -253,9 +316,311 @@ func TestPublishEphemeralSecret(t *testing.T) {
 		require.NoError(t, err, "publishEphemeralSecret")
 	})
 
-	t.Run("ephemeral secret already published", func(t *testing.T) {
+	t.Run("ephemeral secret resubmitted", func(t *testing.T) {
+		numEvents := len(txCtx.GetEvents())
+
 		sigSecret := newSignedSecret()
 		err := ext.publishEphemeralSecret(txCtx, kmState, sigSecret)
+		require.NoError(t, err, "publishEphemeralSecret")
+		require.Len(t, txCtx.GetEvents(), numEvents, "resubmissions should not emit events")
+
+		// Resubmissions should still be charged for.
+		err = kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{
+			GasCosts: secrets.DefaultGasCosts,
+		})
+		require.NoError(t, err, "api.SetConsensusParameters")
+		txCtx.SetGasAccountant(abciAPI.NewGasAccountant(transaction.Gas(math.MaxUint64)))
+
+		err = ext.publishEphemeralSecret(txCtx, kmState, sigSecret)
+		require.NoError(t, err, "publishEphemeralSecret")
+		require.EqualValues(t, secrets.DefaultGasCosts[secrets.GasOpPublishEphemeralSecret], txCtx.Gas().GasUsed(), "resubmissions should use gas")
+
+		txCtx.SetGasAccountant(abciAPI.NewNopGasAccountant())
+		err = kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{})
+		require.NoError(t, err, "api.SetConsensusParameters")
+	})
+
+	t.Run("ephemeral secret already published", func(t *testing.T) {
+		sigSecret := newSignedSecret()
+		sigSecret.Secret.Secret.Ciphertexts[*reks[0].Public()] = []byte{7, 8, 9}
+		sig, err := signature.Sign(raks[0], secrets.EncryptedEphemeralSecretSignatureContext, cbor.Marshal(sigSecret.Secret))
+		require.NoError(t, err, "signature.Sign")
+		sigSecret.Signature = sig.Signature
+
+		err = ext.publishEphemeralSecret(txCtx, kmState, sigSecret)
 		require.EqualError(t, err, "keymanager: ephemeral secret can be proposed once per epoch")
 	})
+
+	t.Run("runtime encryption keys", func(t *testing.T) {
+		query := NewQuery(kmState.ImmutableState, regState.ImmutableState, nil, 0)
+
+		keys, err := query.RuntimeEncryptionKeys(ctx, firstKmID)
+		require.NoError(t, err, "RuntimeEncryptionKeys")
+		require.Len(t, keys, numNodes)
+		for i, k := range keys {
+			idx := slices.Index(nodes, k.NodeID)
+			require.NotEqual(t, -1, idx, "REK should belong to a committee member")
+			require.Equal(t, *reks[idx].Public(), k.REK)
+			if i > 0 {
+				require.Equal(t, -1, bytes.Compare(keys[i-1].NodeID[:], k.NodeID[:]), "REKs should be sorted by node ID")
+			}
+		}
+
+		// Nodes without REKs are skipped.
+		keys, err = query.RuntimeEncryptionKeys(ctx, secondKmID)
+		require.NoError(t, err, "RuntimeEncryptionKeys")
+		require.Empty(t, keys)
+
+		_, err = query.RuntimeEncryptionKeys(ctx, runtimeID)
+		require.EqualError(t, err, "keymanager: runtime is not a key manager: 8000000000000000000000000000000000000000000000000000000000000000")
+	})
+}
+
+func TestPublishMasterSecretGeneration(t *testing.T) {
+	// Prepare key manager app.
+	cfg := abciAPI.MockApplicationStateConfig{}
+	appState := abciAPI.NewMockApplicationState(&cfg)
+	ext := secretsExt{
+		state: appState,
+	}
+
+	// Prepare abci contexts.
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+	txCtx := appState.NewContext(abciAPI.ContextDeliverTx)
+	defer txCtx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	regState := registryState.NewMutableState(ctx.State())
+
+	err := kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{})
+	require.NoError(t, err, "api.SetConsensusParameters")
+
+	// Register a key manager runtime.
+	var kmID common.Namespace
+	err = kmID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000001")
+	require.NoError(t, err, "failed to unmarshal keymanager id")
+	kmRt := registryAPI.Runtime{
+		ID:          kmID,
+		Kind:        registryAPI.KindKeyManager,
+		TEEHardware: node.TEEHardwareIntelSGX,
+	}
+	err = regState.SetRuntime(ctx, &kmRt, false)
+	require.NoError(t, err, "registry.SetRuntime")
+
+	// Set the key manager status, allowing rotations in every epoch.
+	signer := memorySigner.NewTestSigner("node signer")
+	err = kmState.SetStatus(ctx, &secrets.Status{
+		ID:            kmID,
+		IsInitialized: true,
+		IsSecure:      true,
+		Generation:    3,
+		Checksum:      []byte{1, 2, 3},
+		Nodes:         []signature.PublicKey{signer.Public()},
+		Policy: &secrets.SignedPolicySGX{
+			Policy: secrets.PolicySGX{
+				MasterSecretRotationInterval: 1,
+			},
+		},
+	})
+	require.NoError(t, err, "keymanager.SetStatus")
+
+	txCtx.SetTxSigner(signer.Public())
+
+	newSecret := func(generation uint64) *secrets.SignedEncryptedMasterSecret {
+		return &secrets.SignedEncryptedMasterSecret{
+			Secret: secrets.EncryptedMasterSecret{
+				ID:         kmID,
+				Generation: generation,
+				Epoch:      1,
+			},
+		}
+	}
+
+	for _, generation := range []uint64{0, 3, 5} {
+		err = ext.publishMasterSecret(txCtx, kmState, newSecret(generation))
+		require.ErrorIs(t, err, secrets.ErrInvalidGeneration, "generation %d should be rejected", generation)
+	}
+	err = ext.publishMasterSecret(txCtx, kmState, newSecret(5))
+	require.EqualError(t, err, "keymanager: invalid master secret generation: (expected: 4, got: 5)")
+
+	// Proposals for the next generation should pass the sequencing check.
+	err = ext.publishMasterSecret(txCtx, kmState, newSecret(4))
+	require.Error(t, err, "publishMasterSecret")
+	require.NotErrorIs(t, err, secrets.ErrInvalidGeneration, "next generation should be accepted")
+}
+
+func TestRefreshStatus(t *testing.T) {
+	require := require.New(t)
+
+	// Prepare key manager app.
+	cfg := abciAPI.MockApplicationStateConfig{
+		CurrentEpoch: 5,
+	}
+	appState := abciAPI.NewMockApplicationState(&cfg)
+	ext := secretsExt{
+		state: appState,
+	}
+
+	// Prepare abci contexts.
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+	txCtx := appState.NewContext(abciAPI.ContextDeliverTx)
+	defer txCtx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	regState := registryState.NewMutableState(ctx.State())
+
+	err := kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{})
+	require.NoError(err, "keymanager.SetConsensusParameters")
+	err = regState.SetConsensusParameters(ctx, &registryAPI.ConsensusParameters{})
+	require.NoError(err, "registry.SetConsensusParameters")
+
+	// Register a key manager runtime.
+	owner := memorySigner.NewTestSigner("owner")
+	var kmID common.Namespace
+	err = kmID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000001")
+	require.NoError(err, "failed to unmarshal keymanager id")
+	kmRt := registryAPI.Runtime{
+		ID:          kmID,
+		EntityID:    owner.Public(),
+		Kind:        registryAPI.KindKeyManager,
+		TEEHardware: node.TEEHardwareIntelSGX,
+	}
+	err = regState.SetRuntime(ctx, &kmRt, false)
+	require.NoError(err, "registry.SetRuntime")
+
+	// Set the key manager status with a committee member that is no longer registered.
+	nodeID := memorySigner.NewTestSigner("node").Public()
+	status := &secrets.Status{
+		ID:            kmID,
+		IsInitialized: true,
+		IsSecure:      true,
+		Nodes:         []signature.PublicKey{nodeID},
+	}
+	err = kmState.SetStatus(ctx, status)
+	require.NoError(err, "keymanager.SetStatus")
+
+	refreshStatus := func() error {
+		return ext.refreshStatus(txCtx, kmState, &secrets.StatusRefresh{
+			ID: kmID,
+		})
+	}
+
+	// Only the key manager owner can refresh the status.
+	txCtx.SetTxSigner(memorySigner.NewTestSigner("not owner").Public())
+	err = refreshStatus()
+	require.EqualError(err, "keymanager: invalid refresh status signer: 8000000000000000000000000000000000000000000000000000000000000001")
+
+	// The status should be regenerated and emitted immediately.
+	txCtx.SetTxSigner(owner.Public())
+	err = refreshStatus()
+	require.NoError(err, "refreshStatus")
+	require.True(txCtx.HasEvent(ext.appName, &secrets.StatusUpdateEvent{}), "status update event should be emitted")
+
+	var ev secrets.StatusUpdateEvent
+	require.NoError(txCtx.DecodeEvent(0, &ev), "DecodeEvent")
+	require.Equal([]secrets.StatusChange{
+		{Kind: secrets.StatusChangeNodeRemoved, NodeID: &nodeID},
+	}, ev.Changes[kmID], "committee change should be reported")
+
+	newStatus, err := kmState.Status(ctx, kmID)
+	require.NoError(err, "keymanager.Status")
+	require.Empty(newStatus.Nodes, "unregistered node should be dropped from the committee")
+
+	// Repeated refreshes in the same epoch should not exceed the committee change limits.
+	err = kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{
+		MaxCommitteeRemovals: 1,
+	})
+	require.NoError(err, "keymanager.SetConsensusParameters")
+	status.Nodes = []signature.PublicKey{
+		memorySigner.NewTestSigner("node 1").Public(),
+		memorySigner.NewTestSigner("node 2").Public(),
+	}
+	err = kmState.SetStatus(ctx, status)
+	require.NoError(err, "keymanager.SetStatus")
+
+	err = refreshStatus()
+	require.NoError(err, "refreshStatus")
+	newStatus, err = kmState.Status(ctx, kmID)
+	require.NoError(err, "keymanager.Status")
+	require.Len(newStatus.Nodes, 1, "only one node should be removed")
+
+	err = refreshStatus()
+	require.NoError(err, "refreshStatus")
+	newStatus, err = kmState.Status(ctx, kmID)
+	require.NoError(err, "keymanager.Status")
+	require.Len(newStatus.Nodes, 1, "no further nodes should be removed in the same epoch")
+	require.Equal(&secrets.CommitteeChanges{Epoch: 5, Removals: 1}, newStatus.CommitteeChanges)
+}
+
+func TestUpdatePolicySizeLimit(t *testing.T) {
+	require := require.New(t)
+
+	// Prepare key manager app.
+	cfg := abciAPI.MockApplicationStateConfig{
+		CurrentEpoch: 1,
+	}
+	appState := abciAPI.NewMockApplicationState(&cfg)
+	ext := secretsExt{
+		state: appState,
+	}
+
+	// Prepare abci contexts.
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+	txCtx := appState.NewContext(abciAPI.ContextCheckTx)
+	defer txCtx.Close()
+
+	// Prepare states.
+	kmState := secretsState.NewMutableState(ctx.State())
+	regState := registryState.NewMutableState(ctx.State())
+
+	// Register a key manager runtime.
+	owner := memorySigner.NewTestSigner("owner")
+	var kmID common.Namespace
+	err := kmID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000001")
+	require.NoError(err, "failed to unmarshal keymanager id")
+	kmRt := registryAPI.Runtime{
+		ID:          kmID,
+		EntityID:    owner.Public(),
+		Kind:        registryAPI.KindKeyManager,
+		TEEHardware: node.TEEHardwareIntelSGX,
+	}
+	err = regState.SetRuntime(ctx, &kmRt, false)
+	require.NoError(err, "registry.SetRuntime")
+
+	sigPol := &secrets.SignedPolicySGX{
+		Policy: secrets.PolicySGX{
+			Serial: 1,
+			ID:     kmID,
+		},
+		Description: "initial policy",
+	}
+	size := uint64(len(cbor.Marshal(sigPol)))
+	txCtx.SetTxSigner(owner.Public())
+
+	for _, tc := range []struct {
+		maxPolicySize uint64
+		err           string
+	}{
+		{0, ""},
+		{size, ""},
+		{size - 1, fmt.Sprintf("keymanager: policy too large (max: %d, got: %d)", size-1, size)},
+	} {
+		err = kmState.SetConsensusParameters(ctx, &secrets.ConsensusParameters{
+			MaxPolicySize: tc.maxPolicySize,
+		})
+		require.NoError(err, "keymanager.SetConsensusParameters")
+
+		err = ext.updatePolicy(txCtx, kmState, sigPol)
+		switch tc.err {
+		case "":
+			require.NoError(err, "updatePolicy (max policy size: %d)", tc.maxPolicySize)
+		default:
+			require.EqualError(err, tc.err, "updatePolicy (max policy size: %d)", tc.maxPolicySize)
+		}
+	}
 }
@@ -193,6 +193,27 @@ func (sc *serviceClient) GetBeacon(ctx context.Context, height int64) ([]byte, e
 	return q.Beacon(ctx)
 }
 
+func (sc *serviceClient) HealthCheck(ctx context.Context) error {
+	params, err := sc.ConsensusParameters(ctx, consensus.HeightLatest)
+	if err != nil {
+		return err
+	}
+
+	// The insecure backend derives the beacon from block data, so it is always available.
+	if params.Backend == beaconAPI.BackendInsecure {
+		return nil
+	}
+
+	b, err := sc.GetBeacon(ctx, consensus.HeightLatest)
+	if err != nil {
+		return fmt.Errorf("beacon: failed to get beacon: %w", err)
+	}
+	if len(b) != beaconAPI.BeaconSize {
+		return beaconAPI.ErrBeaconNotAvailable
+	}
+	return nil
+}
+
 func (sc *serviceClient) GetVRFState(ctx context.Context, height int64) (*beaconAPI.VRFState, error) {
 	q, err := sc.querier.QueryAt(ctx, height)
 	if err != nil {
@@ -44,6 +44,15 @@ func (sc *ServiceClient) GetStatuses(ctx context.Context, height int64) ([]*secr
 	return q.Secrets().Statuses(ctx)
 }
 
+func (sc *ServiceClient) GetHealthSummary(ctx context.Context, height int64) (*secrets.HealthSummary, error) {
+	q, err := sc.querier.QueryAt(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().HealthSummary(ctx)
+}
+
 func (sc *ServiceClient) WatchStatuses() (<-chan *secrets.Status, *pubsub.Subscription) {
 	sub := sc.statusNotifier.Subscribe()
 	ch := make(chan *secrets.Status)
@@ -79,6 +88,96 @@ func (sc *ServiceClient) GetEphemeralSecret(ctx context.Context, query *registry
 	return q.Secrets().EphemeralSecret(ctx, query.ID)
 }
 
+func (sc *ServiceClient) GetRuntimeEncryptionKeys(ctx context.Context, query *registry.NamespaceQuery) ([]*secrets.RuntimeEncryptionKey, error) {
+	q, err := sc.querier.QueryAt(ctx, query.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().RuntimeEncryptionKeys(ctx, query.ID)
+}
+
+func (sc *ServiceClient) GetStatusProjection(ctx context.Context, query *registry.NamespaceQuery) (*secrets.StatusProjection, error) {
+	q, err := sc.querier.QueryAt(ctx, query.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().StatusProjection(ctx, query.ID)
+}
+
+func (sc *ServiceClient) GetPolicy(ctx context.Context, query *registry.NamespaceQuery) (*secrets.SignedPolicySGX, error) {
+	q, err := sc.querier.QueryAt(ctx, query.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().Policy(ctx, query.ID)
+}
+
+func (sc *ServiceClient) GetAdmissionPreview(ctx context.Context, query *secrets.AdmissionPreviewQuery) ([]*secrets.AdmissionRecord, error) {
+	q, err := sc.querier.QueryAt(ctx, query.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().AdmissionPreview(ctx, query.ID, query.NodeIDs)
+}
+
+func (sc *ServiceClient) GetNodePolicyStatus(ctx context.Context, query *secrets.NodePolicyQuery) (*secrets.NodePolicyStatus, error) {
+	q, err := sc.querier.QueryAt(ctx, query.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().NodePolicyStatus(ctx, query.ID, query.NodeID)
+}
+
+func (sc *ServiceClient) GetInitializedStatus(ctx context.Context, query *registry.NamespaceQuery) (*secrets.Status, error) {
+	q, err := sc.querier.QueryAt(ctx, query.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().InitializedStatus(ctx, query.ID)
+}
+
+func (sc *ServiceClient) GetAdmissionRecords(ctx context.Context, query *registry.NamespaceQuery) ([]*secrets.AdmissionRecord, error) {
+	q, err := sc.querier.QueryAt(ctx, query.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().AdmissionRecords(ctx, query.ID)
+}
+
+func (sc *ServiceClient) GetReplicationFailures(ctx context.Context, query *registry.NamespaceQuery) (*secrets.ReplicationFailures, error) {
+	q, err := sc.querier.QueryAt(ctx, query.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().ReplicationFailures(ctx, query.ID)
+}
+
+func (sc *ServiceClient) ConsensusParameters(ctx context.Context, height int64) (*secrets.ConsensusParameters, error) {
+	q, err := sc.querier.QueryAt(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().ConsensusParameters(ctx)
+}
+
+func (sc *ServiceClient) GetEpochTransition(ctx context.Context, height int64) (*secrets.EpochTransition, error) {
+	q, err := sc.querier.QueryAt(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Secrets().EpochTransition(ctx)
+}
+
 func (sc *ServiceClient) WatchMasterSecrets() (<-chan *secrets.SignedEncryptedMasterSecret, *pubsub.Subscription) {
 	sub := sc.mstSecretNotifier.Subscribe()
 	ch := make(chan *secrets.SignedEncryptedMasterSecret)
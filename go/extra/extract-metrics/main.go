@@ -2,158 +2,1897 @@
 package main
 
 import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"go/ast"
+	"go/build/constraint"
+	"go/constant"
 	"go/parser"
 	"go/token"
+	"go/types"
 	"html"
+	"io"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/tools/go/packages"
 )
 
 const (
 	CfgMarkdown               = "markdown"
+	CfgHash                   = "hash"
 	CfgMarkdownTplFile        = "markdown.template.file"
 	CfgMarkdownTplPlaceholder = "markdown.template.placeholder"
+	CfgMarkdownGroupBy        = "markdown.group-by"
 	CfgCodebasePath           = "codebase.path"
 	CfgCodebaseURL            = "codebase.url"
+	CfgPackage                = "package"
+	CfgExclude                = "exclude"
+	CfgInclude                = "include"
+	CfgVerbose                = "verbose"
+	CfgJSONSortLabels         = "json.sort-labels"
+	CfgStabilityPattern       = "stability.pattern"
+	CfgType                   = "type"
+	CfgLint                   = "lint"
+	CfgLintStrict             = "lint.strict"
+	CfgLintCounterAllowlist   = "lint.counter-allowlist"
+	CfgLintFormat             = "lint-format"
+	CfgLintErrorOn            = "error-on"
+	CfgNames                  = "names"
+	CfgByPackage              = "by-package"
+	CfgStream                 = "stream"
+	CfgOutput                 = "output"
+	CfgGzip                   = "gzip"
+	CfgCache                  = "cache"
+	CfgProvenance             = "provenance"
+	CfgCodeowners             = "codeowners"
+
+	// defaultStability is the stability level of metrics without a stability tag.
+	defaultStability = "unspecified"
+
+	// miscGroup is the Markdown section of metrics that don't belong to any group.
+	miscGroup = "Misc"
+
+	// lintFormatText and lintFormatJSON are the human-readable and machine-readable lint
+	// report formats.
+	lintFormatText = "text"
+	lintFormatJSON = "json"
+
+	// cacheVersion is the version of the metric cache format, which must be bumped whenever
+	// the metrics extracted from a file change so that stale caches are discarded.
+	cacheVersion = 3
+
+	// defaultRegistry is the registry of metrics registered with the package-level functions
+	// of the prometheus package, which register them into prometheus.DefaultRegisterer.
+	defaultRegistry = "default"
 )
 
 var (
 	scriptName = filepath.Base(os.Args[0])
 
-	rootCmd = &cobra.Command{
-		Use:   scriptName,
-		Short: "Extracts Prometheus metrics from .go code.",
-		Long: `This tool parses .go source files in the given codebase path
-and generates a set of registered Prometheus metrics. By default it outputs JSON formatted metrics
-map. You can also provide --markdown flag and it will print a Markdown-formatted table of metrics
-useful for embedding into other Markdown files. Additionally, you can use --markdown.template.file
-and it will embed the table in place of the placeholder in the provided template file.`,
-		Example: "./extract-metrics --codebase.path ../.. --markdown",
-		Run:     doExtractMetrics,
+	// output is where the extracted metrics are written to.
+	output io.Writer = os.Stdout
+
+	// metricTypes are the supported metric types, without the Vec suffix.
+	metricTypes = []string{"Counter", "Gauge", "Histogram", "Summary"}
+
+	// lintFormats are the supported lint report formats.
+	lintFormats = []string{lintFormatText, lintFormatJSON}
+
+	// lintRules are the IDs of the lint rules.
+	lintRules = []string{"labels-without-vec", "counter-suffix", "label-names", "duplicate", "duplicate-conflict", "duplicate-type"}
+
+	// markdownGroups are the supported ways of grouping metrics into Markdown sections.
+	markdownGroups = []string{"package", "subsystem", "type"}
+
+	// defaultExclude are the default glob patterns of file and directory names skipped when
+	// scanning the codebase.
+	defaultExclude = []string{"vendor", "testdata", "*_test.go", "*.pb.go", "*_gen.go"}
+
+	rootCmd = &cobra.Command{
+		Use:   scriptName,
+		Short: "Extracts Prometheus metrics from .go code.",
+		Long: `This tool parses .go source files in the given codebase path
+and generates a set of registered Prometheus metrics. By default it outputs JSON formatted metrics
+map. You can also provide --markdown flag and it will print a Markdown-formatted table of metrics
+useful for embedding into other Markdown files. Additionally, you can use --markdown.template.file
+and it will embed the table in place of the placeholder in the provided template file.
+Use --markdown.group-by to split the table into sections grouped by package, subsystem or type.
+Use --hash to only print a stable hash of the extracted metric set, useful for detecting changes.
+The JSON output preserves the source order of metric labels, use --json.sort-labels to sort
+them for diff-friendly output.
+Files and directories matching any of the --exclude glob patterns are skipped. Use --include to
+only scan the files whose paths relative to the codebase path, or any of their parent directories,
+match any of the given glob patterns (e.g. --include worker,consensus/*), before the exclusions.
+The stability level of each metric is extracted from its doc comment using the
+--stability.pattern regular expression (e.g. // metric:stable).
+Use --type to only output metrics of the given types (e.g. --type Histogram,Summary).
+Use --lint to check the metrics for common instrumentation errors instead, and --lint.strict
+to also fail on warnings (e.g. inconsistently named labels). Counters must end in _total,
+use --lint.counter-allowlist to exempt legacy counter names. Metrics defined more than once are
+reported as warnings, or as errors if the definitions disagree on their help text, labels
+or type.
+Use --lint-format json to print the lint findings as a JSON array with the rule ID, severity,
+metric name, location and message of each finding, e.g. for annotating pull requests in CI.
+Use --error-on to escalate the warnings of the given lint rules to errors (e.g. --error-on
+label-names,duplicate), leaving the warnings of the other rules as they are.
+Use --names to only print the sorted metric names, one per line.
+Use --by-package to print a JSON index of the sorted metric names keyed by their Go package path.
+Use --stream to print the metrics as newline-delimited JSON as soon as they are discovered,
+without buffering the whole metric set in memory. Metrics defined more than once are only
+streamed at their first definition, without the locations of the other definitions.
+Use --output to write the output to a file instead of stdout, and --gzip to compress it.
+Use --cache to keep the metrics found in each file in the given cache file, so that files whose
+content hasn't changed are not parsed again on subsequent runs.
+Use --provenance to record the generation time and, if the codebase path is in a git repository,
+its revision in the header of the generated Markdown file and in the JSON output, which then
+holds them under "metadata" with the metrics under "metrics".
+Use --codeowners to tag each metric with the owners of the file defining it according to the
+given CODEOWNERS file, which are included in the JSON output and as a Markdown column.
+The JSON output includes the variable each metric is assigned to and whether it is exported.
+It also includes the registry each metric is registered into, if it can be determined from the
+file defining the metric, with "default" denoting the default Prometheus registry.
+Metrics defined more than once are reported at their first definition, with the locations of
+all definitions listed in their sources.
+The JSON output includes the //go:build constraint of metrics defined in files with one, which
+is also noted in the Markdown descriptions.
+Help texts built with fmt.Sprintf from constant arguments are resolved to the final string.
+Use --package to load the given packages (e.g. github.com/oasisprotocol/oasis-core/go/...) with
+the Go package loader instead of walking the codebase path, which is slower but resolves
+constants accurately across packages.`,
+		Example: "./extract-metrics --codebase.path ../.. --markdown",
+		Run:     doExtractMetrics,
+	}
+)
+
+type Metric struct {
+	Name            string     `json:"name"`
+	Type            string     `json:"type"`
+	Help            string     `json:"help"`
+	Labels          []string   `json:"labels"`
+	Objectives      Objectives `json:"objectives,omitempty"`
+	Filename        string     `json:"filename"`
+	Line            int        `json:"line"`
+	Column          int        `json:"column"`
+	Vec             bool       `json:"vec"`
+	Stability       string     `json:"stability"`
+	Variable        string     `json:"variable,omitempty"`
+	Exported        *bool      `json:"exported,omitempty"`
+	BuildConstraint string     `json:"build_constraint,omitempty"`
+	Registry        string     `json:"registry,omitempty"`
+	Owner           string     `json:"owner,omitempty"`
+
+	// Sources are the locations of all definitions of the metric in the order they were
+	// discovered, if it is defined more than once. Filename and Line refer to the first one.
+	Sources []SourceLoc `json:"sources,omitempty"`
+
+	// nameRef is the reference to the constant defining the metric name in another package,
+	// resolved once the whole codebase has been scanned.
+	nameRef *constRef
+
+	// nameExpr and helpExpr are the expressions defining the metric name and help text, used
+	// to resolve them with type information when loading packages.
+	nameExpr ast.Expr
+	helpExpr ast.Expr
+}
+
+// SourceLoc is the location of a metric definition.
+type SourceLoc struct {
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+// source returns the location of the metric definition.
+func (m Metric) source() SourceLoc {
+	return SourceLoc{
+		Filename: m.Filename,
+		Line:     m.Line,
+		Column:   m.Column,
+	}
+}
+
+// constRef is a reference to a constant declared in another package.
+type constRef struct {
+	pkg        string
+	importPath string
+	name       string
+}
+
+// String returns the reference as it appears in the source.
+func (r constRef) String() string {
+	return r.pkg + "." + r.name
+}
+
+// constIndex is an index of exported string constants, keyed by the slash-separated package
+// directory relative to the codebase path and the constant name.
+type constIndex map[string]map[string]string
+
+// add indexes the given constants declared in the given package directory.
+func (ci constIndex) add(dir string, consts map[string]string) {
+	if len(consts) == 0 {
+		return
+	}
+	if ci[dir] == nil {
+		ci[dir] = make(map[string]string)
+	}
+	for name, val := range consts {
+		ci[dir][name] = val
+	}
+}
+
+// fileConsts returns the exported string constants declared in the given file.
+func fileConsts(src *ast.File) map[string]string {
+	consts := make(map[string]string)
+	for _, decl := range src.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs, okVS := spec.(*ast.ValueSpec)
+			if !okVS || len(vs.Names) != len(vs.Values) {
+				continue
+			}
+			for i, name := range vs.Names {
+				lit, okLit := vs.Values[i].(*ast.BasicLit)
+				if !name.IsExported() || !okLit || lit.Kind != token.STRING {
+					continue
+				}
+				val, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					continue
+				}
+				consts[name.Name] = val
+			}
+		}
+	}
+	return consts
+}
+
+// resolve returns the value of the referenced constant, matching the import path against
+// the indexed package directories. The longest matching directory is used, as shorter ones,
+// e.g. "api" for ".../registry/api", may belong to unrelated packages.
+func (ci constIndex) resolve(ref constRef) (string, bool) {
+	var best string
+	for dir := range ci {
+		if dir == "." || len(dir) <= len(best) {
+			continue
+		}
+		if ref.importPath == dir || strings.HasSuffix(ref.importPath, "/"+dir) {
+			best = dir
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	val, ok := ci[best][ref.name]
+	return val, ok
+}
+
+// fileImports returns the import paths of the given file, keyed by the package name
+// they are referenced by.
+func fileImports(src *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, imp := range src.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := filepath.Base(path)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// MetricSet is a set of extracted metrics keyed by metric name.
+type MetricSet map[string]Metric
+
+// Canonical returns a canonical copy of the metric set, suitable for stable serialization.
+//
+// Metrics are serialized in the order of their names and metric fields in the order of
+// declaration. If sortLabels is set, the labels of each metric are sorted as well, otherwise
+// they are kept in source order.
+func (ms MetricSet) Canonical(sortLabels bool) MetricSet {
+	canonical := make(MetricSet, len(ms))
+	for k, m := range ms {
+		canonical[k] = m.Canonical(sortLabels)
+	}
+	return canonical
+}
+
+// Canonical returns a canonical copy of the metric, optionally with sorted labels.
+func (m Metric) Canonical(sortLabels bool) Metric {
+	m.Labels = slices.Clone(m.Labels)
+	if sortLabels {
+		sort.Strings(m.Labels)
+	}
+	return m
+}
+
+// Objectives are the quantile objectives of a summary metric, mapping quantiles to their
+// absolute error.
+type Objectives map[float64]float64
+
+// MarshalJSON encodes objectives as a JSON object keyed by the formatted quantile.
+func (o Objectives) MarshalJSON() ([]byte, error) {
+	m := make(map[string]float64, len(o))
+	for q, e := range o {
+		m[strconv.FormatFloat(q, 'f', -1, 64)] = e
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes objectives from a JSON object keyed by the formatted quantile.
+func (o *Objectives) UnmarshalJSON(data []byte) error {
+	var m map[string]float64
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	if m == nil {
+		*o = nil
+		return nil
+	}
+	*o = make(Objectives, len(m))
+	for k, e := range m {
+		q, err := strconv.ParseFloat(k, 64)
+		if err != nil {
+			return fmt.Errorf("invalid objective quantile %q: %w", k, err)
+		}
+		(*o)[q] = e
+	}
+	return nil
+}
+
+// Percentiles returns the sorted objective quantiles formatted as percentiles (e.g. p50, p99).
+func (o Objectives) Percentiles() []string {
+	qs := make([]float64, 0, len(o))
+	for q := range o {
+		qs = append(qs, q)
+	}
+	sort.Float64s(qs)
+
+	ps := make([]string, 0, len(qs))
+	for _, q := range qs {
+		ps = append(ps, "p"+strconv.FormatFloat(q*100, 'f', -1, 64))
+	}
+	return ps
+}
+
+// metricPackage returns the package of the metric, relative to the codebase path.
+func metricPackage(m Metric) string {
+	pkg, _ := filepath.Rel(viper.GetString(CfgCodebasePath), m.Filename)
+	return filepath.Dir(pkg)
+}
+
+// metricSubsystem returns the subsystem of the metric, i.e. the second component of
+// the fully-qualified metric name (namespace_subsystem_name), or an empty string if
+// the name has no subsystem.
+func metricSubsystem(m Metric) string {
+	parts := strings.SplitN(m.Name, "_", 3)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
+
+// groupMetrics groups the metrics by the given criterion. Metrics that don't belong to any
+// group are grouped under miscGroup.
+func groupMetrics(metrics map[string]Metric, groupBy string) map[string]map[string]Metric {
+	groups := make(map[string]map[string]Metric)
+	for k, m := range metrics {
+		var group string
+		switch groupBy {
+		case "package":
+			group = metricPackage(m)
+		case "subsystem":
+			group = metricSubsystem(m)
+		case "type":
+			group = m.Type
+		}
+		if group == "" || group == "." {
+			group = miscGroup
+		}
+		if groups[group] == nil {
+			groups[group] = make(map[string]Metric)
+		}
+		groups[group][k] = m
+	}
+	return groups
+}
+
+func markdownTable(metrics map[string]Metric) string {
+	// The percentiles and stability columns are only included if any metric has them, so that
+	// all tables have the same columns.
+	var withPercentiles, withStability bool
+	for _, m := range metrics {
+		withPercentiles = withPercentiles || len(m.Objectives) > 0
+		withStability = withStability || (m.Stability != "" && m.Stability != defaultStability)
+	}
+
+	groupBy := viper.GetString(CfgMarkdownGroupBy)
+	if groupBy == "" {
+		return markdownGroupTable(metrics, withPercentiles, withStability)
+	}
+
+	groups := groupMetrics(metrics, groupBy)
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		if name != miscGroup {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := groups[miscGroup]; ok {
+		names = append(names, miscGroup)
+	}
+
+	var mdTables []string
+	for _, name := range names {
+		mdTables = append(mdTables, fmt.Sprintf("### %s\n\n%s", name, markdownGroupTable(groups[name], withPercentiles, withStability)))
+	}
+	return strings.Join(mdTables, "\n")
+}
+
+func markdownGroupTable(metrics map[string]Metric, withPercentiles, withStability bool) string {
+	var ordKeys []string
+	for k := range metrics {
+		ordKeys = append(ordKeys, k)
+	}
+	sort.Slice(ordKeys, func(i, j int) bool {
+		return metrics[ordKeys[i]].Name < metrics[ordKeys[j]].Name
+	})
+
+	baseDir := viper.GetString(CfgCodebasePath)
+	if viper.IsSet(CfgMarkdownTplFile) && !viper.IsSet(CfgCodebaseURL) {
+		baseDir = filepath.Dir(viper.GetString(CfgMarkdownTplFile))
+	}
+
+	// The owner column is only included if the owners are known.
+	withOwner := viper.GetString(CfgCodeowners) != ""
+
+	mdTable := "Name | Type | Description | Labels"
+	mdSep := "-----|------|-------------|-------"
+	if withPercentiles {
+		mdTable += " | Percentiles"
+		mdSep += "-|------------"
+	}
+	if withStability {
+		mdTable += " | Stability"
+		mdSep += "-|----------"
+	}
+	mdTable += " | Package"
+	mdSep += "-|--------"
+	if withOwner {
+		mdTable += " | Owner"
+		mdSep += "|------"
+	}
+	mdTable += "\n" + mdSep + "\n"
+	for _, k := range ordKeys {
+		m := metrics[k]
+		pkg := metricPackage(m)
+		fileURL, _ := filepath.Rel(baseDir, m.Filename)
+		if viper.IsSet(CfgCodebaseURL) {
+			fileURL = viper.GetString(CfgCodebaseURL) + fileURL
+		}
+		desc := html.EscapeString(m.Help)
+		if m.BuildConstraint != "" {
+			// Escape the pipes of OR expressions, which would otherwise split the table cell.
+			desc += fmt.Sprintf(" _(only in builds matching `%s`)_", strings.ReplaceAll(m.BuildConstraint, "|", `\|`))
+		}
+		labels := strings.Join(m.Labels, ", ")
+
+		mdTable += fmt.Sprintf("%s | %s | %s | %s", m.Name, m.Type, desc, labels)
+		if withPercentiles {
+			mdTable += " | " + strings.Join(m.Objectives.Percentiles(), ", ")
+		}
+		if withStability {
+			mdTable += " | " + m.Stability
+		}
+		mdTable += fmt.Sprintf(" | [%s](%s)", pkg, fileURL)
+		if withOwner {
+			mdTable += " | " + m.Owner
+		}
+		mdTable += "\n"
+	}
+
+	return mdTable
+}
+
+func printMarkdown(metrics map[string]Metric) {
+	mdTable := markdownTable(metrics)
+
+	if !viper.IsSet(CfgMarkdownTplFile) {
+		// Print Markdown table only.
+		fmt.Fprint(output, mdTable)
+		return
+	}
+
+	md, err := os.ReadFile(viper.GetString(CfgMarkdownTplFile))
+	if err != nil {
+		panic(err)
+	}
+	mdStr, err := embedMarkdownTable(string(md), viper.GetString(CfgMarkdownTplPlaceholder), mdTable, outputProvenance())
+	if err != nil {
+		log.Fatalf("invalid markdown template %s: %v", viper.GetString(CfgMarkdownTplFile), err)
+	}
+
+	fmt.Fprint(output, mdStr)
+}
+
+// embedMarkdownTable replaces the placeholder line in the Markdown template with the table
+// and prepends the generated file header, including the provenance if given. It fails if the
+// placeholder line is missing, so that a renamed placeholder doesn't silently produce a file
+// without the table.
+func embedMarkdownTable(tpl, placeholder, table string, prov *Provenance) (string, error) {
+	if !strings.Contains(tpl, placeholder+"\n") {
+		return "", fmt.Errorf("placeholder %q not found on its own line", placeholder)
+	}
+	mdStr := fmt.Sprintf("---\n# DO NOT EDIT. This file was generated by %s\n", scriptName)
+	if prov != nil {
+		mdStr += fmt.Sprintf("# Generated at: %s\n", prov.GeneratedAt.Format(time.RFC3339))
+		if prov.Revision != "" {
+			mdStr += fmt.Sprintf("# Revision: %s\n", prov.Revision)
+		}
+	}
+	mdStr += "---\n\n"
+	mdStr += strings.Replace(tpl, placeholder+"\n", table, 1)
+	return mdStr, nil
+}
+
+// Provenance records when and from which revision of the codebase the output was generated.
+type Provenance struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Revision    string    `json:"revision,omitempty"`
+}
+
+// outputProvenance returns the provenance of the output, or nil if it is not requested.
+func outputProvenance() *Provenance {
+	if !viper.GetBool(CfgProvenance) {
+		return nil
+	}
+	return &Provenance{
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+		Revision:    gitRevision(viper.GetString(CfgCodebasePath)),
+	}
+}
+
+// gitRevision returns the revision of the git repository containing the given directory, or
+// an empty string if it is not in a git repository or git is not available.
+func gitRevision(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func printJSON(m MetricSet) {
+	var v interface{} = m.Canonical(viper.GetBool(CfgJSONSortLabels))
+	if prov := outputProvenance(); prov != nil {
+		v = struct {
+			Metadata *Provenance `json:"metadata"`
+			Metrics  interface{} `json:"metrics"`
+		}{prov, v}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(output, "%s", data)
+}
+
+func printNames(metrics MetricSet) {
+	names := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(output, name)
+	}
+}
+
+// readModulePath returns the module path declared in the go.mod file in the given directory,
+// or an empty string if there is none.
+func readModulePath(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// packageIndex returns the sorted metric names keyed by the Go package path of the metrics.
+// Package paths are prefixed with the given module path, if any.
+func packageIndex(metrics MetricSet, modulePath string) map[string][]string {
+	index := make(map[string][]string)
+	for _, m := range metrics {
+		pkg := filepath.ToSlash(metricPackage(m))
+		switch {
+		case modulePath == "":
+		case pkg == ".":
+			pkg = modulePath
+		default:
+			pkg = modulePath + "/" + pkg
+		}
+		index[pkg] = append(index[pkg], m.Name)
+	}
+	for _, names := range index {
+		sort.Strings(names)
+	}
+	return index
+}
+
+func printByPackage(metrics MetricSet) {
+	index := packageIndex(metrics, readModulePath(viper.GetString(CfgCodebasePath)))
+	data, err := json.Marshal(index)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(output, "%s", data)
+}
+
+// canonicalMetric is the subset of metric fields that contribute to the metric set hash.
+type canonicalMetric struct {
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	Help       string     `json:"help"`
+	Labels     []string   `json:"labels"`
+	Objectives Objectives `json:"objectives,omitempty"`
+	Vec        bool       `json:"vec"`
+	Stability  string     `json:"stability"`
+}
+
+// metricsHash computes a SHA-256 hash over the canonicalized metric set.
+//
+// Only the name, type, help, labels, objectives and stability of each metric are taken into
+// account, so the hash does not depend on the location of the codebase or the position of the
+// definitions.
+func metricsHash(metrics map[string]Metric) string {
+	canonical := make([]canonicalMetric, 0, len(metrics))
+	for _, m := range metrics {
+		labels := append([]string{}, m.Labels...)
+		sort.Strings(labels)
+		canonical = append(canonical, canonicalMetric{
+			Name:       m.Name,
+			Type:       m.Type,
+			Help:       m.Help,
+			Labels:     labels,
+			Objectives: m.Objectives,
+			Vec:        m.Vec,
+			Stability:  m.Stability,
+		})
+	}
+	sort.Slice(canonical, func(i, j int) bool {
+		return canonical[i].Name < canonical[j].Name
+	})
+
+	data, err := json.Marshal(canonical)
+	if err != nil {
+		panic(err)
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func printHash(m map[string]Metric) {
+	fmt.Fprintln(output, metricsHash(m))
+}
+
+var metrics = MetricSet{}
+
+// duplicates are all the definitions of metrics that are defined more than once, keyed by
+// the metric name. Only the first definition is kept in metrics.
+var duplicates = make(map[string][]Metric)
+
+// collectMetric adds the metric to the metric set. Redefinitions of a metric with the same name
+// are recorded as duplicates and in the sources of the first definition, which is kept so that
+// the reported location doesn't depend on the scanning order of the remaining ones.
+func collectMetric(m Metric) {
+	prev, ok := metrics[m.Name]
+	if !ok {
+		metrics[m.Name] = m
+		return
+	}
+
+	if len(duplicates[m.Name]) == 0 {
+		duplicates[m.Name] = []Metric{prev}
+	}
+	duplicates[m.Name] = append(duplicates[m.Name], m)
+
+	if len(prev.Sources) == 0 {
+		prev.Sources = []SourceLoc{prev.source()}
+	}
+	prev.Sources = append(prev.Sources, m.source())
+	metrics[m.Name] = prev
+}
+
+// filterByType returns the metrics of the given types. Vec metrics match their base type.
+//
+// If no types are given, all metrics are returned.
+func filterByType(metrics MetricSet, types []string) MetricSet {
+	if len(types) == 0 {
+		return metrics
+	}
+
+	filtered := make(MetricSet)
+	for k, m := range metrics {
+		if slices.Contains(types, m.Type) {
+			filtered[k] = m
+		}
+	}
+	return filtered
+}
+
+// sortByLocation returns the metrics sorted by their location.
+func sortByLocation(metrics MetricSet) []Metric {
+	var sorted []Metric
+	for _, m := range metrics {
+		sorted = append(sorted, m)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Filename != sorted[j].Filename {
+			return sorted[i].Filename < sorted[j].Filename
+		}
+		return sorted[i].Line < sorted[j].Line
+	})
+	return sorted
+}
+
+// Lint finding severities.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// Finding is a lint finding.
+type Finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Metric   string `json:"metric,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+// String returns the human-readable form of the finding, prefixed with its location if any.
+func (f Finding) String() string {
+	if f.Filename == "" {
+		return f.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", f.Filename, f.Line, f.Message)
+}
+
+// lintMetrics checks the metrics for common instrumentation errors and returns the found
+// issues, sorted by their location.
+func lintMetrics(metrics MetricSet) []Finding {
+	var issues []Finding
+	for _, m := range sortByLocation(metrics) {
+		if !m.Vec && len(m.Labels) > 0 {
+			issues = append(issues, Finding{
+				Rule:     "labels-without-vec",
+				Severity: severityError,
+				Metric:   m.Name,
+				Filename: m.Filename,
+				Line:     m.Line,
+				Message:  fmt.Sprintf("metric %s is not a vec but declares labels: %s", m.Name, strings.Join(m.Labels, ", ")),
+			})
+		}
+	}
+	return issues
+}
+
+// lintCounterNames returns issues about counters whose names don't end in _total, sorted by
+// their location. Counters in the allowlist are not checked.
+func lintCounterNames(metrics MetricSet, allowlist []string) []Finding {
+	var issues []Finding
+	for _, m := range sortByLocation(metrics) {
+		if m.Type != "Counter" || strings.HasSuffix(m.Name, "_total") || slices.Contains(allowlist, m.Name) {
+			continue
+		}
+		issues = append(issues, Finding{
+			Rule:     "counter-suffix",
+			Severity: severityError,
+			Metric:   m.Name,
+			Filename: m.Filename,
+			Line:     m.Line,
+			Message:  fmt.Sprintf("counter %s does not end in _total", m.Name),
+		})
+	}
+	return issues
+}
+
+// lintLabelNames returns warnings about distinct label names that collide when case and
+// underscores are ignored (e.g. runtime_id and runtimeID), listing the metrics using them.
+func lintLabelNames(metrics MetricSet) []Finding {
+	// Normalized label name -> label name -> metric names.
+	groups := make(map[string]map[string][]string)
+	for _, m := range metrics {
+		for _, l := range m.Labels {
+			norm := strings.ToLower(strings.ReplaceAll(l, "_", ""))
+			if groups[norm] == nil {
+				groups[norm] = make(map[string][]string)
+			}
+			groups[norm][l] = append(groups[norm][l], m.Name)
+		}
+	}
+
+	var warnings []Finding
+	for _, labels := range groups {
+		if len(labels) < 2 {
+			continue
+		}
+		var uses []string
+		for l, names := range labels {
+			sort.Strings(names)
+			uses = append(uses, fmt.Sprintf("%s (%s)", l, strings.Join(names, ", ")))
+		}
+		sort.Strings(uses)
+		warnings = append(warnings, Finding{
+			Rule:     "label-names",
+			Severity: severityWarning,
+			Message:  "inconsistently named labels: " + strings.Join(uses, ", "),
+		})
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Message < warnings[j].Message
+	})
+	return warnings
+}
+
+// lintDuplicates checks the metrics defined more than once. Definitions that disagree on the
+// help text or labels are returned as issues, listing all definitions, while benign
+// re-registrations are only returned as warnings. Duplicates of metrics not in the metric set
+// are ignored.
+func lintDuplicates(metrics MetricSet, duplicates map[string][]Metric) ([]Finding, []Finding) {
+	names := make([]string, 0, len(duplicates))
+	for name := range duplicates {
+		if _, ok := metrics[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var issues, warnings []Finding
+	for _, name := range names {
+		defs := duplicates[name]
+		if hasTypeConflict(defs) {
+			// Reported by lintDuplicateTypes.
+			continue
+		}
+		var conflicting bool
+		locations := make([]string, 0, len(defs))
+		for _, m := range defs {
+			if m.Help != defs[0].Help || !sameLabels(m.Labels, defs[0].Labels) {
+				conflicting = true
+			}
+			locations = append(locations, fmt.Sprintf("%s:%d", m.Filename, m.Line))
+		}
+		if !conflicting {
+			warnings = append(warnings, Finding{
+				Rule:     "duplicate",
+				Severity: severityWarning,
+				Metric:   name,
+				Message:  fmt.Sprintf("metric %s is defined multiple times: %s", name, strings.Join(locations, ", ")),
+			})
+			continue
+		}
+
+		details := make([]string, 0, len(defs))
+		for _, m := range defs {
+			details = append(details, fmt.Sprintf("\n\t%s:%d: help %q, labels [%s]",
+				m.Filename, m.Line, m.Help, strings.Join(m.Labels, ", ")))
+		}
+		issues = append(issues, Finding{
+			Rule:     "duplicate-conflict",
+			Severity: severityError,
+			Metric:   name,
+			Message:  fmt.Sprintf("metric %s has conflicting definitions:%s", name, strings.Join(details, "")),
+		})
+	}
+	return issues, warnings
+}
+
+// lintDuplicateTypes returns issues about metrics registered with different types, listing all
+// definitions. Prometheus rejects such metrics at runtime, so these are always errors. Duplicates
+// of metrics not in the metric set are ignored.
+func lintDuplicateTypes(metrics MetricSet, duplicates map[string][]Metric) []Finding {
+	names := make([]string, 0, len(duplicates))
+	for name, defs := range duplicates {
+		if _, ok := metrics[name]; ok && hasTypeConflict(defs) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	issues := make([]Finding, 0, len(names))
+	for _, name := range names {
+		defs := duplicates[name]
+		details := make([]string, 0, len(defs))
+		for _, m := range defs {
+			details = append(details, fmt.Sprintf("\n\t%s:%d: %s", m.Filename, m.Line, m.Type))
+		}
+		issues = append(issues, Finding{
+			Rule:     "duplicate-type",
+			Severity: severityError,
+			Metric:   name,
+			Message:  fmt.Sprintf("metric %s is registered with different types:%s", name, strings.Join(details, "")),
+		})
+	}
+	return issues
+}
+
+// sameLabels returns true iff the given label names are the same, regardless of their order.
+func sameLabels(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	sort.Strings(a)
+	sort.Strings(b)
+	return slices.Equal(a, b)
+}
+
+// hasTypeConflict returns true iff the given definitions of a metric disagree on its type.
+func hasTypeConflict(defs []Metric) bool {
+	for _, m := range defs {
+		if m.Type != defs[0].Type {
+			return true
+		}
+	}
+	return false
+}
+
+// lintFindings runs all lint checks and returns the found issues followed by the warnings.
+func lintFindings(metrics MetricSet) []Finding {
+	findings := lintMetrics(metrics)
+	findings = append(findings, lintCounterNames(metrics, viper.GetStringSlice(CfgLintCounterAllowlist))...)
+	findings = append(findings, lintDuplicateTypes(metrics, duplicates)...)
+	dupIssues, dupWarnings := lintDuplicates(metrics, duplicates)
+	findings = append(findings, dupIssues...)
+	findings = append(findings, lintLabelNames(metrics)...)
+	findings = append(findings, dupWarnings...)
+	return escalateFindings(findings, viper.GetStringSlice(CfgLintErrorOn))
+}
+
+// escalateFindings escalates the warnings of the given lint rules to errors.
+func escalateFindings(findings []Finding, rules []string) []Finding {
+	for i, f := range findings {
+		if f.Severity == severityWarning && slices.Contains(rules, f.Rule) {
+			findings[i].Severity = severityError
+		}
+	}
+	return findings
+}
+
+// printLint prints the lint findings in the configured format and returns true iff the lint
+// check failed.
+func printLint(metrics MetricSet) bool {
+	findings := lintFindings(metrics)
+
+	var failed bool
+	for _, f := range findings {
+		if f.Severity == severityError || viper.GetBool(CfgLintStrict) {
+			failed = true
+		}
+	}
+
+	switch viper.GetString(CfgLintFormat) {
+	case lintFormatJSON:
+		if findings == nil {
+			findings = []Finding{}
+		}
+		data, err := json.Marshal(findings)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(output, "%s\n", data)
+	default:
+		for _, f := range findings {
+			if f.Severity == severityWarning {
+				fmt.Fprintln(output, "warning: "+f.String())
+				continue
+			}
+			fmt.Fprintln(output, f.String())
+		}
+	}
+	return failed
+}
+
+// openOutput redirects the output to the configured file, optionally gzip compressed, and
+// returns a function that flushes and closes it.
+func openOutput() (func() error, error) {
+	var closers []io.Closer
+	if path := viper.GetString(CfgOutput); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		output = f
+		closers = append(closers, f)
+	}
+	if viper.GetBool(CfgGzip) {
+		zw := gzip.NewWriter(output)
+		output = zw
+		closers = append(closers, zw)
+	}
+
+	return func() error {
+		// Close in reverse order, so that the compressed stream is flushed before the file
+		// is closed.
+		for i := len(closers) - 1; i >= 0; i-- {
+			if err := closers[i].Close(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// codeownersRule is a rule of a CODEOWNERS file.
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeowners are the rules of a CODEOWNERS file, with paths relative to the repository root.
+type codeowners struct {
+	root  string
+	rules []codeownersRule
+}
+
+// loadCodeowners loads the CODEOWNERS file at the given path. The file is expected to be in the
+// root, .github or docs directory of the repository, as on GitHub.
+func loadCodeowners(path string) (*codeowners, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	root, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	if base := filepath.Base(root); base == ".github" || base == "docs" {
+		root = filepath.Dir(root)
+	}
+
+	co, err := parseCodeowners(f)
+	if err != nil {
+		return nil, err
+	}
+	co.root = root
+	return co, nil
+}
+
+// parseCodeowners parses the rules of a CODEOWNERS file.
+func parseCodeowners(r io.Reader) (*codeowners, error) {
+	var co codeowners
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		for i, f := range fields {
+			if strings.HasPrefix(f, "#") {
+				fields = fields[:i]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		co.rules = append(co.rules, codeownersRule{
+			pattern: codeownersPattern(fields[0]),
+			owners:  fields[1:],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &co, nil
+}
+
+// codeownersPattern converts a CODEOWNERS path pattern into a regular expression matching
+// slash-separated paths relative to the repository root.
+//
+// Patterns follow the gitignore rules used by GitHub: patterns starting with or containing a
+// slash are relative to the root, others match at any level, * and ? don't match slashes
+// and ** matches any number of directories. Patterns match the files in the directories they
+// match, except for patterns ending in /*, which only match files directly in the directory.
+func codeownersPattern(pattern string) *regexp.Regexp {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	switch {
+	case dirOnly:
+		expr.WriteString("/.*$")
+	case strings.HasSuffix(pattern, "/*"):
+		expr.WriteString("$")
+	default:
+		expr.WriteString("(?:/.*)?$")
+	}
+	return regexp.MustCompile(expr.String())
+}
+
+// owner returns the owners of the given slash-separated path relative to the repository root,
+// separated by spaces. The last matching rule wins, and paths without a matching rule have
+// no owners.
+func (co *codeowners) owner(path string) string {
+	for i := len(co.rules) - 1; i >= 0; i-- {
+		if co.rules[i].pattern.MatchString(path) {
+			return strings.Join(co.rules[i].owners, " ")
+		}
+	}
+	return ""
+}
+
+// tag returns a collect function that tags each metric with the owners of the file defining
+// it before passing it to the given collect function.
+func (co *codeowners) tag(collect func(Metric)) func(Metric) {
+	return func(m Metric) {
+		if path, err := filepath.Abs(m.Filename); err == nil {
+			if rel, err := filepath.Rel(co.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+				m.Owner = co.owner(filepath.ToSlash(rel))
+			}
+		}
+		collect(m)
+	}
+}
+
+// isIncluded returns true iff the given slash-separated path relative to the codebase path, or
+// any of its parent directories, matches any of the patterns. All paths are included if there
+// are no patterns.
+func isIncluded(path string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for ; path != "." && path != "/" && path != ""; path = filepath.ToSlash(filepath.Dir(path)) {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, path); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isExcluded returns true iff the given file or directory name matches any of the patterns.
+func isExcluded(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// streamJSON returns a function that prints each metric as a line of JSON. Like in the buffered
+// output, metrics defined more than once are only printed at their first definition.
+func streamJSON(types []string) func(Metric) {
+	enc := json.NewEncoder(output)
+	sortLabels := viper.GetBool(CfgJSONSortLabels)
+	seen := make(map[string]struct{})
+	return func(m Metric) {
+		if len(types) > 0 && !slices.Contains(types, m.Type) {
+			return
+		}
+		if _, ok := seen[m.Name]; ok {
+			return
+		}
+		seen[m.Name] = struct{}{}
+		if err := enc.Encode(m.Canonical(sortLabels)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func doExtractMetrics(*cobra.Command, []string) {
+	searchDir := viper.GetString(CfgCodebasePath)
+	exclude := viper.GetStringSlice(CfgExclude)
+	for _, pattern := range exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			log.Fatalf("invalid exclusion pattern %q: %v", pattern, err)
+		}
+	}
+	include := viper.GetStringSlice(CfgInclude)
+	for _, pattern := range include {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			log.Fatalf("invalid inclusion pattern %q: %v", pattern, err)
+		}
+	}
+
+	types := viper.GetStringSlice(CfgType)
+	for i, t := range types {
+		// Vec metrics are filtered by their base type.
+		t = strings.TrimSuffix(t, "Vec")
+		types[i] = t
+		if !slices.Contains(metricTypes, t) {
+			log.Fatalf("unknown metric type %q (supported: %s)", t, strings.Join(metricTypes, ", "))
+		}
+	}
+
+	if format := viper.GetString(CfgLintFormat); !slices.Contains(lintFormats, format) {
+		log.Fatalf("unknown lint format %q (supported: %s)", format, strings.Join(lintFormats, ", "))
+	}
+
+	for _, rule := range viper.GetStringSlice(CfgLintErrorOn) {
+		if !slices.Contains(lintRules, rule) {
+			log.Fatalf("unknown lint rule %q (supported: %s)", rule, strings.Join(lintRules, ", "))
+		}
+	}
+
+	if groupBy := viper.GetString(CfgMarkdownGroupBy); groupBy != "" && !slices.Contains(markdownGroups, groupBy) {
+		log.Fatalf("unknown markdown grouping %q (supported: %s)", groupBy, strings.Join(markdownGroups, ", "))
+	}
+
+	stabilityRe, err := regexp.Compile(viper.GetString(CfgStabilityPattern))
+	if err != nil {
+		log.Fatalf("invalid stability pattern: %v", err)
+	}
+	if stabilityRe.NumSubexp() != 1 {
+		log.Fatalf("stability pattern must contain exactly one capture group")
+	}
+
+	closeOutput, err := openOutput()
+	if err != nil {
+		log.Fatalf("failed to open output: %v", err)
+	}
+
+	// Collect the metrics, unless they are streamed to the output as they are discovered.
+	collect := collectMetric
+	stream := viper.GetBool(CfgStream)
+	if stream {
+		// Only the line-oriented output is streamed, other formats need the whole metric set.
+		for _, cfg := range []string{CfgMarkdown, CfgHash, CfgNames, CfgByPackage, CfgLint, CfgProvenance} {
+			if viper.GetBool(cfg) {
+				log.Fatalf("--%s cannot be used together with --%s", cfg, CfgStream)
+			}
+		}
+		collect = streamJSON(types)
+	}
+
+	// Tag the metrics with their owners before they are collected.
+	if path := viper.GetString(CfgCodeowners); path != "" {
+		owners, err := loadCodeowners(path)
+		if err != nil {
+			log.Fatalf("failed to load code owners: %v", err)
+		}
+		collect = owners.tag(collect)
+	}
+
+	// Only the metrics found by walking the codebase are cached, as loading packages resolves
+	// constants across files.
+	var cache *metricCache
+	cachePath := viper.GetString(CfgCache)
+	if cachePath != "" {
+		if viper.GetString(CfgPackage) != "" {
+			log.Fatalf("--%s cannot be used together with --%s", CfgCache, CfgPackage)
+		}
+		cache = loadMetricCache(cachePath, stabilityRe.String())
+	}
+
+	var skipped int
+	if pattern := viper.GetString(CfgPackage); pattern != "" {
+		skipped, err = loadPackageMetrics(searchDir, pattern, include, exclude, stabilityRe, collect)
+	} else {
+		skipped, err = walkMetrics(searchDir, include, exclude, stabilityRe, cache, collect)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cache != nil {
+		if err = cache.save(cachePath); err != nil {
+			log.Fatalf("failed to save metric cache: %v", err)
+		}
+	}
+	if viper.GetBool(CfgVerbose) {
+		fmt.Fprintf(os.Stderr, "skipped %d excluded files\n", skipped)
+	}
+
+	var failed bool
+	filtered := filterByType(metrics, types)
+	switch {
+	case stream:
+		// Metrics have already been printed.
+	case viper.GetBool(CfgLint):
+		failed = printLint(filtered)
+	case viper.GetBool(CfgHash):
+		printHash(filtered)
+	case viper.GetBool(CfgNames):
+		printNames(filtered)
+	case viper.GetBool(CfgByPackage):
+		printByPackage(filtered)
+	case viper.GetBool(CfgMarkdown):
+		printMarkdown(filtered)
+	default:
+		printJSON(filtered)
+	}
+
+	if err = closeOutput(); err != nil {
+		log.Fatalf("failed to close output: %v", err)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// walkMetrics parses the included .go files in the given directory tree and passes the metrics
+// defined in them to collect. It returns the number of skipped excluded files.
+//
+// If a cache is given, files whose content hasn't changed since they were cached are not parsed
+// again, and the cache is updated with the files scanned in this run.
+func walkMetrics(searchDir string, include, exclude []string, stabilityRe *regexp.Regexp, cache *metricCache, collect func(Metric)) (int, error) {
+	// Metrics whose names are defined in other packages are resolved after the whole codebase
+	// has been scanned.
+	consts := make(constIndex)
+	var pending []Metric
+
+	var skipped int
+	fset := token.NewFileSet() // positions are relative to fset
+	err := filepath.Walk(searchDir, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			log.Fatal(err)
+		}
+		if f.IsDir() {
+			if path != searchDir && isExcluded(f.Name(), exclude) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(f.Name(), ".go") {
+			return nil
+		}
+		if rel, relErr := filepath.Rel(searchDir, path); relErr == nil && !isIncluded(filepath.ToSlash(rel), include) {
+			return nil
+		}
+		if isExcluded(f.Name(), exclude) {
+			skipped++
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		hash := fileHash(data)
+
+		var (
+			fileConstants map[string]string
+			fileMetrics   []Metric
+		)
+		if entry, ok := cache.lookup(path, hash); ok {
+			fileConstants, fileMetrics = entry.Consts, entry.metrics()
+		} else {
+			src, err := parser.ParseFile(fset, path, data, parser.ParseComments)
+			if err != nil {
+				return err
+			}
+			fileConstants, fileMetrics = fileConsts(src), extractFileMetrics(fset, path, src, stabilityRe)
+			cache.store(path, newCachedFile(hash, fileConstants, fileMetrics))
+		}
+		if dir, relErr := filepath.Rel(searchDir, filepath.Dir(path)); relErr == nil {
+			consts.add(filepath.ToSlash(dir), fileConstants)
+		}
+
+		for _, m := range fileMetrics {
+			if m.nameRef != nil {
+				pending = append(pending, m)
+				continue
+			}
+			collect(m)
+		}
+		return nil
+	})
+	if err != nil {
+		return skipped, err
+	}
+	for _, m := range pending {
+		name, ok := consts.resolve(*m.nameRef)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "warning: %s:%d: cannot resolve metric name %s\n", m.Filename, m.Line, m.nameRef)
+			continue
+		}
+		m.Name = name
+		collect(m)
+	}
+	return skipped, nil
+}
+
+// metricCache is the on-disk cache of the metrics found in each scanned file.
+type metricCache struct {
+	Version          int                    `json:"version"`
+	StabilityPattern string                 `json:"stability_pattern"`
+	Files            map[string]*cachedFile `json:"files"`
+
+	// seen are the entries of the files scanned in this run. They replace the loaded entries
+	// when the cache is saved, so that entries of removed files are dropped.
+	seen map[string]*cachedFile
+}
+
+// cachedFile is the cache entry of a scanned file.
+type cachedFile struct {
+	// Hash is the hex-encoded SHA-256 hash of the file content the entry was created from.
+	Hash    string            `json:"hash"`
+	Consts  map[string]string `json:"consts,omitempty"`
+	Metrics []cachedMetric    `json:"metrics,omitempty"`
+}
+
+// cachedMetric is a cached metric, including its unresolved name reference.
+type cachedMetric struct {
+	Metric
+	NameRef *cachedConstRef `json:"name_ref,omitempty"`
+}
+
+// cachedConstRef is a cached reference to a constant declared in another package.
+type cachedConstRef struct {
+	Pkg        string `json:"pkg"`
+	ImportPath string `json:"import_path"`
+	Name       string `json:"name"`
+}
+
+// loadMetricCache loads the metric cache from the given path. Missing, invalid or stale caches,
+// e.g. created with a different stability pattern, are replaced by an empty one.
+func loadMetricCache(path, stabilityPattern string) *metricCache {
+	cache := &metricCache{
+		Version:          cacheVersion,
+		StabilityPattern: stabilityPattern,
+		Files:            make(map[string]*cachedFile),
+		seen:             make(map[string]*cachedFile),
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return cache
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "warning: ignoring unreadable metric cache %s: %v\n", path, err)
+		return cache
+	}
+
+	var loaded metricCache
+	if err = json.Unmarshal(data, &loaded); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: ignoring invalid metric cache %s: %v\n", path, err)
+		return cache
 	}
-)
+	if loaded.Version != cacheVersion || loaded.StabilityPattern != stabilityPattern || loaded.Files == nil {
+		return cache
+	}
+	cache.Files = loaded.Files
+	return cache
+}
 
-type Metric struct {
-	Name     string   `json:"name"`
-	Type     string   `json:"type"`
-	Help     string   `json:"help"`
-	Labels   []string `json:"labels"`
-	Filename string   `json:"filename"`
-	Line     int      `json:"line"`
-	Vec      bool     `json:"vec"`
+// lookup returns the cache entry of the given file, if the file content hasn't changed since
+// it was cached.
+func (c *metricCache) lookup(path, hash string) (*cachedFile, bool) {
+	if c == nil {
+		return nil, false
+	}
+	entry, ok := c.Files[path]
+	if !ok || entry.Hash != hash {
+		return nil, false
+	}
+	c.seen[path] = entry
+	return entry, true
 }
 
-func markdownTable(metrics map[string]Metric) string {
-	var ordKeys []string
-	for k := range metrics {
-		ordKeys = append(ordKeys, k)
+// store records the cache entry of the given file.
+func (c *metricCache) store(path string, entry *cachedFile) {
+	if c == nil {
+		return
 	}
-	sort.Slice(ordKeys, func(i, j int) bool {
-		return metrics[ordKeys[i]].Name < metrics[ordKeys[j]].Name
-	})
+	c.seen[path] = entry
+}
 
-	baseDir := viper.GetString(CfgCodebasePath)
-	if viper.IsSet(CfgMarkdownTplFile) && !viper.IsSet(CfgCodebaseURL) {
-		baseDir = filepath.Dir(viper.GetString(CfgMarkdownTplFile))
+// save writes the entries of the files scanned in this run to the given path.
+func (c *metricCache) save(path string) error {
+	c.Files = c.seen
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
 	}
+	return os.WriteFile(path, data, 0o644)
+}
 
-	mdTable := "Name | Type | Description | Labels | Package\n"
-	mdTable += "-----|------|-------------|--------|--------\n"
-	for _, k := range ordKeys {
-		m := metrics[k]
-		pkg, _ := filepath.Rel(viper.GetString(CfgCodebasePath), m.Filename)
-		pkg = filepath.Dir(pkg)
-		fileURL, _ := filepath.Rel(baseDir, m.Filename)
-		if viper.IsSet(CfgCodebaseURL) {
-			fileURL = viper.GetString(CfgCodebaseURL) + fileURL
+// newCachedFile returns the cache entry of a file with the given hash, constants and metrics.
+func newCachedFile(hash string, consts map[string]string, metrics []Metric) *cachedFile {
+	entry := &cachedFile{
+		Hash:   hash,
+		Consts: consts,
+	}
+	for _, m := range metrics {
+		cm := cachedMetric{Metric: m}
+		if m.nameRef != nil {
+			cm.NameRef = &cachedConstRef{
+				Pkg:        m.nameRef.pkg,
+				ImportPath: m.nameRef.importPath,
+				Name:       m.nameRef.name,
+			}
 		}
-		desc := html.EscapeString(m.Help)
-		labels := strings.Join(m.Labels, ", ")
+		entry.Metrics = append(entry.Metrics, cm)
+	}
+	return entry
+}
 
-		mdTable += fmt.Sprintf("%s | %s | %s | %s | [%s](%s)\n", m.Name, m.Type, desc,
-			labels, pkg, fileURL)
+// metrics returns the cached metrics of the file.
+func (f *cachedFile) metrics() []Metric {
+	metrics := make([]Metric, 0, len(f.Metrics))
+	for _, cm := range f.Metrics {
+		m := cm.Metric
+		if cm.NameRef != nil {
+			m.nameRef = &constRef{
+				pkg:        cm.NameRef.Pkg,
+				importPath: cm.NameRef.ImportPath,
+				name:       cm.NameRef.Name,
+			}
+		}
+		metrics = append(metrics, m)
 	}
+	return metrics
+}
 
-	return mdTable
+// fileHash returns the hex-encoded SHA-256 hash of the given file content.
+func fileHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
-func printMarkdown(metrics map[string]Metric) {
-	mdTable := markdownTable(metrics)
+// loadPackageMetrics loads the packages matching the given pattern (e.g. an import path)
+// using the Go toolchain's package loader from the given directory and passes the metrics
+// defined in their included files to collect. It returns the number of skipped excluded files.
+//
+// Unlike walkMetrics, metric names and help texts are resolved using type information, so
+// constants are resolved accurately across packages and their dependencies.
+func loadPackageMetrics(dir, pattern string, include, exclude []string, stabilityRe *regexp.Regexp, collect func(Metric)) (int, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+			packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:  dir,
+		Fset: token.NewFileSet(),
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return 0, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return 0, fmt.Errorf("failed to load packages matching %q", pattern)
+	}
 
-	if !viper.IsSet(CfgMarkdownTplFile) {
-		// Print Markdown table only.
-		fmt.Print(mdTable)
-		return
+	var skipped int
+	for _, pkg := range pkgs {
+		for _, src := range pkg.Syntax {
+			path := cfg.Fset.File(src.Pos()).Name()
+			if rel, relErr := filepath.Rel(dir, path); relErr == nil && !isIncluded(filepath.ToSlash(rel), include) {
+				continue
+			}
+			if isExcluded(filepath.Base(path), exclude) {
+				skipped++
+				continue
+			}
+			for _, m := range extractFileMetrics(cfg.Fset, path, src, stabilityRe) {
+				if name, ok := constString(pkg.TypesInfo, m.nameExpr); ok {
+					m.Name = name
+					m.nameRef = nil
+				}
+				if help, ok := constString(pkg.TypesInfo, m.helpExpr); ok {
+					m.Help = help
+				}
+				if m.nameRef != nil {
+					fmt.Fprintf(os.Stderr, "warning: %s:%d: cannot resolve metric name %s\n", m.Filename, m.Line, m.nameRef)
+					continue
+				}
+				m.nameExpr, m.helpExpr = nil, nil
+				collect(m)
+			}
+		}
 	}
+	return skipped, nil
+}
 
-	md, err := os.ReadFile(viper.GetString(CfgMarkdownTplFile))
-	if err != nil {
-		panic(err)
+// constString returns the value of the given expression if it is a constant string according
+// to the type information.
+func constString(info *types.Info, n ast.Expr) (string, bool) {
+	if info == nil || n == nil {
+		return "", false
 	}
-	mdStr := fmt.Sprintf("---\n# DO NOT EDIT. This file was generated by %s\n---\n\n", scriptName)
-	mdStr += string(md)
-	mdStr = strings.Replace(mdStr, viper.GetString(CfgMarkdownTplPlaceholder)+"\n", mdTable, 1)
+	tv, ok := info.Types[n]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
 
-	fmt.Print(mdStr)
+// extractFileMetrics returns the metrics defined in the given parsed file, in source order.
+func extractFileMetrics(fset *token.FileSet, path string, src *ast.File, stabilityRe *regexp.Regexp) []Metric {
+	cmap := ast.NewCommentMap(fset, src, src.Comments)
+	imports := fileImports(src)
+	constraintExpr := buildConstraint(src)
+	registries := fileRegistries(src)
+
+	// Keep track of the enclosing nodes to find the doc comments and variables of the metrics.
+	var (
+		stack   []ast.Node
+		metrics []Metric
+	)
+	ast.Inspect(src, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, n)
+
+		m, ok := checkNewPrometheusMetric(fset, n, imports)
+		if ok {
+			m.Filename = path
+			m.BuildConstraint = constraintExpr
+			m.Stability = extractStability(cmap, stack, stabilityRe)
+			m.Variable = extractVariable(stack)
+			if m.Variable != "" {
+				exported := isExportedVariable(m.Variable)
+				m.Exported = &exported
+			}
+			m.Registry = extractRegistry(stack)
+			if m.Registry == "" && m.Variable != "" {
+				m.Registry = registries[m.Variable[strings.LastIndex(m.Variable, ".")+1:]]
+			}
+			metrics = append(metrics, m)
+		}
+		return true
+	})
+	return metrics
 }
 
-func printJSON(m map[string]Metric) {
-	data, err := json.Marshal(m)
-	if err != nil {
-		panic(err)
+// buildConstraint returns the //go:build constraint of the file in its canonical form,
+// or an empty string if the file has no build constraint.
+func buildConstraint(src *ast.File) string {
+	for _, cg := range src.Comments {
+		if cg.Pos() > src.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if !constraint.IsGoBuild(c.Text) {
+				continue
+			}
+			expr, err := constraint.Parse(c.Text)
+			if err != nil {
+				continue
+			}
+			return expr.String()
+		}
 	}
-	fmt.Printf("%s", data)
+	return ""
 }
 
-var metrics = map[string]Metric{}
+// extractVariable returns the name of the variable or field the metric, which is the last
+// node of the stack, is assigned to, for example:
+//
+// ```
+// var (
+//
+//	upGauge   = prometheus.NewGauge(...)
+//	downGauge = prometheus.NewGauge(...)
+//
+// )
+// ```
+//
+// Multiple names declared or assigned at once are matched by the position of the value.
+// If the metric is not directly assigned, an empty string is returned.
+func extractVariable(stack []ast.Node) string {
+	if len(stack) < 2 {
+		return ""
+	}
+	call := stack[len(stack)-1]
 
-func doExtractMetrics(*cobra.Command, []string) {
-	searchDir := viper.GetString(CfgCodebasePath)
-	fset := token.NewFileSet() // positions are relative to fset
-	err := filepath.Walk(searchDir, func(path string, f os.FileInfo, err error) error {
-		if err != nil {
-			log.Fatal(err)
+	var (
+		names  []ast.Expr
+		values []ast.Expr
+	)
+	switch n := stack[len(stack)-2].(type) {
+	case *ast.ValueSpec:
+		for _, name := range n.Names {
+			names = append(names, name)
 		}
-		if f.IsDir() {
-			return nil
+		values = n.Values
+	case *ast.AssignStmt:
+		names, values = n.Lhs, n.Rhs
+	case *ast.KeyValueExpr:
+		names, values = []ast.Expr{n.Key}, []ast.Expr{n.Value}
+	default:
+		return ""
+	}
+	if len(names) != len(values) {
+		return ""
+	}
+	for i, v := range values {
+		if v != call {
+			continue
 		}
-		if !strings.HasSuffix(f.Name(), ".go") {
-			return nil
+		switch name := names[i].(type) {
+		case *ast.Ident:
+			return name.Name
+		case *ast.SelectorExpr:
+			if x, ok := name.X.(*ast.Ident); ok {
+				return x.Name + "." + name.Sel.Name
+			}
 		}
-		src, err := parser.ParseFile(fset, path, nil, 0)
-		if err != nil {
-			return err
+	}
+	return ""
+}
+
+// registryName returns the name of the registry the given call registers collectors into, if it
+// is a call to the Register or MustRegister method of a registry or the prometheus package.
+func registryName(c *ast.CallExpr) (string, bool) {
+	sel, ok := c.Fun.(*ast.SelectorExpr)
+	if !ok || (sel.Sel.Name != "Register" && sel.Sel.Name != "MustRegister") {
+		return "", false
+	}
+	switch x := sel.X.(type) {
+	case *ast.Ident:
+		if x.Name == "prometheus" {
+			return defaultRegistry, true
 		}
+		return x.Name, true
+	case *ast.SelectorExpr:
+		if pkg, okPkg := x.X.(*ast.Ident); okPkg {
+			return pkg.Name + "." + x.Sel.Name, true
+		}
+	}
+	return "", false
+}
+
+// collectorName returns the variable or field name of the given collector expression, without
+// the qualifier, or an empty string if the expression is not a variable or a field.
+func collectorName(n ast.Expr) string {
+	switch n := n.(type) {
+	case *ast.Ident:
+		return n.Name
+	case *ast.SelectorExpr:
+		return n.Sel.Name
+	default:
+		return ""
+	}
+}
 
-		ast.Inspect(src, func(n ast.Node) bool {
-			m, ok := checkNewPrometheusMetric(fset, n)
-			if ok {
-				m.Filename = path
-				metrics[m.Name] = m
+// fileRegistries returns the registries the collectors registered in the given file are
+// registered into, keyed by the unqualified variable or field name of the collectors.
+//
+// Collectors registered as elements of a slice literal assigned to a variable in the same file
+// (e.g. prometheus.MustRegister(collectors...)) are resolved as well.
+func fileRegistries(src *ast.File) map[string]string {
+	// Find the collector slice literals assigned to variables.
+	collectorSlices := make(map[string][]string)
+	ast.Inspect(src, func(n ast.Node) bool {
+		var names, values []ast.Expr
+		switch n := n.(type) {
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				names = append(names, name)
 			}
+			values = n.Values
+		case *ast.AssignStmt:
+			names, values = n.Lhs, n.Rhs
+		default:
 			return true
-		})
-		return nil
+		}
+		if len(names) != len(values) {
+			return true
+		}
+		for i, v := range values {
+			lit, ok := v.(*ast.CompositeLit)
+			if !ok {
+				continue
+			}
+			if _, ok = lit.Type.(*ast.ArrayType); !ok {
+				continue
+			}
+			name := collectorName(names[i])
+			for _, e := range lit.Elts {
+				if elt := collectorName(e); name != "" && elt != "" {
+					collectorSlices[name] = append(collectorSlices[name], elt)
+				}
+			}
+		}
+		return true
 	})
-	if err != nil {
-		log.Fatal(err)
-	}
 
-	if viper.GetBool(CfgMarkdown) {
-		printMarkdown(metrics)
-	} else {
-		printJSON(metrics)
+	registries := make(map[string]string)
+	ast.Inspect(src, func(n ast.Node) bool {
+		c, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		registry, ok := registryName(c)
+		if !ok {
+			return true
+		}
+		for i, arg := range c.Args {
+			name := collectorName(arg)
+			if name == "" {
+				continue
+			}
+			if c.Ellipsis.IsValid() && i == len(c.Args)-1 {
+				for _, elt := range collectorSlices[name] {
+					registries[elt] = registry
+				}
+				continue
+			}
+			registries[name] = registry
+		}
+		return true
+	})
+	return registries
+}
+
+// extractRegistry returns the registry the metric constructed by the last node of the stack is
+// registered into, if the constructor call is passed directly to a registration call.
+func extractRegistry(stack []ast.Node) string {
+	if len(stack) < 2 {
+		return ""
 	}
+	c, ok := stack[len(stack)-2].(*ast.CallExpr)
+	if !ok {
+		return ""
+	}
+	registry, ok := registryName(c)
+	if !ok {
+		return ""
+	}
+	for _, arg := range c.Args {
+		if arg == stack[len(stack)-1] {
+			return registry
+		}
+	}
+	return ""
+}
+
+// isExportedVariable returns true iff the variable or field with the given, possibly qualified,
+// name is exported. Unexported metrics can only be registered in the package declaring them.
+func isExportedVariable(name string) bool {
+	return ast.IsExported(name[strings.LastIndex(name, ".")+1:])
 }
 
 // checkNewPrometheusMetric checks the given node in AST, if it contains Prometheus metric.
@@ -186,7 +1925,10 @@ func doExtractMetrics(*cobra.Command, []string) {
 //
 // )
 // ```
-func checkNewPrometheusMetric(f *token.FileSet, n ast.Node) (m Metric, ok bool) {
+//
+// Metric names referencing constants in other packages (e.g. Name: metrics.NameFoo) are
+// returned as references in nameRef, to be resolved once the whole codebase is scanned.
+func checkNewPrometheusMetric(f *token.FileSet, n ast.Node, imports map[string]string) (m Metric, ok bool) {
 	c, ok := n.(*ast.CallExpr)
 	if !ok {
 		return
@@ -212,27 +1954,53 @@ func checkNewPrometheusMetric(f *token.FileSet, n ast.Node) (m Metric, ok bool)
 		m.Type = m.Type[:len(m.Type)-3]
 	}
 
-	m.Line = f.Position(c.Pos()).Line
+	// Skip other constructors, such as prometheus.NewGoCollector(), which are commonly nested
+	// inside registration calls together with the metrics.
+	if !slices.Contains(metricTypes, m.Type) || len(c.Args) == 0 {
+		return Metric{}, false
+	}
+
+	pos := f.Position(c.Pos())
+	m.Line = pos.Line
+	m.Column = pos.Column
 
-	// Obtain metric Name and Help values.
-	ast.Inspect(c.Args[0], func(n ast.Node) bool {
-		// Find metrics Name: and Help: attributes.
+	// Obtain metric Name, Help and, for summaries, Objectives values.
+	helpResolved := true
+	ast.Inspect(resolveOpts(c.Args[0]), func(n ast.Node) bool {
+		// Find metrics Name:, Help: and Objectives: attributes.
 		kv, okKV := n.(*ast.KeyValueExpr)
 		if !okKV {
 			return true
 		}
-		if kv.Key.(*ast.Ident).Name == "Name" {
-			m.Name = extractValue(kv.Value)
+		key, okKey := kv.Key.(*ast.Ident)
+		if !okKey {
+			return true
 		}
-		if kv.Key.(*ast.Ident).Name == "Help" {
-			m.Help = extractValue(kv.Value)
+		switch key.Name {
+		case "Name":
+			m.Name = extractValue(kv.Value)
+			m.nameRef = extractConstRef(kv.Value, imports)
+			m.nameExpr = kv.Value
+		case "Help":
+			m.Help, helpResolved = extractHelp(kv.Value, imports)
+			m.helpExpr = kv.Value
+		case "Objectives":
+			if m.Type == "Summary" {
+				m.Objectives = extractObjectives(kv.Value)
+			}
+			// Do not descend into the objectives map literal.
+			return false
 		}
 		return true
 	})
+	if !helpResolved {
+		fmt.Fprintf(os.Stderr, "warning: %s:%d: cannot resolve help arguments of metric %s, using the format string\n",
+			pos.Filename, pos.Line, m.Name)
+	}
 
 	// If labels are defined, extract them.
 	if len(c.Args) > 1 {
-		l, okL := c.Args[1].(*ast.CompositeLit)
+		l, okL := resolveOpts(c.Args[1]).(*ast.CompositeLit)
 		if !okL {
 			return
 		}
@@ -244,6 +2012,87 @@ func checkNewPrometheusMetric(f *token.FileSet, n ast.Node) (m Metric, ok bool)
 	return
 }
 
+// extractStability returns the stability level of a metric from the doc comments of the
+// enclosing declaration or statement, for example:
+//
+// ```
+// // metric:stable
+// upGauge = prometheus.NewGauge(...)
+// ```
+//
+// The innermost doc comment matching the pattern wins. If no comment matches,
+// defaultStability is returned.
+func extractStability(cmap ast.CommentMap, stack []ast.Node, re *regexp.Regexp) string {
+	for i := len(stack) - 1; i >= 0; i-- {
+		n := stack[i]
+		switch n.(type) {
+		case *ast.ValueSpec, *ast.GenDecl, *ast.AssignStmt, *ast.ExprStmt, *ast.KeyValueExpr:
+		case *ast.FuncDecl, *ast.File:
+			return defaultStability
+		default:
+			continue
+		}
+
+		for _, cg := range cmap[n] {
+			// Only consider comments preceding the node.
+			if cg.End() > n.Pos() {
+				continue
+			}
+			if match := re.FindStringSubmatch(cg.Text()); match != nil {
+				return match[1]
+			}
+		}
+	}
+	return defaultStability
+}
+
+// resolveOpts returns the expression defining the metric opts or labels.
+//
+// If the opts are passed as an identifier, it is followed back to its declaration or assignment
+// within the same file, for example:
+//
+// ```
+// opts := prometheus.GaugeOpts{
+//
+//	Name: "oasis_up",
+//	Help: "Is oasis-test-runner active.",
+//
+// }
+// upGauge = prometheus.NewGauge(opts)
+// ```
+func resolveOpts(n ast.Expr) ast.Expr {
+	ident, ok := n.(*ast.Ident)
+	if !ok || ident.Obj == nil {
+		return n
+	}
+
+	var (
+		names  []*ast.Ident
+		values []ast.Expr
+	)
+	switch decl := ident.Obj.Decl.(type) {
+	case *ast.ValueSpec:
+		names, values = decl.Names, decl.Values
+	case *ast.AssignStmt:
+		for _, lhs := range decl.Lhs {
+			name, _ := lhs.(*ast.Ident)
+			names = append(names, name)
+		}
+		values = decl.Rhs
+	default:
+		return n
+	}
+	if len(names) != len(values) {
+		return n
+	}
+	for i, name := range names {
+		if name != nil && name.Name == ident.Name {
+			return values[i]
+		}
+	}
+	return n
+}
+
 // extractValue returns string value of the identifier or literal.
 func extractValue(n ast.Expr) string {
 	lit, ok := n.(*ast.BasicLit)
@@ -268,12 +2117,185 @@ func extractValue(n ast.Expr) string {
 	return val.Value[1 : len(val.Value)-1]
 }
 
+// extractHelp returns the help text of a metric.
+//
+// In addition to the values supported by extractValue, help texts built with fmt.Sprintf
+// are resolved if the format string and all the arguments are literals or constants, for
+// example:
+//
+// ```
+// const backendName = "badger"
+// ...
+// Help: fmt.Sprintf("Size of the %s database (bytes).", backendName),
+// ```
+//
+// If any of the arguments can't be resolved statically, the raw format string is returned
+// and ok is false.
+func extractHelp(n ast.Expr, imports map[string]string) (help string, ok bool) {
+	c, isCall := n.(*ast.CallExpr)
+	if !isCall {
+		return extractValue(n), true
+	}
+	sel, isSel := c.Fun.(*ast.SelectorExpr)
+	if !isSel || sel.Sel.Name != "Sprintf" || len(c.Args) == 0 {
+		return "", true
+	}
+	pkg, isIdent := sel.X.(*ast.Ident)
+	if !isIdent || imports[pkg.Name] != "fmt" {
+		return "", true
+	}
+
+	format, isString := extractConstValue(c.Args[0]).(string)
+	if !isString {
+		return "", true
+	}
+	var args []interface{}
+	for _, arg := range c.Args[1:] {
+		v := extractConstValue(arg)
+		if v == nil {
+			return format, false
+		}
+		args = append(args, v)
+	}
+	return fmt.Sprintf(format, args...), true
+}
+
+// extractConstValue returns the value of the string, integer or floating-point literal or
+// constant identifier, or nil if it can't be resolved.
+func extractConstValue(n ast.Expr) interface{} {
+	if ident, ok := n.(*ast.Ident); ok && ident.Obj != nil {
+		decl, ok := ident.Obj.Decl.(*ast.ValueSpec)
+		if !ok || len(decl.Values) != 1 {
+			return nil
+		}
+		n = decl.Values[0]
+	}
+	lit, ok := n.(*ast.BasicLit)
+	if !ok {
+		return nil
+	}
+
+	var (
+		v   interface{}
+		err error
+	)
+	switch lit.Kind {
+	case token.STRING:
+		v, err = strconv.Unquote(lit.Value)
+	case token.INT:
+		v, err = strconv.ParseInt(lit.Value, 0, 64)
+	case token.FLOAT:
+		v, err = strconv.ParseFloat(lit.Value, 64)
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// extractConstRef returns the reference to a constant in another package, if the expression
+// is a selector of the form pkg.ConstName.
+func extractConstRef(n ast.Expr, imports map[string]string) *constRef {
+	sel, ok := n.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	importPath, ok := imports[pkg.Name]
+	if !ok {
+		return nil
+	}
+	return &constRef{
+		pkg:        pkg.Name,
+		importPath: importPath,
+		name:       sel.Sel.Name,
+	}
+}
+
+// extractObjectives returns the quantile objectives defined by the given map literal.
+//
+// Entries whose key or value cannot be resolved to a numeric constant are skipped.
+func extractObjectives(n ast.Expr) Objectives {
+	lit, ok := n.(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+	objectives := make(Objectives)
+	for _, e := range lit.Elts {
+		kv, okKV := e.(*ast.KeyValueExpr)
+		if !okKV {
+			continue
+		}
+		q, okQ := extractNumber(kv.Key)
+		maxErr, okE := extractNumber(kv.Value)
+		if !okQ || !okE {
+			continue
+		}
+		objectives[q] = maxErr
+	}
+	if len(objectives) == 0 {
+		return nil
+	}
+	return objectives
+}
+
+// extractNumber returns numeric value of the identifier or literal.
+func extractNumber(n ast.Expr) (float64, bool) {
+	if ident, ok := n.(*ast.Ident); ok {
+		if ident.Obj == nil {
+			return 0, false
+		}
+		decl, ok := ident.Obj.Decl.(*ast.ValueSpec)
+		if !ok || len(decl.Values) != 1 {
+			return 0, false
+		}
+		n = decl.Values[0]
+	}
+
+	lit, ok := n.(*ast.BasicLit)
+	if !ok || (lit.Kind != token.INT && lit.Kind != token.FLOAT) {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(lit.Value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
 func main() {
 	rootCmd.Flags().Bool(CfgMarkdown, false, "print metrics in markdown format")
+	rootCmd.Flags().Bool(CfgLint, false, "check metrics for common instrumentation errors (e.g. labels on non-vec metrics)")
+	rootCmd.Flags().Bool(CfgLintStrict, false, "treat lint warnings as errors")
+	rootCmd.Flags().StringSlice(CfgLintCounterAllowlist, nil, "counter names exempt from the _total suffix lint check")
+	rootCmd.Flags().String(CfgLintFormat, lintFormatText, "lint report format ("+strings.Join(lintFormats, ", ")+")")
+	rootCmd.Flags().StringSlice(CfgLintErrorOn, nil, "lint rules whose warnings are treated as errors ("+strings.Join(lintRules, ", ")+")")
+	rootCmd.Flags().Bool(CfgHash, false, "print only a stable SHA-256 hash of the extracted metric set")
+	rootCmd.Flags().Bool(CfgNames, false, "print only the sorted metric names, one per line")
+	rootCmd.Flags().Bool(CfgByPackage, false, "print the metric names grouped by their Go package path as JSON")
+	rootCmd.Flags().String(CfgOutput, "", "write the output to the given file instead of stdout")
+	rootCmd.Flags().Bool(CfgGzip, false, "gzip compress the output")
+	rootCmd.Flags().String(CfgCache, "", "path to a cache of the metrics found in each file, to skip unchanged files on subsequent runs")
+	rootCmd.Flags().String(CfgCodeowners, "", "path to a CODEOWNERS file used to tag metrics with the owners of their files")
+	rootCmd.Flags().Bool(CfgProvenance, false, "include the generation time and git revision of the codebase in the Markdown header and JSON output")
+	rootCmd.Flags().Bool(CfgStream, false, "stream metrics as newline-delimited JSON as they are discovered")
 	rootCmd.Flags().String(CfgCodebasePath, "", "path to Go codebase")
+	rootCmd.Flags().String(CfgPackage, "", "load the metrics of the packages matching this pattern (e.g. an import path) with the Go package loader, relative to the codebase path")
 	rootCmd.Flags().String(CfgCodebaseURL, "", "show URL to Go files with this base instead of relative path (optional) (e.g. https://github.com/oasisprotocol/oasis-core/tree/master/go/)")
 	rootCmd.Flags().String(CfgMarkdownTplFile, "", "path to Markdown template file")
 	rootCmd.Flags().String(CfgMarkdownTplPlaceholder, "<!--- OASIS_METRICS -->", "placeholder for Markdown table in the template")
+	rootCmd.Flags().String(CfgMarkdownGroupBy, "", "group Markdown metrics into sections by ("+strings.Join(markdownGroups, ", ")+")")
+	rootCmd.Flags().StringSlice(CfgExclude, defaultExclude, "glob patterns of file and directory names to skip")
+	rootCmd.Flags().StringSlice(CfgInclude, nil, "glob patterns of paths relative to the codebase path to scan (default: everything)")
+	rootCmd.Flags().Bool(CfgVerbose, false, "print the number of skipped files to stderr")
+	rootCmd.Flags().Bool(CfgJSONSortLabels, false, "sort metric labels in JSON output")
+	rootCmd.Flags().StringSlice(CfgType, nil, "only output metrics of the given types ("+strings.Join(metricTypes, ", ")+")")
+	rootCmd.Flags().String(CfgStabilityPattern, `metric:(\w+)`, "regular expression with one capture group matching the stability level in metric doc comments")
 	_ = cobra.MarkFlagRequired(rootCmd.Flags(), CfgCodebasePath)
 	_ = viper.BindPFlags(rootCmd.Flags())
 
@@ -0,0 +1,1140 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractFileMetricsVariables(t *testing.T) {
+	require := require.New(t)
+
+	fset := token.NewFileSet()
+	src, err := parser.ParseFile(fset, "testdata/multivar.go", nil, parser.ParseComments)
+	require.NoError(err, "ParseFile")
+
+	metrics := extractFileMetrics(fset, "testdata/multivar.go", src, regexp.MustCompile(`metric:(\w+)`))
+	vars := make(map[string]string)
+	for _, m := range metrics {
+		vars[m.Name] = m.Variable
+	}
+	require.Equal(map[string]string{
+		"oasis_test_first":   "firstGauge",
+		"oasis_test_second":  "secondCounter",
+		"oasis_test_third":   "thirdCounter",
+		"oasis_test_fourth":  "fourth",
+		"oasis_test_fifth":   "fifthGauge",
+		"oasis_test_sixth":   "",
+		"oasis_test_seventh": "SeventhGauge",
+	}, vars, "metrics should be associated with the variables they are assigned to")
+}
+
+func TestExtractFileMetricsPositions(t *testing.T) {
+	require := require.New(t)
+
+	fset := token.NewFileSet()
+	src, err := parser.ParseFile(fset, "testdata/multivar.go", nil, parser.ParseComments)
+	require.NoError(err, "ParseFile")
+
+	metrics := extractFileMetrics(fset, "testdata/multivar.go", src, regexp.MustCompile(`metric:(\w+)`))
+	positions := make(map[string][2]int)
+	for _, m := range metrics {
+		positions[m.Name] = [2]int{m.Line, m.Column}
+	}
+	require.Equal(map[string][2]int{
+		"oasis_test_first":   {6, 15},
+		"oasis_test_second":  {12, 32},
+		"oasis_test_third":   {18, 5},
+		"oasis_test_fourth":  {32, 11},
+		"oasis_test_fifth":   {37, 16},
+		"oasis_test_sixth":   {41, 38},
+		"oasis_test_seventh": {48, 20},
+	}, positions, "metrics should point to their constructor calls")
+}
+
+func TestExtractFileMetricsExported(t *testing.T) {
+	require := require.New(t)
+
+	fset := token.NewFileSet()
+	src, err := parser.ParseFile(fset, "testdata/multivar.go", nil, parser.ParseComments)
+	require.NoError(err, "ParseFile")
+
+	metrics := extractFileMetrics(fset, "testdata/multivar.go", src, regexp.MustCompile(`metric:(\w+)`))
+	exported := make(map[string]*bool)
+	for _, m := range metrics {
+		exported[m.Name] = m.Exported
+	}
+	yes, no := true, false
+	require.Equal(map[string]*bool{
+		"oasis_test_first":   &no,
+		"oasis_test_second":  &no,
+		"oasis_test_third":   &no,
+		"oasis_test_fourth":  &no,
+		"oasis_test_fifth":   &no,
+		"oasis_test_sixth":   nil,
+		"oasis_test_seventh": &yes,
+	}, exported, "exported should only be set for metrics assigned to variables")
+}
+
+func TestExtractFileMetricsSprintfHelp(t *testing.T) {
+	require := require.New(t)
+
+	fset := token.NewFileSet()
+	src, err := parser.ParseFile(fset, "testdata/sprintf.go", nil, parser.ParseComments)
+	require.NoError(err, "ParseFile")
+
+	metrics := extractFileMetrics(fset, "testdata/sprintf.go", src, regexp.MustCompile(`metric:(\w+)`))
+	help := make(map[string]string)
+	for _, m := range metrics {
+		help[m.Name] = m.Help
+	}
+	require.Equal(map[string]string{
+		"oasis_test_literal": "Size of the badger database (bytes).",
+		"oasis_test_const":   "Size of the badger database (bytes).",
+		"oasis_test_number":  "Queue size (max: 100, ratio: 0.5).",
+		"oasis_test_dynamic": "Size of the %s database (bytes).",
+	}, help, "help built with fmt.Sprintf should be resolved")
+}
+
+func TestExtractFileMetricsBuildConstraint(t *testing.T) {
+	require := require.New(t)
+
+	fset := token.NewFileSet()
+	src, err := parser.ParseFile(fset, "testdata/buildtags.go", nil, parser.ParseComments)
+	require.NoError(err, "ParseFile")
+
+	metrics := extractFileMetrics(fset, "testdata/buildtags.go", src, regexp.MustCompile(`metric:(\w+)`))
+	require.Len(metrics, 1)
+	require.Equal("sgx && (linux || !windows)", metrics[0].BuildConstraint, "build constraint should be recorded")
+
+	// Files without build constraints should not record any constraint.
+	src, err = parser.ParseFile(fset, "testdata/sprintf.go", nil, parser.ParseComments)
+	require.NoError(err, "ParseFile")
+	for _, m := range extractFileMetrics(fset, "testdata/sprintf.go", src, regexp.MustCompile(`metric:(\w+)`)) {
+		require.Empty(m.BuildConstraint, "metric %s should not have a build constraint", m.Name)
+	}
+}
+
+func TestExtractFileMetricsRegistry(t *testing.T) {
+	require := require.New(t)
+
+	registries := make(map[string]string)
+	for _, path := range []string{"testdata/multivar.go", "testdata/registry.go"} {
+		fset := token.NewFileSet()
+		src, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		require.NoError(err, "ParseFile")
+
+		for _, m := range extractFileMetrics(fset, path, src, regexp.MustCompile(`metric:(\w+)`)) {
+			registries[m.Name] = m.Registry
+		}
+	}
+	require.Equal(map[string]string{
+		"oasis_test_first":         "",
+		"oasis_test_second":        "",
+		"oasis_test_third":         "",
+		"oasis_test_fourth":        "",
+		"oasis_test_fifth":         defaultRegistry,
+		"oasis_test_sixth":         defaultRegistry,
+		"oasis_test_seventh":       "",
+		"oasis_test_default_total": defaultRegistry,
+		"oasis_test_custom":        "reg",
+		"oasis_test_unregistered":  "",
+		"oasis_test_field":         "s.registry",
+		"oasis_test_inline":        "reg",
+	}, registries, "metrics should be associated with the registries they are registered into")
+}
+
+func TestExtractFileMetricsNested(t *testing.T) {
+	require := require.New(t)
+
+	fset := token.NewFileSet()
+	src, err := parser.ParseFile(fset, "testdata/nested.go", nil, parser.ParseComments)
+	require.NoError(err, "ParseFile")
+
+	metrics := extractFileMetrics(fset, "testdata/nested.go", src, regexp.MustCompile(`metric:(\w+)`))
+	labels := make(map[string][]string)
+	registries := make(map[string]string)
+	for _, m := range metrics {
+		labels[m.Name] = m.Labels
+		registries[m.Name] = m.Registry
+	}
+	require.Equal(map[string][]string{
+		"oasis_test_nested_total":   {"runtime"},
+		"oasis_test_nested_labels":  {"runtime", "kind"},
+		"oasis_test_nested_summary": {"method", "result"},
+	}, labels, "labels of constructors nested in registration calls should be extracted")
+	require.Equal(map[string]string{
+		"oasis_test_nested_total":   defaultRegistry,
+		"oasis_test_nested_labels":  "reg",
+		"oasis_test_nested_summary": "reg",
+	}, registries, "constructors nested in registration calls should record the registry")
+}
+
+func TestExtractFileMetricsObjectives(t *testing.T) {
+	require := require.New(t)
+
+	fset := token.NewFileSet()
+	src, err := parser.ParseFile(fset, "testdata/objectives.go", nil, parser.ParseComments)
+	require.NoError(err, "ParseFile")
+
+	metrics := extractFileMetrics(fset, "testdata/objectives.go", src, regexp.MustCompile(`metric:(\w+)`))
+	require.Len(metrics, 2)
+	require.Equal(Objectives{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}, metrics[0].Objectives, "objectives should be resolved")
+	require.Equal([]string{"p50", "p90", "p99"}, metrics[0].Objectives.Percentiles())
+	require.Nil(metrics[1].Objectives, "summaries without objectives should not have any")
+
+	// Objectives should survive a JSON round trip.
+	data, err := json.Marshal(metrics[0])
+	require.NoError(err, "Marshal")
+	require.Contains(string(data), `"objectives":{"0.5":0.05,"0.9":0.01,"0.99":0.001}`)
+	var m Metric
+	require.NoError(json.Unmarshal(data, &m), "Unmarshal")
+	require.Equal(metrics[0].Objectives, m.Objectives)
+}
+
+func TestExtractFileMetricsStability(t *testing.T) {
+	require := require.New(t)
+
+	fset := token.NewFileSet()
+	src, err := parser.ParseFile(fset, "testdata/stability.go", nil, parser.ParseComments)
+	require.NoError(err, "ParseFile")
+
+	metrics := extractFileMetrics(fset, "testdata/stability.go", src, regexp.MustCompile(`metric:(\w+)`))
+	stability := make(map[string]string)
+	for _, m := range metrics {
+		stability[m.Name] = m.Stability
+	}
+	require.Equal(map[string]string{
+		"oasis_test_stable":       "stable",
+		"oasis_test_experimental": "experimental",
+		"oasis_test_plain":        defaultStability,
+		"oasis_test_field_beta":   "beta",
+		"oasis_test_function":     defaultStability,
+	}, stability, "stability should be taken from the closest preceding annotation")
+}
+
+func TestExtractFileMetricsOptsVariable(t *testing.T) {
+	require := require.New(t)
+
+	fset := token.NewFileSet()
+	src, err := parser.ParseFile(fset, "testdata/opts.go", nil, parser.ParseComments)
+	require.NoError(err, "ParseFile")
+
+	metrics := extractFileMetrics(fset, "testdata/opts.go", src, regexp.MustCompile(`metric:(\w+)`))
+	require.Len(metrics, 2)
+	require.Equal("oasis_test_package_opts", metrics[0].Name, "opts declared as a variable should be resolved")
+	require.Equal("Opts declared at package level.", metrics[0].Help)
+	require.Equal([]string{"runtime"}, metrics[0].Labels, "labels declared as a variable should be resolved")
+	require.Equal("oasis_test_local_opts", metrics[1].Name, "opts assigned to a variable should be resolved")
+	require.Equal("Opts assigned within a function.", metrics[1].Help)
+}
+
+func TestWalkMetricsCache(t *testing.T) {
+	require := require.New(t)
+
+	stabilityRe := regexp.MustCompile(`metric:(\w+)`)
+	walk := func(cache *metricCache) string {
+		var collected []Metric
+		_, err := walkMetrics("testdata", nil, defaultExclude, stabilityRe, cache, func(m Metric) {
+			collected = append(collected, m)
+		})
+		require.NoError(err, "walkMetrics")
+		out, err := json.Marshal(collected)
+		require.NoError(err, "json.Marshal")
+		return string(out)
+	}
+	expected := walk(nil)
+
+	// Populate the cache and load it back.
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cache := loadMetricCache(path, stabilityRe.String())
+	require.JSONEq(expected, walk(cache), "populating the cache should not change the metrics")
+	require.NoError(cache.save(path), "save")
+
+	cache = loadMetricCache(path, stabilityRe.String())
+	require.Len(cache.Files, 8, "all scanned files should be cached")
+	require.JSONEq(expected, walk(cache), "cached metrics should match a full run")
+
+	// Unchanged files should not be parsed again.
+	entry := cache.Files[filepath.Join("testdata", "multivar.go")]
+	require.NotNil(entry, "multivar.go should be cached")
+	entry.Metrics[0].Help = "Cached metric."
+	require.Contains(walk(cache), "Cached metric.", "cached metrics should be reused")
+
+	// Entries of changed files should be invalidated.
+	entry.Hash = "stale"
+	require.JSONEq(expected, walk(cache), "changed files should be parsed again")
+
+	// Caches created with a different stability pattern should be discarded.
+	require.Empty(loadMetricCache(path, `stability:(\w+)`).Files)
+}
+
+func TestWalkMetricsUnresolvedName(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	src := `package unresolved
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"example.com/missing/names"
+)
+
+var (
+	resolved = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "oasis_test_resolved_total",
+		Help: "Metric with a literal name.",
+	})
+	unresolved = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: names.Missing,
+		Help: "Metric named by a constant that is not part of the codebase.",
+	})
+)
+`
+	require.NoError(os.WriteFile(filepath.Join(dir, "metrics.go"), []byte(src), 0o600), "WriteFile")
+
+	var names []string
+	_, err := walkMetrics(dir, nil, defaultExclude, regexp.MustCompile(`metric:(\w+)`), nil, func(m Metric) {
+		names = append(names, m.Name)
+	})
+	require.NoError(err, "walkMetrics")
+	require.Equal([]string{"oasis_test_resolved_total"}, names, "metrics with unresolved names should be skipped")
+}
+
+func TestWalkMetricsDefaultExclude(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	metricSrc := func(name string) []byte {
+		return []byte(`package excluded
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var gauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "` + name + `",
+	Help: "Test metric.",
+})
+`)
+	}
+	require.NoError(os.MkdirAll(filepath.Join(dir, "vendor"), 0o700), "MkdirAll")
+	for file, name := range map[string]string{
+		"metrics.go":         "oasis_test_metrics",
+		"metrics_test.go":    "oasis_test_test",
+		"types.pb.go":        "oasis_test_pb",
+		"types_gen.go":       "oasis_test_gen",
+		"vendor/vendored.go": "oasis_test_vendored",
+	} {
+		require.NoError(os.WriteFile(filepath.Join(dir, file), metricSrc(name), 0o600), "WriteFile")
+	}
+
+	walk := func(exclude []string) ([]string, int) {
+		var names []string
+		skipped, err := walkMetrics(dir, nil, exclude, regexp.MustCompile(`metric:(\w+)`), nil, func(m Metric) {
+			names = append(names, m.Name)
+		})
+		require.NoError(err, "walkMetrics")
+		sort.Strings(names)
+		return names, skipped
+	}
+
+	names, skipped := walk(defaultExclude)
+	require.Equal([]string{"oasis_test_metrics"}, names, "generated, test and vendored files should be skipped by default")
+	require.Equal(3, skipped, "skipped files should be counted, but not the ones in skipped directories")
+
+	names, skipped = walk([]string{"vendor", "*_test.go"})
+	require.Equal([]string{"oasis_test_gen", "oasis_test_metrics", "oasis_test_pb"}, names, "overriding the exclusions should scan generated files")
+	require.Equal(1, skipped)
+}
+
+func TestConstIndexResolve(t *testing.T) {
+	require := require.New(t)
+
+	ci := make(constIndex)
+	ci.add(".", map[string]string{"ModuleName": "main"})
+	ci.add("api", map[string]string{"ModuleName": "unrelated"})
+	ci.add("registry/api", map[string]string{"ModuleName": "registry"})
+	ci.add("go/registry/api", map[string]string{"ModuleName": "go registry"})
+	ci.add("go/staking/api", map[string]string{"ModuleName": "staking"})
+
+	// The longest matching directory should be used, regardless of the map order.
+	for i := 0; i < 10; i++ {
+		v, ok := ci.resolve(constRef{importPath: "github.com/oasisprotocol/oasis-core/go/registry/api", name: "ModuleName"})
+		require.True(ok, "constant should be resolved")
+		require.Equal("go registry", v)
+	}
+
+	v, ok := ci.resolve(constRef{importPath: "example.com/api", name: "ModuleName"})
+	require.True(ok, "constant should be resolved")
+	require.Equal("unrelated", v)
+
+	// Constants missing from the longest matching directory should not be resolved from others.
+	_, ok = ci.resolve(constRef{importPath: "github.com/oasisprotocol/oasis-core/go/registry/api", name: "Other"})
+	require.False(ok, "missing constants should not be resolved")
+	_, ok = ci.resolve(constRef{importPath: "example.com/other", name: "ModuleName"})
+	require.False(ok, "unknown packages should not be resolved")
+}
+
+func TestConstString(t *testing.T) {
+	require := require.New(t)
+
+	const src = `package test
+
+const (
+	prefix = "oasis_test_"
+	name   = prefix + "typed"
+)
+
+var help = "Not a constant."
+
+var _ = []interface{}{name, help, 42, "Literal \"help\"."}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.go", src, 0)
+	require.NoError(err, "ParseFile")
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	_, err = (&types.Config{}).Check("test", fset, []*ast.File{f}, info)
+	require.NoError(err, "Check")
+
+	var values []ast.Expr
+	ast.Inspect(f, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.CompositeLit); ok {
+			values = lit.Elts
+			return false
+		}
+		return true
+	})
+	require.Len(values, 4)
+
+	v, ok := constString(info, values[0])
+	require.True(ok, "constants should be resolved across declarations")
+	require.Equal("oasis_test_typed", v)
+	_, ok = constString(info, values[1])
+	require.False(ok, "variables should not be resolved")
+	_, ok = constString(info, values[2])
+	require.False(ok, "non-string constants should not be resolved")
+	v, ok = constString(info, values[3])
+	require.True(ok, "string literals should be resolved")
+	require.Equal(`Literal "help".`, v)
+	_, ok = constString(nil, values[0])
+	require.False(ok, "nothing should be resolved without type information")
+}
+
+func TestGroupMetrics(t *testing.T) {
+	require := require.New(t)
+
+	metrics := map[string]Metric{
+		"oasis_abci_db_size":      {Name: "oasis_abci_db_size", Type: "Gauge"},
+		"oasis_codec_size":        {Name: "oasis_codec_size", Type: "Summary"},
+		"oasis_codec_calls":       {Name: "oasis_codec_calls", Type: "Counter"},
+		"oasis_up":                {Name: "oasis_up", Type: "Gauge"},
+		"oasis_worker_processed":  {Name: "oasis_worker_processed", Type: "Counter"},
+		"oasis_worker_queue_size": {Name: "oasis_worker_queue_size", Type: "Gauge"},
+	}
+	groupNames := func(groups map[string]map[string]Metric) map[string][]string {
+		names := make(map[string][]string)
+		for group, ms := range groups {
+			for _, m := range ms {
+				names[group] = append(names[group], m.Name)
+			}
+			sort.Strings(names[group])
+		}
+		return names
+	}
+
+	require.Equal(map[string][]string{
+		"abci":    {"oasis_abci_db_size"},
+		"codec":   {"oasis_codec_calls", "oasis_codec_size"},
+		"worker":  {"oasis_worker_processed", "oasis_worker_queue_size"},
+		miscGroup: {"oasis_up"},
+	}, groupNames(groupMetrics(metrics, "subsystem")), "metrics should be grouped by subsystem")
+
+	require.Equal(map[string][]string{
+		"Counter": {"oasis_codec_calls", "oasis_worker_processed"},
+		"Gauge":   {"oasis_abci_db_size", "oasis_up", "oasis_worker_queue_size"},
+		"Summary": {"oasis_codec_size"},
+	}, groupNames(groupMetrics(metrics, "type")), "metrics should be grouped by type")
+}
+
+func TestEmbedMarkdownTable(t *testing.T) {
+	require := require.New(t)
+
+	const placeholder = "<!--- OASIS_METRICS -->"
+	md, err := embedMarkdownTable("# Metrics\n\n"+placeholder+"\n\nMore text.\n", placeholder, "| table |\n", nil)
+	require.NoError(err, "embedMarkdownTable")
+	require.True(strings.HasSuffix(md, "# Metrics\n\n| table |\n\nMore text.\n"), "placeholder should be replaced with the table")
+	require.NotContains(md, "Generated at", "provenance should only be included if given")
+
+	_, err = embedMarkdownTable("# Metrics\n\n<!--- METRICS -->\n", placeholder, "| table |\n", nil)
+	require.ErrorContains(err, placeholder, "missing placeholder should be reported")
+
+	prov := &Provenance{
+		GeneratedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Revision:    "0123abcd",
+	}
+	md, err = embedMarkdownTable(placeholder+"\n", placeholder, "| table |\n", prov)
+	require.NoError(err, "embedMarkdownTable")
+	require.Contains(md, "# Generated at: 2024-01-02T03:04:05Z\n# Revision: 0123abcd\n---\n", "provenance should be included in the header")
+
+	prov.Revision = ""
+	md, err = embedMarkdownTable(placeholder+"\n", placeholder, "| table |\n", prov)
+	require.NoError(err, "embedMarkdownTable")
+	require.Contains(md, "# Generated at: 2024-01-02T03:04:05Z\n---\n", "unknown revision should be omitted")
+}
+
+func TestMarkdownTableColumns(t *testing.T) {
+	require := require.New(t)
+
+	metrics := map[string]Metric{
+		"oasis_up": {Name: "oasis_up", Type: "Gauge", Help: "Up.", Stability: defaultStability},
+	}
+	md := markdownTable(metrics)
+	require.True(strings.HasPrefix(md, "Name | Type | Description | Labels | Package\n-----|------|-------------|--------|--------\n"), "empty columns should be omitted")
+	require.Contains(md, "oasis_up | Gauge | Up. |  | [", "empty columns should be omitted from the rows")
+
+	metrics["oasis_latency"] = Metric{Name: "oasis_latency", Type: "Summary", Help: "Latency.", Objectives: Objectives{0.5: 0.05}, Stability: defaultStability}
+	md = markdownTable(metrics)
+	require.True(strings.HasPrefix(md, "Name | Type | Description | Labels | Percentiles | Package\n"), "percentiles should be included if known")
+	require.Contains(md, "oasis_latency | Summary | Latency. |  | p50 | [")
+	require.Contains(md, "oasis_up | Gauge | Up. |  |  | [")
+
+	metrics["oasis_up"] = Metric{Name: "oasis_up", Type: "Gauge", Help: "Up.", Stability: "stable"}
+	md = markdownTable(metrics)
+	require.True(strings.HasPrefix(md, "Name | Type | Description | Labels | Percentiles | Stability | Package\n-----|------|-------------|--------|-------------|-----------|--------\n"), "stability should be included if known")
+	require.Contains(md, "oasis_latency | Summary | Latency. |  | p50 | unspecified | [")
+	require.Contains(md, "oasis_up | Gauge | Up. |  |  | stable | [")
+}
+
+func TestMarkdownTableBuildConstraint(t *testing.T) {
+	require := require.New(t)
+
+	md := markdownTable(map[string]Metric{
+		"oasis_sgx": {
+			Name:            "oasis_sgx",
+			Type:            "Gauge",
+			Help:            "SGX metric.",
+			BuildConstraint: "sgx && (linux || !windows)",
+		},
+	})
+	require.Contains(md, "SGX metric. _(only in builds matching `sgx && (linux \\|\\| !windows)`)_ |", "build constraint should be rendered with escaped pipes")
+}
+
+func TestMetricSetCanonical(t *testing.T) {
+	require := require.New(t)
+
+	metrics := MetricSet{
+		"oasis_latency": {Name: "oasis_latency", Type: "Summary", Labels: []string{"result", "method"}, Vec: true},
+		"oasis_up":      {Name: "oasis_up", Type: "Gauge"},
+	}
+
+	canonical := metrics.Canonical(false)
+	require.Equal([]string{"result", "method"}, canonical["oasis_latency"].Labels, "label order should be preserved by default")
+	require.Nil(canonical["oasis_up"].Labels)
+
+	canonical = metrics.Canonical(true)
+	require.Equal([]string{"method", "result"}, canonical["oasis_latency"].Labels, "labels should be sorted if requested")
+	require.Equal([]string{"result", "method"}, metrics["oasis_latency"].Labels, "the original labels should not be modified")
+
+	// Metric names should be serialized in order.
+	data, err := json.Marshal(canonical)
+	require.NoError(err, "Marshal")
+	require.Less(strings.Index(string(data), `"oasis_latency"`), strings.Index(string(data), `"oasis_up"`))
+}
+
+func TestPrintHash(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	output = &buf
+	defer func() {
+		output = os.Stdout
+	}()
+
+	metrics := MetricSet{
+		"oasis_up": {
+			Name:      "oasis_up",
+			Type:      "Gauge",
+			Help:      "Whether the node is up.",
+			Filename:  "a.go",
+			Line:      1,
+			Stability: "stable",
+		},
+		"oasis_latency": {
+			Name:       "oasis_latency",
+			Type:       "Summary",
+			Help:       "Request latency.",
+			Labels:     []string{"method", "result"},
+			Objectives: Objectives{0.5: 0.05, 0.9: 0.01},
+			Filename:   "b.go",
+			Line:       2,
+			Vec:        true,
+			Stability:  "experimental",
+		},
+	}
+	printHash(metrics)
+	require.Equal("7c4e022febd7966be07ba322550ee91b149b64b27c6de791f9458681100b12af\n", buf.String(), "hash should match the golden value")
+
+	// Locations and label order should not affect the hash.
+	m := metrics["oasis_latency"]
+	m.Filename, m.Line = "c.go", 3
+	m.Labels = []string{"result", "method"}
+	metrics["oasis_latency"] = m
+	require.Equal(strings.TrimSpace(buf.String()), metricsHash(metrics))
+
+	// Objectives and stability should affect the hash.
+	changed := m
+	changed.Objectives = Objectives{0.5: 0.05}
+	metrics["oasis_latency"] = changed
+	require.NotEqual(strings.TrimSpace(buf.String()), metricsHash(metrics), "objectives should be hashed")
+	changed = m
+	changed.Stability = "stable"
+	metrics["oasis_latency"] = changed
+	require.NotEqual(strings.TrimSpace(buf.String()), metricsHash(metrics), "stability should be hashed")
+}
+
+func TestPrintNames(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	output = &buf
+	defer func() {
+		output = os.Stdout
+	}()
+
+	printNames(MetricSet{
+		"oasis_up":      {Name: "oasis_up", Type: "Gauge"},
+		"oasis_latency": {Name: "oasis_latency", Type: "Summary", Labels: []string{"method"}},
+		"oasis_calls":   {Name: "oasis_calls", Type: "Counter"},
+	})
+	require.Equal("oasis_calls\noasis_latency\noasis_up\n", buf.String(), "names should be printed one per line, sorted")
+}
+
+func TestMetricsHashFields(t *testing.T) {
+	require := require.New(t)
+
+	base := Metric{Name: "oasis_calls_total", Type: "Counter", Help: "Number of calls.", Labels: []string{"method"}, Vec: true}
+	expected := metricsHash(MetricSet{base.Name: base})
+	for _, tc := range []struct {
+		field  string
+		modify func(m *Metric)
+	}{
+		{"name", func(m *Metric) { m.Name = "oasis_requests_total" }},
+		{"type", func(m *Metric) { m.Type = "Gauge" }},
+		{"help", func(m *Metric) { m.Help = "Number of requests." }},
+		{"labels", func(m *Metric) { m.Labels = []string{"method", "result"} }},
+		{"vec", func(m *Metric) { m.Vec = false }},
+	} {
+		m := base
+		tc.modify(&m)
+		require.NotEqual(expected, metricsHash(MetricSet{m.Name: m}), "%s should be hashed", tc.field)
+	}
+}
+
+func TestPrintJSONProvenance(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	output = &buf
+	defer func() {
+		output = os.Stdout
+	}()
+
+	// Directories outside of git repositories should not fail the run.
+	viper.Set(CfgProvenance, true)
+	viper.Set(CfgCodebasePath, t.TempDir())
+	defer func() {
+		viper.Set(CfgProvenance, false)
+		viper.Set(CfgCodebasePath, "")
+	}()
+
+	metrics := MetricSet{
+		"oasis_up": {Name: "oasis_up", Type: "Gauge", Filename: "a.go", Line: 1},
+	}
+	printJSON(metrics)
+
+	var out struct {
+		Metadata Provenance `json:"metadata"`
+		Metrics  MetricSet  `json:"metrics"`
+	}
+	require.NoError(json.Unmarshal(buf.Bytes(), &out), "Unmarshal")
+	require.False(out.Metadata.GeneratedAt.IsZero(), "generation time should be included")
+	require.Empty(out.Metadata.Revision, "revision should be omitted outside of git repositories")
+	require.Contains(out.Metrics, "oasis_up")
+}
+
+func TestOpenOutputGzip(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "metrics.json.gz")
+	viper.Set(CfgOutput, path)
+	viper.Set(CfgGzip, true)
+	defer func() {
+		viper.Set(CfgOutput, "")
+		viper.Set(CfgGzip, false)
+		output = os.Stdout
+	}()
+
+	metrics := MetricSet{
+		"oasis_up":      {Name: "oasis_up", Type: "Gauge", Help: "Up.", Filename: "a.go", Line: 1},
+		"oasis_latency": {Name: "oasis_latency", Type: "Summary", Help: "Latency.", Labels: []string{"method"}, Objectives: Objectives{0.5: 0.05}, Filename: "b.go", Line: 2, Vec: true},
+	}
+	closeOutput, err := openOutput()
+	require.NoError(err, "openOutput")
+	printJSON(metrics)
+	require.NoError(closeOutput(), "closeOutput")
+
+	f, err := os.Open(path)
+	require.NoError(err, "Open")
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(err, "gzip.NewReader")
+	data, err := io.ReadAll(zr)
+	require.NoError(err, "ReadAll")
+
+	var decoded MetricSet
+	require.NoError(json.Unmarshal(data, &decoded), "Unmarshal")
+	require.Equal(metrics, decoded, "metrics should survive a gzip round trip")
+}
+
+func TestStreamJSON(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	output = &buf
+	defer func() {
+		output = os.Stdout
+	}()
+
+	stream := streamJSON([]string{"Counter", "Gauge"})
+	for _, m := range []Metric{
+		{Name: "oasis_up", Type: "Gauge", Labels: []string{"role", "id"}, Filename: "a.go", Line: 1},
+		{Name: "oasis_requests_total", Type: "Counter", Filename: "a.go", Line: 2},
+		{Name: "oasis_latency", Type: "Histogram", Filename: "b.go", Line: 1},
+		{Name: "oasis_up", Type: "Gauge", Filename: "c.go", Line: 1},
+	} {
+		stream(m)
+	}
+
+	// Each metric should be printed as a line of JSON, at its first definition only.
+	var streamed []Metric
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var m Metric
+		require.NoError(dec.Decode(&m), "Decode")
+		streamed = append(streamed, m)
+	}
+	require.Len(streamed, 2, "filtered types and duplicate names should not be streamed")
+	require.Equal("oasis_up", streamed[0].Name)
+	require.Equal("a.go", streamed[0].Filename, "metrics should be streamed at their first definition")
+	require.Equal([]string{"role", "id"}, streamed[0].Labels, "label order should be preserved by default")
+	require.Equal("oasis_requests_total", streamed[1].Name)
+}
+
+func TestCodeowners(t *testing.T) {
+	require := require.New(t)
+
+	co, err := parseCodeowners(strings.NewReader(`# Default code owners.
+* @default
+
+# Rules matching at any level.
+*.md @docs # Inline comment.
+worker/ @worker
+
+# Rules relative to the root.
+/go/consensus/ @consensus
+go/runtime/*.go @runtime
+go/**/keymanager @keymanager
+/go/storage/mkvs
+`))
+	require.NoError(err, "parseCodeowners")
+
+	for path, owner := range map[string]string{
+		"README.md":                              "@docs",
+		"go/README.md":                           "@docs",
+		"go/common/errors/errors.go":             "@default",
+		"go/worker/common/p2p/metrics.go":        "@worker",
+		"go/consensus/cometbft/api/api.go":       "@consensus",
+		"consensus/cometbft/api/api.go":          "@default",
+		"go/runtime/runtime.go":                  "@runtime",
+		"go/runtime/host/host.go":                "@default",
+		"go/consensus/keymanager/api.go":         "@keymanager",
+		"go/keymanager/secrets/api.go":           "@keymanager",
+		"go/storage/mkvs/tree.go":                "",
+		"go/storage/mkvs/checkpoint/metrics.go":  "",
+		"go/storage/mkvsextra/metrics.go":        "@default",
+		"go/consensus/cometbft/worker/worker.go": "@consensus",
+	} {
+		require.Equal(owner, co.owner(path), "owner of %s", path)
+	}
+
+	// Special characters should be matched literally.
+	co, err = parseCodeowners(strings.NewReader("go/[a-z].go @literal\n"))
+	require.NoError(err, "parseCodeowners")
+	require.Equal("@literal", co.owner("go/[a-z].go"))
+	require.Empty(co.owner("go/a.go"))
+
+	// Metric files should be resolved relative to the repository root.
+	root := t.TempDir()
+	require.NoError(os.Mkdir(filepath.Join(root, ".github"), 0o755), "Mkdir")
+	path := filepath.Join(root, ".github", "CODEOWNERS")
+	require.NoError(os.WriteFile(path, []byte("/go/worker/ @worker @team\n"), 0o600), "WriteFile")
+	co, err = loadCodeowners(path)
+	require.NoError(err, "loadCodeowners")
+
+	var owners []string
+	collect := co.tag(func(m Metric) {
+		owners = append(owners, m.Owner)
+	})
+	collect(Metric{Filename: filepath.Join(root, "go", "worker", "metrics.go")})
+	collect(Metric{Filename: filepath.Join(root, "go", "consensus", "metrics.go")})
+	collect(Metric{Filename: filepath.Join(t.TempDir(), "go", "worker", "metrics.go")})
+	require.Equal([]string{"@worker @team", "", ""}, owners)
+}
+
+func TestIsIncluded(t *testing.T) {
+	require := require.New(t)
+
+	patterns := []string{"worker", "consensus/*/apps"}
+	for _, tc := range []struct {
+		path     string
+		included bool
+	}{
+		{"worker/metrics.go", true},
+		{"worker/common/metrics.go", true},
+		{"consensus/cometbft/apps/metrics.go", true},
+		{"consensus/cometbft/apps/keymanager/metrics.go", true},
+		{"consensus/cometbft/metrics.go", false},
+		{"oasis-node/worker/metrics.go", false},
+		{"metrics.go", false},
+	} {
+		require.Equal(tc.included, isIncluded(tc.path, patterns), "isIncluded(%q)", tc.path)
+	}
+
+	require.True(isIncluded("metrics.go", nil), "all paths should be included without patterns")
+}
+
+func TestFilterByType(t *testing.T) {
+	require := require.New(t)
+
+	metrics := MetricSet{
+		"oasis_calls_total":   {Name: "oasis_calls_total", Type: "Counter"},
+		"oasis_queue_size":    {Name: "oasis_queue_size", Type: "Gauge"},
+		"oasis_request_bytes": {Name: "oasis_request_bytes", Type: "Histogram"},
+		"oasis_latency":       {Name: "oasis_latency", Type: "Histogram", Vec: true},
+		"oasis_sizes":         {Name: "oasis_sizes", Type: "Summary"},
+	}
+	names := func(metrics MetricSet) []string {
+		var names []string
+		for name := range metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	require.Equal(metrics, filterByType(metrics, nil), "all metrics should be returned without types")
+	require.Equal([]string{"oasis_latency", "oasis_request_bytes"}, names(filterByType(metrics, []string{"Histogram"})), "vec metrics should match their base type")
+	require.Equal([]string{"oasis_calls_total", "oasis_sizes"}, names(filterByType(metrics, []string{"Counter", "Summary"})))
+	require.Empty(filterByType(metrics, []string{"Untyped"}))
+}
+
+func TestLintMetrics(t *testing.T) {
+	require := require.New(t)
+
+	metrics := MetricSet{
+		"oasis_calls_total":  {Name: "oasis_calls_total", Type: "Counter", Labels: []string{"method"}, Vec: true, Filename: "a.go", Line: 1},
+		"oasis_up":           {Name: "oasis_up", Type: "Gauge", Filename: "a.go", Line: 2},
+		"oasis_queue_size":   {Name: "oasis_queue_size", Type: "Gauge", Labels: []string{"queue", "kind"}, Filename: "b.go", Line: 1},
+		"oasis_failed_total": {Name: "oasis_failed_total", Type: "Counter", Labels: []string{"reason"}, Filename: "a.go", Line: 3},
+	}
+	issues := lintMetrics(metrics)
+	require.Equal([]string{
+		"a.go:3: metric oasis_failed_total is not a vec but declares labels: reason",
+		"b.go:1: metric oasis_queue_size is not a vec but declares labels: queue, kind",
+	}, findingStrings(issues), "only non-vec metrics with labels should be flagged")
+	for _, issue := range issues {
+		require.Equal("labels-without-vec", issue.Rule)
+		require.Equal(severityError, issue.Severity)
+	}
+}
+
+func TestLintCounterNames(t *testing.T) {
+	require := require.New(t)
+
+	metrics := MetricSet{
+		"oasis_calls_total":   {Name: "oasis_calls_total", Type: "Counter", Filename: "a.go", Line: 1},
+		"oasis_calls":         {Name: "oasis_calls", Type: "Counter", Filename: "a.go", Line: 2},
+		"oasis_legacy_calls":  {Name: "oasis_legacy_calls", Type: "Counter", Filename: "a.go", Line: 3},
+		"oasis_failures":      {Name: "oasis_failures", Type: "Counter", Vec: true, Filename: "b.go", Line: 1},
+		"oasis_queue_size":    {Name: "oasis_queue_size", Type: "Gauge", Filename: "b.go", Line: 2},
+		"oasis_request_bytes": {Name: "oasis_request_bytes", Type: "Histogram", Filename: "b.go", Line: 3},
+	}
+	require.Equal([]string{
+		"a.go:2: counter oasis_calls does not end in _total",
+		"b.go:1: counter oasis_failures does not end in _total",
+	}, findingStrings(lintCounterNames(metrics, []string{"oasis_legacy_calls"})), "only counters not in the allowlist should be flagged")
+}
+
+func TestLintLabelNames(t *testing.T) {
+	require := require.New(t)
+
+	metrics := MetricSet{
+		"oasis_calls_total": {Name: "oasis_calls_total", Labels: []string{"runtime_id", "method"}},
+		"oasis_queue_size":  {Name: "oasis_queue_size", Labels: []string{"runtimeID"}},
+		"oasis_errors":      {Name: "oasis_errors", Labels: []string{"runtimeid", "Method"}},
+		"oasis_latency":     {Name: "oasis_latency", Labels: []string{"runtime_id", "method"}},
+		"oasis_up":          {Name: "oasis_up"},
+	}
+	warnings := lintLabelNames(metrics)
+	require.Equal([]string{
+		"inconsistently named labels: Method (oasis_errors), method (oasis_calls_total, oasis_latency)",
+		"inconsistently named labels: runtimeID (oasis_queue_size), runtime_id (oasis_calls_total, oasis_latency), runtimeid (oasis_errors)",
+	}, findingStrings(warnings), "labels colliding when ignoring case and underscores should be reported")
+	for _, warning := range warnings {
+		require.Equal("label-names", warning.Rule)
+		require.Equal(severityWarning, warning.Severity)
+	}
+
+	require.Empty(lintLabelNames(MetricSet{"oasis_latency": metrics["oasis_latency"]}), "consistent labels should not be reported")
+}
+
+func TestLintDuplicates(t *testing.T) {
+	require := require.New(t)
+
+	duplicates := map[string][]Metric{
+		"oasis_calls_total": {
+			{Name: "oasis_calls_total", Help: "Number of calls.", Labels: []string{"method"}, Filename: "a.go", Line: 1},
+			{Name: "oasis_calls_total", Help: "Number of calls.", Labels: []string{"method"}, Filename: "b.go", Line: 2},
+		},
+		"oasis_queue_size": {
+			{Name: "oasis_queue_size", Help: "Queue size.", Filename: "a.go", Line: 3},
+			{Name: "oasis_queue_size", Help: "Number of queued items.", Filename: "c.go", Line: 4},
+		},
+		"oasis_failures_total": {
+			{Name: "oasis_failures_total", Help: "Failures.", Labels: []string{"reason"}, Filename: "a.go", Line: 5},
+			{Name: "oasis_failures_total", Help: "Failures.", Labels: []string{"reason", "method"}, Filename: "c.go", Line: 6},
+		},
+		"oasis_filtered_total": {
+			{Name: "oasis_filtered_total", Help: "Filtered.", Filename: "a.go", Line: 7},
+			{Name: "oasis_filtered_total", Help: "Other.", Filename: "b.go", Line: 8},
+		},
+		"oasis_reordered_total": {
+			{Name: "oasis_reordered_total", Help: "Reordered.", Labels: []string{"method", "result"}, Filename: "a.go", Line: 9},
+			{Name: "oasis_reordered_total", Help: "Reordered.", Labels: []string{"result", "method"}, Filename: "b.go", Line: 10},
+		},
+	}
+	metrics := MetricSet{
+		"oasis_calls_total":     duplicates["oasis_calls_total"][1],
+		"oasis_queue_size":      duplicates["oasis_queue_size"][1],
+		"oasis_failures_total":  duplicates["oasis_failures_total"][1],
+		"oasis_reordered_total": duplicates["oasis_reordered_total"][1],
+	}
+
+	issues, warnings := lintDuplicates(metrics, duplicates)
+	require.Equal([]string{
+		"metric oasis_failures_total has conflicting definitions:" +
+			"\n\ta.go:5: help \"Failures.\", labels [reason]" +
+			"\n\tc.go:6: help \"Failures.\", labels [reason, method]",
+		"metric oasis_queue_size has conflicting definitions:" +
+			"\n\ta.go:3: help \"Queue size.\", labels []" +
+			"\n\tc.go:4: help \"Number of queued items.\", labels []",
+	}, findingStrings(issues), "duplicates with different help or labels should be reported as issues")
+	require.Equal([]string{
+		"metric oasis_calls_total is defined multiple times: a.go:1, b.go:2",
+		"metric oasis_reordered_total is defined multiple times: a.go:9, b.go:10",
+	}, findingStrings(warnings), "identical duplicates should only be reported as warnings, regardless of the label order")
+}
+
+func TestCollectMetricSources(t *testing.T) {
+	require := require.New(t)
+
+	oldMetrics, oldDuplicates := metrics, duplicates
+	metrics, duplicates = MetricSet{}, make(map[string][]Metric)
+	defer func() {
+		metrics, duplicates = oldMetrics, oldDuplicates
+	}()
+
+	collectMetric(Metric{Name: "oasis_up", Help: "First.", Filename: "b.go", Line: 1, Column: 2})
+	collectMetric(Metric{Name: "oasis_calls_total", Filename: "a.go", Line: 3, Column: 4})
+	collectMetric(Metric{Name: "oasis_up", Help: "Second.", Filename: "a.go", Line: 5, Column: 6})
+	collectMetric(Metric{Name: "oasis_up", Help: "Third.", Filename: "c.go", Line: 7, Column: 8})
+
+	// The first definition should be kept, listing the locations of all definitions.
+	up := metrics["oasis_up"]
+	require.Equal("First.", up.Help, "first definition should be kept")
+	require.Equal("b.go", up.Filename)
+	require.Equal(1, up.Line)
+	require.Equal([]SourceLoc{
+		{Filename: "b.go", Line: 1, Column: 2},
+		{Filename: "a.go", Line: 5, Column: 6},
+		{Filename: "c.go", Line: 7, Column: 8},
+	}, up.Sources, "all definitions should be listed")
+	require.Len(duplicates["oasis_up"], 3)
+
+	// Metrics defined once should not list their sources.
+	require.Empty(metrics["oasis_calls_total"].Sources)
+}
+
+func TestLintDuplicateTypes(t *testing.T) {
+	require := require.New(t)
+
+	duplicates := map[string][]Metric{
+		"oasis_calls_total": {
+			{Name: "oasis_calls_total", Type: "Counter", Help: "Number of calls.", Filename: "a.go", Line: 1},
+			{Name: "oasis_calls_total", Type: "Counter", Help: "Number of calls.", Filename: "b.go", Line: 2},
+		},
+		"oasis_queue_size": {
+			{Name: "oasis_queue_size", Type: "Gauge", Help: "Queue size.", Filename: "a.go", Line: 3},
+			{Name: "oasis_queue_size", Type: "Counter", Help: "Queue size.", Filename: "c.go", Line: 4},
+		},
+		"oasis_filtered": {
+			{Name: "oasis_filtered", Type: "Gauge", Filename: "a.go", Line: 5},
+			{Name: "oasis_filtered", Type: "Histogram", Filename: "b.go", Line: 6},
+		},
+	}
+	metrics := MetricSet{
+		"oasis_calls_total": duplicates["oasis_calls_total"][1],
+		"oasis_queue_size":  duplicates["oasis_queue_size"][1],
+	}
+
+	require.Equal([]string{
+		"metric oasis_queue_size is registered with different types:" +
+			"\n\ta.go:3: Gauge" +
+			"\n\tc.go:4: Counter",
+	}, findingStrings(lintDuplicateTypes(metrics, duplicates)), "only metrics with different types should be reported")
+
+	// Type conflicts should not be reported again as benign duplicates.
+	issues, warnings := lintDuplicates(metrics, duplicates)
+	require.Empty(issues, "type conflicts should not be reported as conflicting definitions")
+	require.Equal([]string{
+		"metric oasis_calls_total is defined multiple times: a.go:1, b.go:2",
+	}, findingStrings(warnings))
+}
+
+func TestPrintLintJSON(t *testing.T) {
+	require := require.New(t)
+
+	viper.Set(CfgLintFormat, lintFormatJSON)
+	defer viper.Set(CfgLintFormat, lintFormatText)
+
+	var buf bytes.Buffer
+	output = &buf
+	defer func() {
+		output = os.Stdout
+	}()
+
+	// Clean metrics should produce an empty report.
+	metrics := MetricSet{
+		"oasis_calls_total": {Name: "oasis_calls_total", Type: "Counter", Labels: []string{"runtime_id"}, Vec: true, Filename: "a.go", Line: 1},
+	}
+	require.False(printLint(metrics), "clean metrics should pass")
+	require.Equal("[]\n", buf.String())
+
+	// Warnings should be reported without failing the check.
+	metrics["oasis_queue_size"] = Metric{Name: "oasis_queue_size", Type: "Gauge", Labels: []string{"runtimeID"}, Vec: true, Filename: "b.go", Line: 2}
+	buf.Reset()
+	require.False(printLint(metrics), "warnings should not fail the check")
+
+	var findings []Finding
+	require.NoError(json.Unmarshal(buf.Bytes(), &findings), "Unmarshal")
+	require.Equal([]Finding{{
+		Rule:     "label-names",
+		Severity: severityWarning,
+		Message:  "inconsistently named labels: runtimeID (oasis_queue_size), runtime_id (oasis_calls_total)",
+	}}, findings)
+
+	// Errors should fail the check.
+	metrics["oasis_calls"] = Metric{Name: "oasis_calls", Type: "Counter", Filename: "c.go", Line: 3}
+	buf.Reset()
+	require.True(printLint(metrics), "errors should fail the check")
+
+	findings = nil
+	require.NoError(json.Unmarshal(buf.Bytes(), &findings), "Unmarshal")
+	require.Equal(Finding{
+		Rule:     "counter-suffix",
+		Severity: severityError,
+		Metric:   "oasis_calls",
+		Filename: "c.go",
+		Line:     3,
+		Message:  "counter oasis_calls does not end in _total",
+	}, findings[0])
+}
+
+// findingStrings returns the human-readable forms of the given lint findings.
+func findingStrings(findings []Finding) []string {
+	strs := make([]string, 0, len(findings))
+	for _, f := range findings {
+		strs = append(strs, f.String())
+	}
+	return strs
+}
+
+func TestPrintLintErrorOn(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	output = &buf
+	defer func() {
+		output = os.Stdout
+	}()
+
+	metrics := MetricSet{
+		"oasis_calls_total": {Name: "oasis_calls_total", Type: "Counter", Labels: []string{"runtime_id"}, Vec: true, Filename: "a.go", Line: 1},
+		"oasis_queue_size":  {Name: "oasis_queue_size", Type: "Gauge", Labels: []string{"runtimeID"}, Vec: true, Filename: "b.go", Line: 2},
+	}
+	require.False(printLint(metrics), "warnings should not fail the check")
+	require.True(strings.HasPrefix(buf.String(), "warning: "), "finding should be reported as a warning")
+
+	// Warnings of other rules should not be escalated.
+	viper.Set(CfgLintErrorOn, []string{"duplicate"})
+	defer viper.Set(CfgLintErrorOn, []string(nil))
+	buf.Reset()
+	require.False(printLint(metrics), "warnings of other rules should not fail the check")
+
+	// Warnings of the given rules should be escalated.
+	viper.Set(CfgLintErrorOn, []string{"duplicate", "label-names"})
+	buf.Reset()
+	require.True(printLint(metrics), "escalated warnings should fail the check")
+	require.Equal("inconsistently named labels: runtimeID (oasis_queue_size), runtime_id (oasis_calls_total)\n", buf.String())
+}
+
+func TestPackageIndex(t *testing.T) {
+	require := require.New(t)
+
+	viper.Set(CfgCodebasePath, "/src/go")
+	defer viper.Set(CfgCodebasePath, "")
+
+	metrics := MetricSet{
+		"oasis_up":               {Name: "oasis_up", Filename: "/src/go/metrics.go"},
+		"oasis_worker_processed": {Name: "oasis_worker_processed", Filename: "/src/go/worker/common/metrics.go"},
+		"oasis_worker_failed":    {Name: "oasis_worker_failed", Filename: "/src/go/worker/common/worker.go"},
+		"oasis_codec_calls":      {Name: "oasis_codec_calls", Filename: "/src/go/common/cbor/codec.go"},
+	}
+
+	require.Equal(map[string][]string{
+		"example.com/go":               {"oasis_up"},
+		"example.com/go/common/cbor":   {"oasis_codec_calls"},
+		"example.com/go/worker/common": {"oasis_worker_failed", "oasis_worker_processed"},
+	}, packageIndex(metrics, "example.com/go"), "metrics should be indexed by package path")
+
+	require.Equal(map[string][]string{
+		".":             {"oasis_up"},
+		"common/cbor":   {"oasis_codec_calls"},
+		"worker/common": {"oasis_worker_failed", "oasis_worker_processed"},
+	}, packageIndex(metrics, ""), "relative package paths should be used without a module")
+}
@@ -0,0 +1,10 @@
+//go:build sgx && (linux || !windows)
+
+package testdata
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var sgxGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "oasis_test_sgx",
+	Help: "Only defined in SGX builds.",
+})
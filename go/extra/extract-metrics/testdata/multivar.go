@@ -0,0 +1,51 @@
+package testdata
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	firstGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_test_first",
+			Help: "First metric.",
+		},
+	)
+	secondCounter, thirdCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_test_second",
+			Help: "Second metric.",
+		},
+		[]string{"label"},
+	), prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "oasis_test_third",
+			Help: "Third metric.",
+		},
+	)
+)
+
+type metrics struct {
+	fourth prometheus.Gauge
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		fourth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "oasis_test_fourth",
+			Help: "Fourth metric.",
+		}),
+	}
+	fifthGauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "oasis_test_fifth",
+		Help: "Fifth metric.",
+	})
+	prometheus.MustRegister(fifthGauge, prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "oasis_test_sixth",
+		Help: "Sixth metric.",
+	}))
+	return m
+}
+
+var SeventhGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "oasis_test_seventh",
+	Help: "Seventh metric.",
+})
@@ -0,0 +1,26 @@
+package testdata
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var nestedLabels = []string{"runtime", "kind"}
+
+func registerNested(reg *prometheus.Registry) error {
+	prometheus.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "oasis_test_nested_total",
+				Help: "Constructed inside MustRegister.",
+			},
+			[]string{"runtime"},
+		),
+	)
+	reg.MustRegister(prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oasis_test_nested_labels",
+		Help: "Constructed inside MustRegister with labels from a variable.",
+	}, nestedLabels))
+	return reg.Register(prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Name: "oasis_test_nested_summary",
+		Help: "Constructed inside Register.",
+	}, []string{"method", "result"}))
+}
@@ -0,0 +1,20 @@
+package testdata
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	latencySummary = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       "oasis_test_latency",
+			Help:       "Latency.",
+			Objectives: map[float64]float64{0.5: 0.05, .9: 1e-2, 0.99: 0.001},
+		},
+		[]string{"call"},
+	)
+	sizeSummary = prometheus.NewSummary(
+		prometheus.SummaryOpts{
+			Name: "oasis_test_size",
+			Help: "Size.",
+		},
+	)
+)
@@ -0,0 +1,21 @@
+package testdata
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	packageOpts = prometheus.GaugeOpts{
+		Name: "oasis_test_package_opts",
+		Help: "Opts declared at package level.",
+	}
+	packageLabels = []string{"runtime"}
+
+	packageOptsGauge = prometheus.NewGaugeVec(packageOpts, packageLabels)
+)
+
+func newOptsGauge() prometheus.Gauge {
+	opts := prometheus.GaugeOpts{
+		Name: "oasis_test_local_opts",
+		Help: "Opts assigned within a function.",
+	}
+	return prometheus.NewGauge(opts)
+}
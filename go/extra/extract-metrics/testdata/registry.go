@@ -0,0 +1,44 @@
+package testdata
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	defaultCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "oasis_test_default_total",
+		Help: "Registered into the default registry.",
+	})
+	customGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "oasis_test_custom",
+		Help: "Registered into a custom registry.",
+	})
+	unregisteredGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "oasis_test_unregistered",
+		Help: "Not registered.",
+	})
+
+	testCollectors = []prometheus.Collector{
+		defaultCounter,
+	}
+)
+
+type service struct {
+	registry *prometheus.Registry
+	field    prometheus.Gauge
+}
+
+func newService(reg, serviceReg *prometheus.Registry) *service {
+	s := &service{
+		registry: serviceReg,
+		field: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "oasis_test_field",
+			Help: "Registered into a registry field.",
+		}),
+	}
+	s.registry.MustRegister(s.field)
+	reg.MustRegister(customGauge, prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "oasis_test_inline",
+		Help: "Registered inline into a custom registry.",
+	}))
+	prometheus.MustRegister(testCollectors...)
+	return s
+}
@@ -0,0 +1,38 @@
+package testdata
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	backendName   = "badger"
+	sizeFormat    = "Size of the %s %s (bytes)."
+	maxQueueItems = 100
+)
+
+var dynamicName = newName()
+
+func newName() string {
+	return "dynamic"
+}
+
+var (
+	literalGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "oasis_test_literal",
+		Help: fmt.Sprintf("Size of the %s database (bytes).", "badger"),
+	})
+	constGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "oasis_test_const",
+		Help: fmt.Sprintf(sizeFormat, backendName, "database"),
+	})
+	numberGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "oasis_test_number",
+		Help: fmt.Sprintf("Queue size (max: %d, ratio: %.1f).", maxQueueItems, 0.5),
+	})
+	dynamicGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "oasis_test_dynamic",
+		Help: fmt.Sprintf("Size of the %s database (bytes).", dynamicName),
+	})
+)
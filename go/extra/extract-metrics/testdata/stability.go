@@ -0,0 +1,48 @@
+package testdata
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// metric:stable
+var stableGauge = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "oasis_test_stable",
+		Help: "Annotated declaration.",
+	},
+)
+
+var (
+	// metric:experimental
+	experimentalGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_test_experimental",
+			Help: "Annotated value spec.",
+		},
+	)
+	plainGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_test_plain",
+			Help: "Not annotated.",
+		},
+	)
+)
+
+type stabilityMetrics struct {
+	field prometheus.Gauge
+	local prometheus.Gauge
+}
+
+// metric:deprecated
+func newStabilityMetrics() *stabilityMetrics {
+	m := &stabilityMetrics{
+		// metric:beta
+		field: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "oasis_test_field_beta",
+			Help: "Annotated struct field.",
+		}),
+	}
+	m.local = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "oasis_test_function",
+		Help: "Function comments do not apply.",
+	})
+	return m
+}
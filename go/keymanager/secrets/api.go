@@ -2,8 +2,10 @@
 package secrets
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	"github.com/oasisprotocol/oasis-core/go/common/errors"
 	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
 	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
 	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
 )
@@ -43,6 +46,21 @@ var (
 	// does not exist.
 	ErrNoSuchEphemeralSecret = errors.New(moduleName, 4, "keymanager: no such ephemeral secret")
 
+	// ErrNoSuchPolicy is the error returned when a key manager policy does not exist.
+	ErrNoSuchPolicy = errors.New(moduleName, 5, "keymanager: no such policy")
+
+	// ErrInvalidGeneration is the error returned when a master secret is not proposed
+	// for the next generation.
+	ErrInvalidGeneration = errors.New(moduleName, 6, "keymanager: invalid master secret generation")
+
+	// ErrNoSuchReplicationFailures is the error returned when no master secret rotation
+	// has been accepted yet.
+	ErrNoSuchReplicationFailures = errors.New(moduleName, 7, "keymanager: no such replication failures")
+
+	// ErrKeyManagerNotInitialized is the error returned when a key manager status exists,
+	// but the key manager has not been initialized yet.
+	ErrKeyManagerNotInitialized = errors.New(moduleName, 8, "keymanager: key manager not initialized")
+
 	// MethodUpdatePolicy is the method name for policy updates.
 	MethodUpdatePolicy = transaction.NewMethodName(moduleName, "UpdatePolicy", SignedPolicySGX{})
 
@@ -52,11 +70,15 @@ var (
 	// MethodPublishEphemeralSecret is the method name for publishing ephemeral secret.
 	MethodPublishEphemeralSecret = transaction.NewMethodName(moduleName, "PublishEphemeralSecret", SignedEncryptedEphemeralSecret{})
 
+	// MethodRefreshStatus is the method name for refreshing key manager statuses.
+	MethodRefreshStatus = transaction.NewMethodName(moduleName, "RefreshStatus", StatusRefresh{})
+
 	// Methods is the list of all methods supported by the key manager backend.
 	Methods = []transaction.MethodName{
 		MethodUpdatePolicy,
 		MethodPublishMasterSecret,
 		MethodPublishEphemeralSecret,
+		MethodRefreshStatus,
 	}
 
 	// RPCMethodInit is the name of the `init` method.
@@ -106,8 +128,14 @@ const (
 	// GasOpPublishEphemeralSecret is the gas operation identifier for publishing
 	// key manager ephemeral secret.
 	GasOpPublishEphemeralSecret transaction.Op = "publish_ephemeral_secret"
+	// GasOpRefreshStatus is the gas operation identifier for refreshing
+	// key manager statuses.
+	GasOpRefreshStatus transaction.Op = "refresh_status"
 )
 
+// DefaultMaxPolicySize is the "default" maximum size of a signed policy.
+const DefaultMaxPolicySize = 128 * 1024
+
 // XXX: Define reasonable default gas costs.
 
 // DefaultGasCosts are the "default" gas costs for operations.
@@ -115,6 +143,7 @@ var DefaultGasCosts = transaction.Costs{
 	GasOpUpdatePolicy:           1000,
 	GasOpPublishMasterSecret:    1000,
 	GasOpPublishEphemeralSecret: 1000,
+	GasOpRefreshStatus:          1000,
 }
 
 // KeyPairID is a 256-bit key pair identifier.
@@ -131,6 +160,10 @@ type Status struct {
 	// IsSecure is true iff the key manager is secure.
 	IsSecure bool `json:"is_secure"`
 
+	// IsDegraded is true iff fewer than the minimum required percentage of the eligible key
+	// manager nodes passed secure verification.
+	IsDegraded bool `json:"is_degraded,omitempty"`
+
 	// Generation is the generation of the latest master secret.
 	Generation uint64 `json:"generation,omitempty"`
 
@@ -140,14 +173,94 @@ type Status struct {
 	// Checksum is the key manager master secret verification checksum.
 	Checksum []byte `json:"checksum"`
 
+	// RotationPending is true iff the proposal for the next master secret has been published
+	// but not yet replicated by enough committee nodes to be accepted.
+	RotationPending bool `json:"rotation_pending,omitempty"`
+
+	// PendingGeneration is the generation of the pending proposal, if any.
+	PendingGeneration uint64 `json:"pending_generation,omitempty"`
+
+	// PendingEpoch is the epoch of the pending proposal, if any.
+	PendingEpoch beacon.EpochTime `json:"pending_epoch,omitempty"`
+
 	// Nodes is the list of currently active key manager node IDs.
 	Nodes []signature.PublicKey `json:"nodes"`
 
+	// StandbyNodes is the list of key manager node IDs that qualify for the committee, but
+	// exceed the active committee size limit. Standby nodes are promoted to the active
+	// committee once it has free places.
+	StandbyNodes []signature.PublicKey `json:"standby_nodes,omitempty"`
+
 	// Policy is the key manager policy.
 	Policy *SignedPolicySGX `json:"policy"`
 
+	// PolicyDescription is the description of the last policy update, if any.
+	PolicyDescription string `json:"policy_description,omitempty"`
+
 	// RSK is the runtime signing key of the key manager.
 	RSK *signature.PublicKey `json:"rsk,omitempty"`
+
+	// ReportedNodes is the number of key manager nodes that reported a verifiable
+	// initialization response while the key manager is still initializing.
+	//
+	// This field is purely informational and is cleared once the key manager is initialized.
+	ReportedNodes uint64 `json:"reported_nodes,omitempty"`
+
+	// SupportedVersions are the key manager runtime versions run by the committee nodes,
+	// sorted by version.
+	SupportedVersions []VersionCount `json:"supported_versions,omitempty"`
+
+	// CompromisedGenerations are the sorted generations of the master secret that have been
+	// marked as compromised. Runtimes should stop trusting data encrypted under these
+	// generations and re-encrypt it with a newer one.
+	CompromisedGenerations []uint64 `json:"compromised_generations,omitempty"`
+
+	// LastSeenEpochs are the epochs in which the committee nodes were last admitted to the
+	// committee, keyed by node ID. Nodes that dropped out of the committee are retained for
+	// a few epochs, so that recently departed nodes can be told apart from new ones.
+	//
+	// Changes of last seen epochs alone don't trigger status update events.
+	LastSeenEpochs map[signature.PublicKey]beacon.EpochTime `json:"last_seen_epochs,omitempty"`
+
+	// CommitteeChanges are the committee changes made in the most recent epoch in which
+	// the committee changed, tracked only if the number of committee changes per epoch
+	// is limited. Changes made when the status is regenerated within the same epoch count
+	// towards the same limits.
+	CommitteeChanges *CommitteeChanges `json:"committee_changes,omitempty"`
+}
+
+// CommitteeChanges are the key manager committee changes made in an epoch.
+type CommitteeChanges struct {
+	// Epoch is the epoch in which the changes were made.
+	Epoch beacon.EpochTime `json:"epoch"`
+
+	// Additions is the number of nodes that joined the committee.
+	Additions uint64 `json:"additions,omitempty"`
+
+	// Removals is the number of nodes that left the committee.
+	Removals uint64 `json:"removals,omitempty"`
+}
+
+// VersionCount is the number of key manager committee nodes running a runtime version.
+type VersionCount struct {
+	// Version is the key manager runtime version.
+	Version version.Version `json:"version"`
+
+	// Nodes is the number of committee nodes running the version.
+	Nodes uint64 `json:"nodes"`
+}
+
+// IsInitializing returns true iff the key manager is not yet initialized but some nodes
+// have already reported in.
+func (s *Status) IsInitializing() bool {
+	return !s.IsInitialized && s.ReportedNodes > 0
+}
+
+// IsGenerationCompromised returns true iff the given generation of the master secret
+// has been marked as compromised.
+func (s *Status) IsGenerationCompromised(generation uint64) bool {
+	_, found := slices.BinarySearch(s.CompromisedGenerations, generation)
+	return found
 }
 
 // NextGeneration returns the generation of the next master secret.
@@ -158,10 +271,13 @@ func (s *Status) NextGeneration() uint64 {
 	return s.Generation + 1
 }
 
-// VerifyRotationEpoch verifies if rotation can be performed in the given epoch.
-func (s *Status) VerifyRotationEpoch(epoch beacon.EpochTime) error {
+// NextRotationEpoch returns the earliest epoch in which the master secret can be rotated.
+//
+// If rotation is disabled, beacon.EpochInvalid is returned. If no master secret has been
+// generated yet, the first one can be generated in any epoch.
+func (s *Status) NextRotationEpoch() beacon.EpochTime {
 	if nextGen := s.NextGeneration(); nextGen == 0 {
-		return nil
+		return 0
 	}
 
 	// By default, rotation is disabled unless specified in the policy.
@@ -169,14 +285,39 @@ func (s *Status) VerifyRotationEpoch(epoch beacon.EpochTime) error {
 	if s.Policy != nil {
 		rotationInterval = s.Policy.Policy.MasterSecretRotationInterval
 	}
+	if rotationInterval == 0 {
+		return beacon.EpochInvalid
+	}
+
+	// Saturate instead of overflowing for huge intervals.
+	if rotationInterval >= beacon.EpochInvalid-s.RotationEpoch {
+		return beacon.EpochInvalid
+	}
+	return s.RotationEpoch + rotationInterval
+}
+
+// IsCommitteeFrozen returns true iff the committee membership is frozen in the given epoch.
+func (s *Status) IsCommitteeFrozen(epoch beacon.EpochTime) bool {
+	if !s.IsInitialized || len(s.Nodes) == 0 || s.Policy == nil {
+		return false
+	}
+	freezePeriod := s.Policy.Policy.CommitteeFreezePeriod
+	if freezePeriod == 0 {
+		return false
+	}
+	return epoch >= s.RotationEpoch && epoch-s.RotationEpoch < freezePeriod
+}
+
+// VerifyRotationEpoch verifies if rotation can be performed in the given epoch.
+func (s *Status) VerifyRotationEpoch(epoch beacon.EpochTime) error {
+	rotationEpoch := s.NextRotationEpoch()
 
 	// Reject if rotation is disabled.
-	if rotationInterval == 0 {
+	if rotationEpoch == beacon.EpochInvalid {
 		return fmt.Errorf("master secret rotation disabled")
 	}
 
 	// Reject if the rotation period has not expired.
-	rotationEpoch := s.RotationEpoch + rotationInterval
 	if epoch < rotationEpoch {
 		return fmt.Errorf("master secret rotation interval has not yet expired")
 	}
@@ -184,14 +325,342 @@ func (s *Status) VerifyRotationEpoch(epoch beacon.EpochTime) error {
 	return nil
 }
 
+// ChangedFields returns the names of the fields that differ between the given status and
+// this status. Field names match the serialized field names.
+//
+// Last seen epochs are not compared, as they advance on every epoch transition.
+func (s *Status) ChangedFields(old *Status) []string {
+	var changed []string
+	if !s.ID.Equal(&old.ID) {
+		changed = append(changed, "id")
+	}
+	if s.IsInitialized != old.IsInitialized {
+		changed = append(changed, "is_initialized")
+	}
+	if s.IsSecure != old.IsSecure {
+		changed = append(changed, "is_secure")
+	}
+	if s.IsDegraded != old.IsDegraded {
+		changed = append(changed, "is_degraded")
+	}
+	if s.Generation != old.Generation {
+		changed = append(changed, "generation")
+	}
+	if s.RotationEpoch != old.RotationEpoch {
+		changed = append(changed, "rotation_epoch")
+	}
+	if !bytes.Equal(s.Checksum, old.Checksum) {
+		changed = append(changed, "checksum")
+	}
+	if s.RotationPending != old.RotationPending {
+		changed = append(changed, "rotation_pending")
+	}
+	if s.PendingGeneration != old.PendingGeneration {
+		changed = append(changed, "pending_generation")
+	}
+	if s.PendingEpoch != old.PendingEpoch {
+		changed = append(changed, "pending_epoch")
+	}
+	if !slices.Equal(s.Nodes, old.Nodes) {
+		changed = append(changed, "nodes")
+	}
+	if !slices.Equal(s.StandbyNodes, old.StandbyNodes) {
+		changed = append(changed, "standby_nodes")
+	}
+	if !bytes.Equal(cbor.Marshal(s.Policy), cbor.Marshal(old.Policy)) {
+		changed = append(changed, "policy")
+	}
+	if s.PolicyDescription != old.PolicyDescription {
+		changed = append(changed, "policy_description")
+	}
+	switch {
+	case s.RSK == nil && old.RSK == nil:
+	case s.RSK == nil, old.RSK == nil, !s.RSK.Equal(*old.RSK):
+		changed = append(changed, "rsk")
+	}
+	if s.ReportedNodes != old.ReportedNodes {
+		changed = append(changed, "reported_nodes")
+	}
+	if !slices.Equal(s.SupportedVersions, old.SupportedVersions) {
+		changed = append(changed, "supported_versions")
+	}
+	if !slices.Equal(s.CompromisedGenerations, old.CompromisedGenerations) {
+		changed = append(changed, "compromised_generations")
+	}
+	switch {
+	case s.CommitteeChanges == nil && old.CommitteeChanges == nil:
+	case s.CommitteeChanges == nil, old.CommitteeChanges == nil, *s.CommitteeChanges != *old.CommitteeChanges:
+		changed = append(changed, "committee_changes")
+	}
+	return changed
+}
+
+// Kinds of key manager status changes.
+const (
+	StatusChangeInitialized        = "initialized"
+	StatusChangeGenerationAdvanced = "generation_advanced"
+	StatusChangeRSKChanged         = "rsk_changed"
+	StatusChangePolicyChanged      = "policy_changed"
+	StatusChangeSecretsCompromised = "secrets_compromised"
+	StatusChangeNodeAdded          = "node_added"
+	StatusChangeNodeRemoved        = "node_removed"
+)
+
+// StatusChange is a change between two key manager statuses.
+type StatusChange struct {
+	// Kind is the kind of the change.
+	Kind string `json:"kind"`
+
+	// NodeID is the ID of the committee node that was added or removed, if any.
+	NodeID *signature.PublicKey `json:"node_id,omitempty"`
+}
+
+// Diff returns the changes between the given status and this status, classified by kind.
+// Node additions and removals are reported for each node, in the order of the committee.
+//
+// Changes are derived from ChangedFields, but only changes relevant to the users of the key
+// manager are reported.
+func (s *Status) Diff(old *Status) []StatusChange {
+	var changes []StatusChange
+	var generationAdvanced bool
+	for _, field := range s.ChangedFields(old) {
+		switch field {
+		case "is_initialized":
+			if s.IsInitialized {
+				changes = append(changes, StatusChange{Kind: StatusChangeInitialized})
+			}
+		case "generation", "checksum":
+			if generationAdvanced {
+				continue
+			}
+			if s.Generation > old.Generation || len(s.Checksum) > 0 && len(old.Checksum) == 0 {
+				generationAdvanced = true
+				changes = append(changes, StatusChange{Kind: StatusChangeGenerationAdvanced})
+			}
+		case "nodes":
+			for _, id := range s.Nodes {
+				if !slices.ContainsFunc(old.Nodes, id.Equal) {
+					id := id
+					changes = append(changes, StatusChange{Kind: StatusChangeNodeAdded, NodeID: &id})
+				}
+			}
+			for _, id := range old.Nodes {
+				if !slices.ContainsFunc(s.Nodes, id.Equal) {
+					id := id
+					changes = append(changes, StatusChange{Kind: StatusChangeNodeRemoved, NodeID: &id})
+				}
+			}
+		case "policy":
+			changes = append(changes, StatusChange{Kind: StatusChangePolicyChanged})
+		case "rsk":
+			changes = append(changes, StatusChange{Kind: StatusChangeRSKChanged})
+		case "compromised_generations":
+			changes = append(changes, StatusChange{Kind: StatusChangeSecretsCompromised})
+		}
+	}
+	return changes
+}
+
+// Reasons why a node was not admitted to the key manager committee.
+const (
+	AdmissionReasonExpired          = "expired"
+	AdmissionReasonInsecureDisabled = "insecure_disabled"
+	AdmissionReasonTEEMismatch      = "tee_hardware_mismatch"
+	AdmissionReasonTEEConflict      = "tee_hardware_conflict"
+	AdmissionReasonMissingREK       = "missing_rek"
+	AdmissionReasonInvalidExtraInfo = "invalid_extra_info"
+	AdmissionReasonPolicyMismatch   = "policy_mismatch"
+	AdmissionReasonSecurityMismatch = "security_status_mismatch"
+	AdmissionReasonChecksumMismatch = "checksum_mismatch"
+	AdmissionReasonRSKMismatch      = "rsk_mismatch"
+	AdmissionReasonVersionRejected  = "version_rejected"
+	AdmissionReasonShadow           = "shadow"
+	AdmissionReasonNotReplicated    = "secret_not_replicated"
+	AdmissionReasonCommitteeFrozen  = "committee_frozen"
+	AdmissionReasonChangeDeferred   = "committee_change_deferred"
+	AdmissionReasonEntityNotAllowed = "entity_not_allowed"
+	AdmissionReasonStaleVersion     = "stale_version"
+	AdmissionReasonVersionTooOld    = "version_too_old"
+
+	// AdmissionReasonNotCandidate is only used in admission previews for nodes that are
+	// not registered as key manager nodes running the key manager runtime.
+	AdmissionReasonNotCandidate = "not_candidate"
+)
+
+// MaxAdmissionPreviewNodes is the maximum number of nodes in an admission preview query.
+const MaxAdmissionPreviewNodes = 128
+
+// MaxAdmissionRecordsLimit is the upper bound on the maximum number of committee admission
+// records kept in state for each key manager.
+const MaxAdmissionRecordsLimit = 1024
+
+// AdmissionRecord is a record of a key manager committee admission decision.
+type AdmissionRecord struct {
+	// Epoch is the epoch in which the decision was made.
+	Epoch beacon.EpochTime `json:"epoch"`
+
+	// NodeID is the ID of the node.
+	NodeID signature.PublicKey `json:"node_id"`
+
+	// Admitted is true iff the node was admitted to the committee.
+	Admitted bool `json:"admitted,omitempty"`
+
+	// Reason is the reason why the node was not admitted to the committee.
+	Reason string `json:"reason,omitempty"`
+}
+
+// RuntimeEncryptionKey is a runtime encryption key of a key manager committee member.
+type RuntimeEncryptionKey struct {
+	// NodeID is the ID of the node the key belongs to.
+	NodeID signature.PublicKey `json:"node_id"`
+
+	// REK is the runtime encryption key of the node.
+	REK x25519.PublicKey `json:"rek"`
+}
+
+// StatusProjection is a projection of the key manager status for the next epoch, computed
+// from the current node registrations.
+//
+// The projection is speculative, it is never written to state and the actual status may
+// differ if registrations change before the epoch transition.
+type StatusProjection struct {
+	// Epoch is the epoch for which the status was projected.
+	Epoch beacon.EpochTime `json:"epoch"`
+
+	// Status is the projected key manager status.
+	Status *Status `json:"status"`
+
+	// RotationAccepted is true iff the pending proposal for the next master secret would
+	// be accepted.
+	RotationAccepted bool `json:"rotation_accepted,omitempty"`
+}
+
+// HealthSummary is an aggregate summary of the statuses of all key managers.
+type HealthSummary struct {
+	// Runtimes is the number of key manager runtimes with a status.
+	Runtimes uint64 `json:"runtimes"`
+
+	// Initialized is the number of initialized key managers.
+	Initialized uint64 `json:"initialized"`
+
+	// Secure is the number of secure key managers.
+	Secure uint64 `json:"secure"`
+
+	// PendingRotations is the number of key managers with a proposal for the next master
+	// secret that has not yet been replicated by the committee.
+	PendingRotations uint64 `json:"pending_rotations"`
+
+	// MinCommitteeSize is the smallest number of committee nodes of any key manager, or zero
+	// if there are no key managers.
+	MinCommitteeSize uint64 `json:"min_committee_size"`
+}
+
+// ReplicationFailures are the committee members that failed to replicate the most recently
+// accepted master secret rotation and were therefore dropped from the committee.
+type ReplicationFailures struct {
+	// ID is the key manager runtime ID.
+	ID common.Namespace `json:"id"`
+
+	// Generation is the generation of the accepted master secret.
+	Generation uint64 `json:"generation"`
+
+	// Epoch is the epoch in which the rotation was accepted.
+	Epoch beacon.EpochTime `json:"epoch"`
+
+	// Nodes are the IDs of the dropped committee members.
+	Nodes []signature.PublicKey `json:"nodes,omitempty"`
+}
+
+// EpochTransition is the estimated timing of the next epoch transition, at which key manager
+// statuses are recomputed.
+type EpochTransition struct {
+	// Epoch is the current epoch.
+	Epoch beacon.EpochTime `json:"epoch"`
+
+	// Height is the height at which the current epoch started.
+	Height int64 `json:"height"`
+
+	// NextHeight is the height at which the next epoch transition is scheduled, or zero
+	// if no transition is scheduled yet.
+	NextHeight int64 `json:"next_height,omitempty"`
+
+	// BlocksRemaining is the number of blocks until the next epoch transition, or zero
+	// if no transition is scheduled yet.
+	BlocksRemaining int64 `json:"blocks_remaining,omitempty"`
+}
+
+// AdmissionPreviewQuery is a query for the committee admission decisions of the given
+// key manager nodes in the next epoch.
+type AdmissionPreviewQuery struct {
+	// Height is the query height.
+	Height int64 `json:"height"`
+
+	// ID is the key manager runtime ID.
+	ID common.Namespace `json:"id"`
+
+	// NodeIDs are the IDs of the nodes, at most MaxAdmissionPreviewNodes.
+	NodeIDs []signature.PublicKey `json:"node_ids"`
+}
+
+// NodePolicyQuery is a query for the policy status of a key manager node.
+type NodePolicyQuery struct {
+	// Height is the query height.
+	Height int64 `json:"height"`
+
+	// ID is the key manager runtime ID.
+	ID common.Namespace `json:"id"`
+
+	// NodeID is the ID of the key manager node.
+	NodeID signature.PublicKey `json:"node_id"`
+}
+
+// NodePolicyStatus is the status of the key manager policy reported by a node.
+type NodePolicyStatus struct {
+	// PolicyChecksum is the checksum of the active key manager policy.
+	PolicyChecksum []byte `json:"policy_checksum"`
+
+	// IsCurrent is true iff all versions of the key manager runtime run by the node
+	// reported the checksum of the active policy.
+	IsCurrent bool `json:"is_current"`
+
+	// StaleVersions are the versions of the key manager runtime run by the node that
+	// reported a different policy checksum or whose ExtraInfo couldn't be verified.
+	StaleVersions []version.Version `json:"stale_versions,omitempty"`
+}
+
+// VerifyProposalEpoch verifies if a master secret proposal can be published in the given epoch,
+// given the epoch of the last published proposal.
+func (s *Status) VerifyProposalEpoch(lastEpoch, epoch beacon.EpochTime) error {
+	// By default, there is no cooldown unless specified in the policy.
+	var cooldown beacon.EpochTime
+	if s.Policy != nil {
+		cooldown = s.Policy.Policy.MasterSecretProposalCooldown
+	}
+
+	// Reject if the cooldown period has not expired, taking care not to overflow for large
+	// cooldown periods.
+	if epoch < lastEpoch || epoch-lastEpoch < cooldown {
+		return fmt.Errorf("master secret proposal cooldown has not yet expired")
+	}
+
+	return nil
+}
+
 // Backend is a key manager management implementation.
 type Backend interface {
 	// GetStatus returns a key manager status by key manager ID.
 	GetStatus(context.Context, *registry.NamespaceQuery) (*Status, error)
 
+	// GetInitializedStatus returns a key manager status by key manager ID, failing with
+	// ErrKeyManagerNotInitialized if the key manager has not been initialized yet.
+	GetInitializedStatus(context.Context, *registry.NamespaceQuery) (*Status, error)
+
 	// GetStatuses returns all currently tracked key manager statuses.
 	GetStatuses(context.Context, int64) ([]*Status, error)
 
+	// GetHealthSummary returns an aggregate summary of the statuses of all key managers.
+	GetHealthSummary(context.Context, int64) (*HealthSummary, error)
+
 	// WatchStatuses returns a channel that produces a stream of messages
 	// containing the key manager statuses as it changes over time.
 	//
@@ -212,6 +681,41 @@ type Backend interface {
 
 	// WatchEphemeralSecrets returns a channel that produces a stream of ephemeral secrets.
 	WatchEphemeralSecrets() (<-chan *SignedEncryptedEphemeralSecret, *pubsub.Subscription)
+
+	// GetRuntimeEncryptionKeys returns the runtime encryption keys of the key manager committee
+	// to which secrets must be encrypted in order to be accepted, sorted by node ID.
+	GetRuntimeEncryptionKeys(context.Context, *registry.NamespaceQuery) ([]*RuntimeEncryptionKey, error)
+
+	// GetStatusProjection returns a projection of the key manager status for the next epoch,
+	// computed from the current node registrations without modifying the state.
+	GetStatusProjection(context.Context, *registry.NamespaceQuery) (*StatusProjection, error)
+
+	// GetPolicy returns the active key manager policy.
+	GetPolicy(context.Context, *registry.NamespaceQuery) (*SignedPolicySGX, error)
+
+	// GetAdmissionPreview returns the committee admission decisions of the given nodes
+	// for the next epoch, computed from the current node registrations without modifying
+	// the state. The decisions are ordered as the queried nodes.
+	GetAdmissionPreview(context.Context, *AdmissionPreviewQuery) ([]*AdmissionRecord, error)
+
+	// GetNodePolicyStatus returns whether the policy reported by the key manager node
+	// in its ExtraInfo matches the active key manager policy.
+	GetNodePolicyStatus(context.Context, *NodePolicyQuery) (*NodePolicyStatus, error)
+
+	// GetAdmissionRecords returns the most recent key manager committee admission records,
+	// oldest first.
+	GetAdmissionRecords(context.Context, *registry.NamespaceQuery) ([]*AdmissionRecord, error)
+
+	// GetReplicationFailures returns the committee members that failed to replicate
+	// the most recently accepted master secret rotation.
+	GetReplicationFailures(context.Context, *registry.NamespaceQuery) (*ReplicationFailures, error)
+
+	// ConsensusParameters returns the key manager secrets consensus parameters.
+	ConsensusParameters(ctx context.Context, height int64) (*ConsensusParameters, error)
+
+	// GetEpochTransition returns the current epoch and the estimated number of blocks
+	// until the next epoch transition.
+	GetEpochTransition(ctx context.Context, height int64) (*EpochTransition, error)
 }
 
 // NewUpdatePolicyTx creates a new policy update transaction.
@@ -229,6 +733,11 @@ func NewPublishEphemeralSecretTx(nonce uint64, fee *transaction.Fee, sigSec *Sig
 	return transaction.NewTransaction(nonce, fee, MethodPublishEphemeralSecret, sigSec)
 }
 
+// NewRefreshStatusTx creates a new refresh status transaction.
+func NewRefreshStatusTx(nonce uint64, fee *transaction.Fee, sr *StatusRefresh) *transaction.Transaction {
+	return transaction.NewTransaction(nonce, fee, MethodRefreshStatus, sr)
+}
+
 // InitRequest is the initialization RPC request, sent to the key manager
 // enclave.
 type InitRequest struct {
@@ -349,12 +858,104 @@ type Genesis struct {
 // ConsensusParameters are the key manager consensus parameters.
 type ConsensusParameters struct {
 	GasCosts transaction.Costs `json:"gas_costs,omitempty"`
+
+	// NodeExpirationGracePeriod is the number of epochs during which an expired node that was
+	// a member of the key manager committee remains in the committee while it re-registers.
+	// Zero drops expired nodes immediately.
+	NodeExpirationGracePeriod beacon.EpochTime `json:"node_expiration_grace_period,omitempty"`
+
+	// MaxAdmissionRecords is the maximum number of committee admission records kept in state
+	// for each key manager. Only rejections and admissions of nodes that were not yet members
+	// of the committee are recorded. Zero disables the admission records.
+	MaxAdmissionRecords uint64 `json:"max_admission_records,omitempty"`
+
+	// DisableInsecureKeyManagers rejects committee admission and secret publication for key
+	// managers that don't run in a TEE.
+	DisableInsecureKeyManagers bool `json:"disable_insecure_key_managers,omitempty"`
+
+	// PolicyChecksumAlgorithm is the identifier of the algorithm used to compute the checksum
+	// of the key manager policy that nodes need to report to join the committee. Only
+	// algorithms supported by the key manager enclaves are accepted. If empty, SHA3-256 is used.
+	PolicyChecksumAlgorithm string `json:"policy_checksum_algorithm,omitempty"`
+
+	// EnforceActiveDeployment rejects committee admission for nodes running a version of
+	// the key manager runtime other than the one of the currently active deployment.
+	EnforceActiveDeployment bool `json:"enforce_active_deployment,omitempty"`
+
+	// MaxCommitteeAdditions is the maximum number of nodes that can join the key manager
+	// committee in a single epoch transition, excess nodes join in subsequent epochs.
+	// Zero means unlimited.
+	MaxCommitteeAdditions uint64 `json:"max_committee_additions,omitempty"`
+
+	// MaxCommitteeRemovals is the maximum number of nodes that can leave the key manager
+	// committee in a single epoch transition, excess nodes leave in subsequent epochs.
+	// Zero means unlimited.
+	MaxCommitteeRemovals uint64 `json:"max_committee_removals,omitempty"`
+
+	// MaxRetainedGenerations is the soft limit on the number of master secret generations
+	// retained by a key manager. Rotations past the limit are not rejected, as key managers
+	// need all generations, but emit a GenerationLimitExceededEvent.
+	// Zero means unlimited.
+	MaxRetainedGenerations uint64 `json:"max_retained_generations,omitempty"`
+
+	// MaxPolicySize is the maximum size in bytes of a CBOR-serialized signed policy that can
+	// be submitted in a policy update. Zero means unlimited.
+	MaxPolicySize uint64 `json:"max_policy_size,omitempty"`
+
+	// MaxActiveCommitteeSize is the maximum number of nodes in the active key manager
+	// committee, excess nodes that qualify for the committee are kept on standby.
+	// Zero means unlimited.
+	MaxActiveCommitteeSize uint64 `json:"max_active_committee_size,omitempty"`
+
+	// MinSecureNodePercent is the minimum percentage of the registered key manager nodes
+	// that need to pass secure verification, below which the key manager status is marked
+	// as degraded. Zero disables the check.
+	MinSecureNodePercent uint8 `json:"min_secure_node_percent,omitempty"`
 }
 
 // ConsensusParameterChanges are allowed key manager consensus parameter changes.
 type ConsensusParameterChanges struct {
 	// GasCosts are the new gas costs.
 	GasCosts transaction.Costs `json:"gas_costs,omitempty"`
+
+	// NodeExpirationGracePeriod is the new node expiration grace period.
+	NodeExpirationGracePeriod *beacon.EpochTime `json:"node_expiration_grace_period,omitempty"`
+
+	// MaxAdmissionRecords is the new maximum number of committee admission records.
+	MaxAdmissionRecords *uint64 `json:"max_admission_records,omitempty"`
+
+	// DisableInsecureKeyManagers is the new insecure key managers flag.
+	DisableInsecureKeyManagers *bool `json:"disable_insecure_key_managers,omitempty"`
+
+	// PolicyChecksumAlgorithm is the new policy checksum algorithm.
+	PolicyChecksumAlgorithm *string `json:"policy_checksum_algorithm,omitempty"`
+
+	// EnforceActiveDeployment is the new active deployment enforcement flag.
+	EnforceActiveDeployment *bool `json:"enforce_active_deployment,omitempty"`
+
+	// MaxCommitteeAdditions is the new maximum number of committee additions per epoch.
+	MaxCommitteeAdditions *uint64 `json:"max_committee_additions,omitempty"`
+
+	// MaxCommitteeRemovals is the new maximum number of committee removals per epoch.
+	MaxCommitteeRemovals *uint64 `json:"max_committee_removals,omitempty"`
+
+	// MaxRetainedGenerations is the new soft limit on the number of retained generations.
+	MaxRetainedGenerations *uint64 `json:"max_retained_generations,omitempty"`
+
+	// MaxPolicySize is the new maximum size of a signed policy.
+	MaxPolicySize *uint64 `json:"max_policy_size,omitempty"`
+
+	// MaxActiveCommitteeSize is the new maximum number of nodes in the active committee.
+	MaxActiveCommitteeSize *uint64 `json:"max_active_committee_size,omitempty"`
+
+	// MinSecureNodePercent is the new minimum percentage of secure nodes.
+	MinSecureNodePercent *uint8 `json:"min_secure_node_percent,omitempty"`
+
+	// CompromisedSecrets are the master secret generations to mark as compromised.
+	//
+	// Unlike the other changes, these are not consensus parameters, but are recorded in
+	// the statuses of the key managers.
+	CompromisedSecrets []CompromisedSecrets `json:"compromised_secrets,omitempty"`
 }
 
 // Apply applies changes to the given consensus parameters.
@@ -362,12 +963,49 @@ func (c *ConsensusParameterChanges) Apply(params *ConsensusParameters) error {
 	if c.GasCosts != nil {
 		params.GasCosts = c.GasCosts
 	}
+	if c.NodeExpirationGracePeriod != nil {
+		params.NodeExpirationGracePeriod = *c.NodeExpirationGracePeriod
+	}
+	if c.MaxAdmissionRecords != nil {
+		params.MaxAdmissionRecords = *c.MaxAdmissionRecords
+	}
+	if c.DisableInsecureKeyManagers != nil {
+		params.DisableInsecureKeyManagers = *c.DisableInsecureKeyManagers
+	}
+	if c.PolicyChecksumAlgorithm != nil {
+		params.PolicyChecksumAlgorithm = *c.PolicyChecksumAlgorithm
+	}
+	if c.EnforceActiveDeployment != nil {
+		params.EnforceActiveDeployment = *c.EnforceActiveDeployment
+	}
+	if c.MaxCommitteeAdditions != nil {
+		params.MaxCommitteeAdditions = *c.MaxCommitteeAdditions
+	}
+	if c.MaxCommitteeRemovals != nil {
+		params.MaxCommitteeRemovals = *c.MaxCommitteeRemovals
+	}
+	if c.MaxRetainedGenerations != nil {
+		params.MaxRetainedGenerations = *c.MaxRetainedGenerations
+	}
+	if c.MaxPolicySize != nil {
+		params.MaxPolicySize = *c.MaxPolicySize
+	}
+	if c.MaxActiveCommitteeSize != nil {
+		params.MaxActiveCommitteeSize = *c.MaxActiveCommitteeSize
+	}
+	if c.MinSecureNodePercent != nil {
+		params.MinSecureNodePercent = *c.MinSecureNodePercent
+	}
 	return nil
 }
 
 // StatusUpdateEvent is the keymanager status update event.
 type StatusUpdateEvent struct {
 	Statuses []*Status
+
+	// Changes are the classified changes of each updated status, keyed by the key manager
+	// runtime ID.
+	Changes map[common.Namespace][]StatusChange `json:",omitempty"`
 }
 
 // EventKind returns a string representation of this event's kind.
@@ -375,6 +1013,60 @@ func (ev *StatusUpdateEvent) EventKind() string {
 	return "status"
 }
 
+// InitializedEvent is the key manager initialized event, emitted once when the key manager
+// status first becomes initialized.
+type InitializedEvent struct {
+	// ID is the runtime ID of the key manager.
+	ID common.Namespace `json:"id"`
+
+	// IsSecure is true iff the key manager is secure.
+	IsSecure bool `json:"is_secure"`
+}
+
+// EventKind returns a string representation of this event's kind.
+func (ev *InitializedEvent) EventKind() string {
+	return "initialized"
+}
+
+// ReplicationQuorumReachedEvent is the key manager replication quorum reached event, emitted
+// on the epoch transition in which enough committee nodes have replicated the proposal for
+// the next master secret and the rotation is accepted.
+type ReplicationQuorumReachedEvent struct {
+	// ID is the runtime ID of the key manager.
+	ID common.Namespace `json:"id"`
+
+	// Generation is the generation of the accepted master secret.
+	Generation uint64 `json:"generation"`
+
+	// ReplicatedNodes is the number of committee nodes that have replicated the secret,
+	// including the standby nodes.
+	ReplicatedNodes uint64 `json:"replicated_nodes"`
+}
+
+// EventKind returns a string representation of this event's kind.
+func (ev *ReplicationQuorumReachedEvent) EventKind() string {
+	return "replication_quorum_reached"
+}
+
+// GenerationLimitExceededEvent is the key manager generation limit exceeded event, emitted
+// on each accepted rotation after which the key manager retains more generations of the master
+// secret than the soft limit set in the consensus parameters.
+type GenerationLimitExceededEvent struct {
+	// ID is the runtime ID of the key manager.
+	ID common.Namespace `json:"id"`
+
+	// Generations is the number of retained generations of the master secret.
+	Generations uint64 `json:"generations"`
+
+	// Limit is the soft limit on the number of retained generations.
+	Limit uint64 `json:"limit"`
+}
+
+// EventKind returns a string representation of this event's kind.
+func (ev *GenerationLimitExceededEvent) EventKind() string {
+	return "generation_limit_exceeded"
+}
+
 // MasterSecretPublishedEvent is the key manager master secret published event.
 type MasterSecretPublishedEvent struct {
 	Secret *SignedEncryptedMasterSecret
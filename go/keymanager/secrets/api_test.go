@@ -4,7 +4,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
 
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
 )
 
@@ -44,4 +47,239 @@ func TestStatus(t *testing.T) {
 	// Key manager with ten master secret generations.
 	s.Generation = 9
 	require.Equal(uint64(10), s.NextGeneration())
+
+	// Proposals can be published every epoch by default.
+	require.NoError(s.VerifyProposalEpoch(10, 11))
+
+	// Proposals can be published only after the cooldown expires.
+	s.Policy = &SignedPolicySGX{
+		Policy: PolicySGX{
+			MasterSecretProposalCooldown: 3,
+		},
+	}
+	require.Error(s.VerifyProposalEpoch(10, 11))
+	require.Error(s.VerifyProposalEpoch(10, 12))
+	require.NoError(s.VerifyProposalEpoch(10, 13))
+	require.NoError(s.VerifyProposalEpoch(10, 20))
+
+	// Proposals cannot precede the last proposal.
+	require.Error(s.VerifyProposalEpoch(10, 9))
+
+	// Large cooldown periods should not overflow.
+	s.Policy.Policy.MasterSecretProposalCooldown = beacon.EpochMax
+	require.Error(s.VerifyProposalEpoch(10, 11))
+	require.Error(s.VerifyProposalEpoch(10, beacon.EpochMax))
+}
+
+func TestStatusIsCommitteeFrozen(t *testing.T) {
+	require := require.New(t)
+
+	s := Status{
+		IsInitialized: true,
+		RotationEpoch: 10,
+		Nodes:         []signature.PublicKey{signature.NewPublicKey("0000000000000000000000000000000000000000000000000000000000000001")},
+		Policy:        &SignedPolicySGX{},
+	}
+
+	// Committees are not frozen by default.
+	require.False(s.IsCommitteeFrozen(10))
+
+	// Committees are frozen for the freeze period after a rotation.
+	s.Policy.Policy.CommitteeFreezePeriod = 3
+	require.False(s.IsCommitteeFrozen(9))
+	require.True(s.IsCommitteeFrozen(10))
+	require.True(s.IsCommitteeFrozen(12))
+	require.False(s.IsCommitteeFrozen(13))
+
+	// Empty committees are never frozen.
+	s.Nodes = nil
+	require.False(s.IsCommitteeFrozen(10))
+}
+
+func TestStatusNextRotationEpoch(t *testing.T) {
+	require := require.New(t)
+
+	// The first master secret can be generated in any epoch.
+	var s Status
+	require.Equal(beacon.EpochTime(0), s.NextRotationEpoch())
+	require.NoError(s.VerifyRotationEpoch(0))
+
+	// Rotation is disabled by default.
+	s.Checksum = []byte{1, 2, 3}
+	s.RotationEpoch = 10
+	require.Equal(beacon.EpochInvalid, s.NextRotationEpoch())
+	require.EqualError(s.VerifyRotationEpoch(100), "master secret rotation disabled")
+
+	s.Policy = &SignedPolicySGX{}
+	require.Equal(beacon.EpochInvalid, s.NextRotationEpoch())
+	require.EqualError(s.VerifyRotationEpoch(100), "master secret rotation disabled")
+
+	// Rotation is allowed once the interval expires.
+	s.Policy.Policy.MasterSecretRotationInterval = 5
+	require.Equal(beacon.EpochTime(15), s.NextRotationEpoch())
+	require.EqualError(s.VerifyRotationEpoch(14), "master secret rotation interval has not yet expired")
+	require.NoError(s.VerifyRotationEpoch(15))
+	require.NoError(s.VerifyRotationEpoch(16))
+
+	// Huge intervals should not overflow.
+	s.Policy.Policy.MasterSecretRotationInterval = beacon.EpochInvalid - 5
+	require.Equal(beacon.EpochInvalid, s.NextRotationEpoch())
+}
+
+func TestStatusChangedFields(t *testing.T) {
+	require := require.New(t)
+
+	signer1 := memorySigner.NewTestSigner("signer1")
+	signer2 := memorySigner.NewTestSigner("signer2")
+	rsk1, rsk2 := signer1.Public(), signer2.Public()
+
+	old := &Status{
+		IsInitialized: true,
+		Checksum:      []byte{1, 2, 3},
+		Nodes:         []signature.PublicKey{signer1.Public()},
+		RSK:           &rsk1,
+	}
+
+	// Identical statuses.
+	s := *old
+	require.Empty(s.ChangedFields(old))
+
+	// A node joined.
+	s.Nodes = []signature.PublicKey{signer1.Public(), signer2.Public()}
+	require.Equal([]string{"nodes"}, s.ChangedFields(old))
+
+	// Master secret rotated.
+	s = *old
+	s.Generation = 1
+	s.RotationEpoch = 10
+	s.Checksum = []byte{4, 5, 6}
+	require.Equal([]string{"generation", "rotation_epoch", "checksum"}, s.ChangedFields(old))
+
+	// RSK changed or removed.
+	s = *old
+	s.RSK = &rsk2
+	require.Equal([]string{"rsk"}, s.ChangedFields(old))
+	s.RSK = nil
+	require.Equal([]string{"rsk"}, s.ChangedFields(old))
+
+	// Policy changed.
+	s = *old
+	s.Policy = &SignedPolicySGX{Policy: PolicySGX{Serial: 1}}
+	require.Equal([]string{"policy"}, s.ChangedFields(old))
+
+	// Last seen epochs advanced.
+	s = *old
+	s.LastSeenEpochs = map[signature.PublicKey]beacon.EpochTime{signer1.Public(): 10}
+	require.Empty(s.ChangedFields(old), "last seen epochs should not be reported as changed")
+}
+
+func TestStatusDiff(t *testing.T) {
+	require := require.New(t)
+
+	signer1 := memorySigner.NewTestSigner("signer1")
+	signer2 := memorySigner.NewTestSigner("signer2")
+	signer3 := memorySigner.NewTestSigner("signer3")
+	node1, node2, node3 := signer1.Public(), signer2.Public(), signer3.Public()
+	rsk1, rsk2 := signer1.Public(), signer2.Public()
+
+	old := &Status{
+		IsInitialized: true,
+		Checksum:      []byte{1, 2, 3},
+		Nodes:         []signature.PublicKey{node1, node2},
+		RSK:           &rsk1,
+	}
+
+	// Identical statuses.
+	s := *old
+	require.Empty(s.Diff(old))
+
+	// Key manager initialized.
+	uninitialized := &Status{}
+	s = Status{IsInitialized: true}
+	require.Equal([]StatusChange{{Kind: StatusChangeInitialized}}, s.Diff(uninitialized))
+
+	// First master secret generated.
+	s.Checksum = []byte{1, 2, 3}
+	require.Equal([]StatusChange{
+		{Kind: StatusChangeInitialized},
+		{Kind: StatusChangeGenerationAdvanced},
+	}, s.Diff(uninitialized))
+
+	// Master secret rotated.
+	s = *old
+	s.Generation = 1
+	s.Checksum = []byte{4, 5, 6}
+	require.Equal([]StatusChange{{Kind: StatusChangeGenerationAdvanced}}, s.Diff(old))
+
+	// RSK changed or removed.
+	s = *old
+	s.RSK = &rsk2
+	require.Equal([]StatusChange{{Kind: StatusChangeRSKChanged}}, s.Diff(old))
+	s.RSK = nil
+	require.Equal([]StatusChange{{Kind: StatusChangeRSKChanged}}, s.Diff(old))
+
+	// Policy changed.
+	s = *old
+	s.Policy = &SignedPolicySGX{Policy: PolicySGX{Serial: 1}}
+	require.Equal([]StatusChange{{Kind: StatusChangePolicyChanged}}, s.Diff(old))
+
+	// Generations marked as compromised.
+	s = *old
+	s.CompromisedGenerations = []uint64{0}
+	require.Equal([]StatusChange{{Kind: StatusChangeSecretsCompromised}}, s.Diff(old))
+
+	// Nodes added and removed.
+	s = *old
+	s.Nodes = []signature.PublicKey{node2, node3}
+	require.Equal([]StatusChange{
+		{Kind: StatusChangeNodeAdded, NodeID: &node3},
+		{Kind: StatusChangeNodeRemoved, NodeID: &node1},
+	}, s.Diff(old))
+
+	// Fields not relevant to the users of the key manager.
+	s = *old
+	s.ReportedNodes = 3
+	s.LastSeenEpochs = map[signature.PublicKey]beacon.EpochTime{node1: 10}
+	require.Empty(s.Diff(old))
+	require.NotEmpty(s.ChangedFields(old))
+}
+
+func TestConsensusParametersPolicyChecksumAlgorithm(t *testing.T) {
+	require := require.New(t)
+
+	for _, algorithm := range []string{"", PolicyChecksumAlgorithmSHA3} {
+		params := ConsensusParameters{PolicyChecksumAlgorithm: algorithm}
+		require.NoError(params.SanityCheck(), "algorithm %q should be supported", algorithm)
+	}
+
+	// Algorithms not supported by the key manager enclaves should be rejected.
+	for _, algorithm := range []string{"md5", "sha512-256"} {
+		params := ConsensusParameters{PolicyChecksumAlgorithm: algorithm}
+		require.EqualError(params.SanityCheck(), "keymanager: unsupported policy checksum algorithm: "+algorithm)
+	}
+
+	// The default algorithm is SHA3-256.
+	fn, err := GetPolicyChecksumFunc("")
+	require.NoError(err, "GetPolicyChecksumFunc")
+	require.Equal(sha3.Sum256([]byte("policy")), fn([]byte("policy")))
+
+	// Changes to the algorithm are not empty.
+	var params ConsensusParameters
+	algorithm := PolicyChecksumAlgorithmSHA3
+	changes := ConsensusParameterChanges{PolicyChecksumAlgorithm: &algorithm}
+	require.NoError(changes.SanityCheck())
+	require.NoError(changes.Apply(&params))
+	require.Equal(PolicyChecksumAlgorithmSHA3, params.PolicyChecksumAlgorithm)
+}
+
+func TestConsensusParametersMaxAdmissionRecords(t *testing.T) {
+	require := require.New(t)
+
+	for _, n := range []uint64{0, 100, MaxAdmissionRecordsLimit} {
+		params := ConsensusParameters{MaxAdmissionRecords: n}
+		require.NoError(params.SanityCheck(), "%d admission records should be allowed", n)
+	}
+
+	params := ConsensusParameters{MaxAdmissionRecords: MaxAdmissionRecordsLimit + 1}
+	require.EqualError(params.SanityCheck(), "maximum number of admission records 1025 exceeds 1024")
 }
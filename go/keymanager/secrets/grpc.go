@@ -18,10 +18,32 @@ var (
 	methodGetStatus = serviceName.NewMethod("GetStatus", registry.NamespaceQuery{})
 	// methodGetStatuses is the GetStatuses method.
 	methodGetStatuses = serviceName.NewMethod("GetStatuses", int64(0))
+	// methodGetHealthSummary is the GetHealthSummary method.
+	methodGetHealthSummary = serviceName.NewMethod("GetHealthSummary", int64(0))
 	// methodGetMasterSecret is the GetMasterSecret method.
 	methodGetMasterSecret = serviceName.NewMethod("GetMasterSecret", registry.NamespaceQuery{})
 	// methodGetEphemeralSecret is the GetEphemeralSecret method.
 	methodGetEphemeralSecret = serviceName.NewMethod("GetEphemeralSecret", registry.NamespaceQuery{})
+	// methodGetRuntimeEncryptionKeys is the GetRuntimeEncryptionKeys method.
+	methodGetRuntimeEncryptionKeys = serviceName.NewMethod("GetRuntimeEncryptionKeys", registry.NamespaceQuery{})
+	// methodGetStatusProjection is the GetStatusProjection method.
+	methodGetStatusProjection = serviceName.NewMethod("GetStatusProjection", registry.NamespaceQuery{})
+	// methodGetPolicy is the GetPolicy method.
+	methodGetPolicy = serviceName.NewMethod("GetPolicy", registry.NamespaceQuery{})
+	// methodGetAdmissionPreview is the GetAdmissionPreview method.
+	methodGetAdmissionPreview = serviceName.NewMethod("GetAdmissionPreview", AdmissionPreviewQuery{})
+	// methodGetNodePolicyStatus is the GetNodePolicyStatus method.
+	methodGetNodePolicyStatus = serviceName.NewMethod("GetNodePolicyStatus", NodePolicyQuery{})
+	// methodGetInitializedStatus is the GetInitializedStatus method.
+	methodGetInitializedStatus = serviceName.NewMethod("GetInitializedStatus", registry.NamespaceQuery{})
+	// methodGetAdmissionRecords is the GetAdmissionRecords method.
+	methodGetAdmissionRecords = serviceName.NewMethod("GetAdmissionRecords", registry.NamespaceQuery{})
+	// methodGetReplicationFailures is the GetReplicationFailures method.
+	methodGetReplicationFailures = serviceName.NewMethod("GetReplicationFailures", registry.NamespaceQuery{})
+	// methodConsensusParameters is the ConsensusParameters method.
+	methodConsensusParameters = serviceName.NewMethod("ConsensusParameters", int64(0))
+	// methodGetEpochTransition is the GetEpochTransition method.
+	methodGetEpochTransition = serviceName.NewMethod("GetEpochTransition", int64(0))
 
 	// methodWatchStatuses is the WatchStatuses method.
 	methodWatchStatuses = serviceName.NewMethod("WatchStatuses", nil)
@@ -43,6 +65,10 @@ var (
 				MethodName: methodGetStatuses.ShortName(),
 				Handler:    handlerGetStatuses,
 			},
+			{
+				MethodName: methodGetHealthSummary.ShortName(),
+				Handler:    handlerGetHealthSummary,
+			},
 			{
 				MethodName: methodGetMasterSecret.ShortName(),
 				Handler:    handlerGetMasterSecret,
@@ -51,6 +77,46 @@ var (
 				MethodName: methodGetEphemeralSecret.ShortName(),
 				Handler:    handlerGetEphemeralSecret,
 			},
+			{
+				MethodName: methodGetRuntimeEncryptionKeys.ShortName(),
+				Handler:    handlerGetRuntimeEncryptionKeys,
+			},
+			{
+				MethodName: methodGetStatusProjection.ShortName(),
+				Handler:    handlerGetStatusProjection,
+			},
+			{
+				MethodName: methodGetPolicy.ShortName(),
+				Handler:    handlerGetPolicy,
+			},
+			{
+				MethodName: methodGetAdmissionPreview.ShortName(),
+				Handler:    handlerGetAdmissionPreview,
+			},
+			{
+				MethodName: methodGetNodePolicyStatus.ShortName(),
+				Handler:    handlerGetNodePolicyStatus,
+			},
+			{
+				MethodName: methodGetInitializedStatus.ShortName(),
+				Handler:    handlerGetInitializedStatus,
+			},
+			{
+				MethodName: methodGetAdmissionRecords.ShortName(),
+				Handler:    handlerGetAdmissionRecords,
+			},
+			{
+				MethodName: methodGetReplicationFailures.ShortName(),
+				Handler:    handlerGetReplicationFailures,
+			},
+			{
+				MethodName: methodConsensusParameters.ShortName(),
+				Handler:    handlerConsensusParameters,
+			},
+			{
+				MethodName: methodGetEpochTransition.ShortName(),
+				Handler:    handlerGetEpochTransition,
+			},
 		},
 		Streams: []grpc.StreamDesc{
 			{
@@ -118,6 +184,29 @@ func handlerGetStatuses(
 	return interceptor(ctx, height, info, handler)
 }
 
+func handlerGetHealthSummary(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var height int64
+	if err := dec(&height); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetHealthSummary(ctx, height)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetHealthSummary.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetHealthSummary(ctx, req.(int64))
+	}
+	return interceptor(ctx, height, info, handler)
+}
+
 func handlerGetMasterSecret(
 	srv interface{},
 	ctx context.Context,
@@ -164,6 +253,236 @@ func handlerGetEphemeralSecret(
 	return interceptor(ctx, &query, info, handler)
 }
 
+func handlerGetRuntimeEncryptionKeys(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var query registry.NamespaceQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetRuntimeEncryptionKeys(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetRuntimeEncryptionKeys.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetRuntimeEncryptionKeys(ctx, req.(*registry.NamespaceQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
+func handlerGetStatusProjection(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var query registry.NamespaceQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetStatusProjection(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetStatusProjection.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetStatusProjection(ctx, req.(*registry.NamespaceQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
+func handlerGetPolicy(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var query registry.NamespaceQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetPolicy(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetPolicy.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetPolicy(ctx, req.(*registry.NamespaceQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
+func handlerGetAdmissionPreview(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var query AdmissionPreviewQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetAdmissionPreview(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetAdmissionPreview.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetAdmissionPreview(ctx, req.(*AdmissionPreviewQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
+func handlerGetNodePolicyStatus(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var query NodePolicyQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetNodePolicyStatus(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetNodePolicyStatus.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetNodePolicyStatus(ctx, req.(*NodePolicyQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
+func handlerGetInitializedStatus(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var query registry.NamespaceQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetInitializedStatus(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetInitializedStatus.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetInitializedStatus(ctx, req.(*registry.NamespaceQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
+func handlerGetAdmissionRecords(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var query registry.NamespaceQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetAdmissionRecords(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetAdmissionRecords.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetAdmissionRecords(ctx, req.(*registry.NamespaceQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
+func handlerGetReplicationFailures(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var query registry.NamespaceQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetReplicationFailures(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetReplicationFailures.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetReplicationFailures(ctx, req.(*registry.NamespaceQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
+func handlerConsensusParameters(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var height int64
+	if err := dec(&height); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).ConsensusParameters(ctx, height)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodConsensusParameters.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).ConsensusParameters(ctx, req.(int64))
+	}
+	return interceptor(ctx, height, info, handler)
+}
+
+func handlerGetEpochTransition(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var height int64
+	if err := dec(&height); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetEpochTransition(ctx, height)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetEpochTransition.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).GetEpochTransition(ctx, req.(int64))
+	}
+	return interceptor(ctx, height, info, handler)
+}
+
 func handlerWatchStatuses(srv interface{}, stream grpc.ServerStream) error {
 	if err := stream.RecvMsg(nil); err != nil {
 		return err
@@ -265,6 +584,14 @@ func (c *Client) GetStatuses(ctx context.Context, height int64) ([]*Status, erro
 	return resp, nil
 }
 
+func (c *Client) GetHealthSummary(ctx context.Context, height int64) (*HealthSummary, error) {
+	var resp HealthSummary
+	if err := c.conn.Invoke(ctx, methodGetHealthSummary.FullName(), height, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 func (c *Client) GetMasterSecret(ctx context.Context, query *registry.NamespaceQuery) (*SignedEncryptedMasterSecret, error) {
 	var resp *SignedEncryptedMasterSecret
 	if err := c.conn.Invoke(ctx, methodGetMasterSecret.FullName(), query, &resp); err != nil {
@@ -281,6 +608,86 @@ func (c *Client) GetEphemeralSecret(ctx context.Context, query *registry.Namespa
 	return resp, nil
 }
 
+func (c *Client) GetRuntimeEncryptionKeys(ctx context.Context, query *registry.NamespaceQuery) ([]*RuntimeEncryptionKey, error) {
+	var resp []*RuntimeEncryptionKey
+	if err := c.conn.Invoke(ctx, methodGetRuntimeEncryptionKeys.FullName(), query, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) GetStatusProjection(ctx context.Context, query *registry.NamespaceQuery) (*StatusProjection, error) {
+	var resp StatusProjection
+	if err := c.conn.Invoke(ctx, methodGetStatusProjection.FullName(), query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) GetPolicy(ctx context.Context, query *registry.NamespaceQuery) (*SignedPolicySGX, error) {
+	var resp SignedPolicySGX
+	if err := c.conn.Invoke(ctx, methodGetPolicy.FullName(), query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) GetAdmissionPreview(ctx context.Context, query *AdmissionPreviewQuery) ([]*AdmissionRecord, error) {
+	var resp []*AdmissionRecord
+	if err := c.conn.Invoke(ctx, methodGetAdmissionPreview.FullName(), query, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) GetNodePolicyStatus(ctx context.Context, query *NodePolicyQuery) (*NodePolicyStatus, error) {
+	var resp NodePolicyStatus
+	if err := c.conn.Invoke(ctx, methodGetNodePolicyStatus.FullName(), query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) GetInitializedStatus(ctx context.Context, query *registry.NamespaceQuery) (*Status, error) {
+	var resp Status
+	if err := c.conn.Invoke(ctx, methodGetInitializedStatus.FullName(), query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) GetAdmissionRecords(ctx context.Context, query *registry.NamespaceQuery) ([]*AdmissionRecord, error) {
+	var resp []*AdmissionRecord
+	if err := c.conn.Invoke(ctx, methodGetAdmissionRecords.FullName(), query, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) GetReplicationFailures(ctx context.Context, query *registry.NamespaceQuery) (*ReplicationFailures, error) {
+	var resp ReplicationFailures
+	if err := c.conn.Invoke(ctx, methodGetReplicationFailures.FullName(), query, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) ConsensusParameters(ctx context.Context, height int64) (*ConsensusParameters, error) {
+	var resp ConsensusParameters
+	if err := c.conn.Invoke(ctx, methodConsensusParameters.FullName(), height, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) GetEpochTransition(ctx context.Context, height int64) (*EpochTransition, error) {
+	var resp EpochTransition
+	if err := c.conn.Invoke(ctx, methodGetEpochTransition.FullName(), height, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 func (c *Client) WatchStatuses(ctx context.Context) (<-chan *Status, pubsub.ClosableSubscription, error) {
 	ctx, sub := pubsub.NewContextSubscription(ctx)
 
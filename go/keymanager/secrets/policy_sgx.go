@@ -2,17 +2,119 @@ package secrets
 
 import (
 	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/sha3"
 
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	"github.com/oasisprotocol/oasis-core/go/common/sgx"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
 )
 
 // PolicySGXSignatureContext is the context used to sign PolicySGX documents.
 var PolicySGXSignatureContext = signature.NewContext("oasis-core/keymanager: policy")
 
+// PolicySignatureAlgorithmEd25519 is the identifier of the Ed25519 policy signature scheme.
+//
+// This is the default scheme used when a signed policy doesn't specify the algorithm.
+const PolicySignatureAlgorithmEd25519 = "ed25519"
+
+// PolicyChecksumAlgorithmSHA3 is the identifier of the SHA3-256 policy checksum algorithm.
+//
+// This is the default algorithm used when the consensus parameters don't specify one.
+const PolicyChecksumAlgorithmSHA3 = "sha3-256"
+
+// policyChecksumAlgorithms are the supported policy checksum algorithms.
+//
+// Key manager enclaves compute the checksums of the policies they report in their init
+// responses, so an algorithm may only be added once the enclaves support it.
+var policyChecksumAlgorithms = map[string]func([]byte) [ChecksumSize]byte{
+	PolicyChecksumAlgorithmSHA3: sha3.Sum256,
+}
+
+// GetPolicyChecksumFunc returns the policy checksum function for the given algorithm
+// identifier.
+func GetPolicyChecksumFunc(algorithm string) (func([]byte) [ChecksumSize]byte, error) {
+	if algorithm == "" {
+		algorithm = PolicyChecksumAlgorithmSHA3
+	}
+	fn, ok := policyChecksumAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("keymanager: unsupported policy checksum algorithm: %s", algorithm)
+	}
+	return fn, nil
+}
+
+// ComputePolicyHash returns the checksum of the policy that up-to-date key manager nodes
+// report in their init responses, computed with the given checksum function.
+//
+// The checksum of a nil policy is the checksum of an empty input, which nodes without
+// a policy report as an empty checksum.
+func ComputePolicyHash(checksumFn func([]byte) [ChecksumSize]byte, policy *SignedPolicySGX) [ChecksumSize]byte {
+	var rawPolicy []byte
+	if policy != nil {
+		rawPolicy = cbor.Marshal(policy)
+	}
+	return checksumFn(rawPolicy)
+}
+
+// MaxPolicyDescriptionLength is the maximum length of a policy update description in bytes.
+const MaxPolicyDescriptionLength = 256
+
+// PolicySignatureVerifier is a key manager policy signature verification backend.
+type PolicySignatureVerifier interface {
+	// VerifyPolicySignatures verifies all signatures of the given signed policy.
+	VerifyPolicySignatures(sigPol *SignedPolicySGX) error
+}
+
+var policySignatureVerifiers = map[string]PolicySignatureVerifier{
+	PolicySignatureAlgorithmEd25519: &ed25519PolicySignatureVerifier{},
+}
+
+// RegisterPolicySignatureVerifier registers a policy signature verification backend
+// for the given algorithm identifier.
+//
+// This function is not safe for concurrent use and should only be called during
+// initialization.
+func RegisterPolicySignatureVerifier(algorithm string, verifier PolicySignatureVerifier) {
+	if _, ok := policySignatureVerifiers[algorithm]; ok {
+		panic(fmt.Sprintf("keymanager: policy signature verifier already registered: %s", algorithm))
+	}
+	policySignatureVerifiers[algorithm] = verifier
+}
+
+// GetPolicySignatureVerifier returns the policy signature verification backend
+// for the given algorithm identifier.
+func GetPolicySignatureVerifier(algorithm string) (PolicySignatureVerifier, error) {
+	if algorithm == "" {
+		algorithm = PolicySignatureAlgorithmEd25519
+	}
+	verifier, ok := policySignatureVerifiers[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("keymanager: unsupported policy signature algorithm: %s", algorithm)
+	}
+	return verifier, nil
+}
+
+type ed25519PolicySignatureVerifier struct{}
+
+func (v *ed25519PolicySignatureVerifier) VerifyPolicySignatures(sigPol *SignedPolicySGX) error {
+	rawPol := cbor.Marshal(sigPol.Policy)
+	for _, sig := range sigPol.Signatures {
+		if !sig.PublicKey.IsValid() {
+			return fmt.Errorf("keymanager: sanity check failed: SGX policy signature's public key %s is invalid", sig.PublicKey.String())
+		}
+		if !sig.Verify(PolicySGXSignatureContext, rawPol) {
+			return fmt.Errorf("keymanager: sanity check failed: SGX policy signature from %s is invalid", sig.PublicKey.String())
+		}
+	}
+	return nil
+}
+
 // PolicySGX is a key manager access control policy for the replicated
 // SGX key manager.
 type PolicySGX struct {
@@ -31,6 +133,47 @@ type PolicySGX struct {
 
 	// MaxEphemeralSecretAge is the maximum age of an ephemeral secret in the number of epochs.
 	MaxEphemeralSecretAge beacon.EpochTime `json:"max_ephemeral_secret_age,omitempty"`
+
+	// AdmitAnyConformingVersion allows nodes to join the key manager committee if at least one
+	// of the runtime versions they run conforms to the key manager status. By default, all
+	// versions need to conform, which rejects nodes that are in the middle of an upgrade.
+	AdmitAnyConformingVersion bool `json:"admit_any_conforming_version,omitempty"`
+
+	// MasterSecretProposalCooldown is the minimum number of epochs between two published
+	// master secret proposals. Zero allows a proposal every epoch.
+	MasterSecretProposalCooldown beacon.EpochTime `json:"master_secret_proposal_cooldown,omitempty"`
+
+	// CommitteeFreezePeriod is the number of epochs after a master secret rotation during which
+	// the key manager committee membership is frozen. Frozen committees retain their members
+	// despite registration gaps and don't admit new nodes. Policy updates always break
+	// the freeze. Zero disables the freeze.
+	CommitteeFreezePeriod beacon.EpochTime `json:"committee_freeze_period,omitempty"`
+
+	// AllowedEntities is the list of entities whose nodes may join the key manager committee.
+	// Empty allows nodes of any entity.
+	AllowedEntities []signature.PublicKey `json:"allowed_entities,omitempty"`
+
+	// MinEnclaveVersion is the minimum version of the key manager runtime that nodes need
+	// to run to join the key manager committee, even if their enclave is allowed by the policy.
+	// If not set, any version is allowed.
+	MinEnclaveVersion *version.Version `json:"min_enclave_version,omitempty"`
+}
+
+// IsEntityAllowed returns true iff nodes of the given entity may join the key manager committee.
+func (p *PolicySGX) IsEntityAllowed(id signature.PublicKey) bool {
+	if len(p.AllowedEntities) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(p.AllowedEntities, id.Equal)
+}
+
+// IsVersionAllowed returns true iff the given version of the key manager runtime is not below
+// the minimum version required by the policy.
+func (p *PolicySGX) IsVersionAllowed(v version.Version) bool {
+	if p.MinEnclaveVersion == nil {
+		return true
+	}
+	return v.ToU64() >= p.MinEnclaveVersion.ToU64()
 }
 
 // EnclavePolicySGX is the per-SGX key manager enclave ID access control policy.
@@ -53,18 +196,34 @@ type SignedPolicySGX struct {
 	Policy PolicySGX `json:"policy"`
 
 	Signatures []signature.Signature `json:"signatures"`
+
+	// Algorithm is the identifier of the policy signature scheme.
+	//
+	// If empty, the signatures are Ed25519 signatures.
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// Description is an optional human-readable description of the policy update.
+	//
+	// The description is not covered by the signatures. It is stored in the key manager
+	// status instead of the policy, so that it doesn't affect the policy checksum.
+	Description string `json:"description,omitempty"`
 }
 
 // SanityCheckSignedPolicySGX verifies a SignedPolicySGX.
 func SanityCheckSignedPolicySGX(currentSigPol, newSigPol *SignedPolicySGX) error {
-	newRawPol := cbor.Marshal(newSigPol.Policy)
-	for _, sig := range newSigPol.Signatures {
-		if !sig.PublicKey.IsValid() {
-			return fmt.Errorf("keymanager: sanity check failed: SGX policy signature's public key %s is invalid", sig.PublicKey.String())
-		}
-		if !sig.Verify(PolicySGXSignatureContext, newRawPol) {
-			return fmt.Errorf("keymanager: sanity check failed: SGX policy signature from %s is invalid", sig.PublicKey.String())
-		}
+	if len(newSigPol.Description) > MaxPolicyDescriptionLength {
+		return fmt.Errorf("keymanager: sanity check failed: policy description too long (max: %d, got: %d)", MaxPolicyDescriptionLength, len(newSigPol.Description))
+	}
+	if !utf8.ValidString(newSigPol.Description) {
+		return fmt.Errorf("keymanager: sanity check failed: policy description is not valid UTF-8")
+	}
+
+	verifier, err := GetPolicySignatureVerifier(newSigPol.Algorithm)
+	if err != nil {
+		return err
+	}
+	if err = verifier.VerifyPolicySignatures(newSigPol); err != nil {
+		return err
 	}
 
 	// If a prior version of the policy is not provided, then there is nothing
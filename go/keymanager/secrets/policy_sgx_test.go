@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/sgx"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+)
+
+type testPolicySignatureVerifier struct {
+	err error
+}
+
+func (v *testPolicySignatureVerifier) VerifyPolicySignatures(*SignedPolicySGX) error {
+	return v.err
+}
+
+func TestPolicySGXSerialization(t *testing.T) {
+	require := require.New(t)
+
+	// NOTE: These cases should be synced with tests in runtime/src/consensus/keymanager.rs.
+	for _, tc := range []struct {
+		policy         PolicySGX
+		expectedBase64 string
+	}{
+		{
+			PolicySGX{
+				Serial:   1,
+				Enclaves: map[sgx.EnclaveIdentity]*EnclavePolicySGX{},
+			},
+			"o2JpZFggAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABmc2VyaWFsAWhlbmNsYXZlc6A=",
+		},
+		{
+			PolicySGX{
+				Serial:            1,
+				Enclaves:          map[sgx.EnclaveIdentity]*EnclavePolicySGX{},
+				MinEnclaveVersion: &version.Version{},
+			},
+			"pGJpZFggAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABmc2VyaWFsAWhlbmNsYXZlc6BzbWluX2VuY2xhdmVfdmVyc2lvbqA=",
+		},
+		{
+			PolicySGX{
+				Serial:            1,
+				Enclaves:          map[sgx.EnclaveIdentity]*EnclavePolicySGX{},
+				MinEnclaveVersion: &version.Version{Major: 1, Minor: 2, Patch: 3},
+			},
+			"pGJpZFggAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABmc2VyaWFsAWhlbmNsYXZlc6BzbWluX2VuY2xhdmVfdmVyc2lvbqNlbWFqb3IBZW1pbm9yAmVwYXRjaAM=",
+		},
+	} {
+		enc := cbor.Marshal(tc.policy)
+		require.Equal(tc.expectedBase64, base64.StdEncoding.EncodeToString(enc), "serialization should match")
+
+		var dec PolicySGX
+		err := cbor.Unmarshal(enc, &dec)
+		require.NoError(err, "Unmarshal")
+		require.EqualValues(tc.policy, dec, "policy serialization should round-trip")
+	}
+}
+
+func TestSanityCheckSignedPolicySGX(t *testing.T) {
+	require := require.New(t)
+
+	signer := memorySigner.NewTestSigner("policy signer")
+	policy := PolicySGX{
+		Serial: 1,
+	}
+	sig, err := signature.Sign(signer, PolicySGXSignatureContext, cbor.Marshal(policy))
+	require.NoError(err, "Sign")
+
+	sigPol := SignedPolicySGX{
+		Policy:     policy,
+		Signatures: []signature.Signature{*sig},
+	}
+
+	// The default scheme should be used if the algorithm is not set.
+	err = SanityCheckSignedPolicySGX(nil, &sigPol)
+	require.NoError(err, "sanity check should succeed for a valid Ed25519 signature")
+
+	sigPol.Algorithm = PolicySignatureAlgorithmEd25519
+	err = SanityCheckSignedPolicySGX(nil, &sigPol)
+	require.NoError(err, "sanity check should succeed for an explicit Ed25519 algorithm")
+
+	sigPol.Policy.Serial = 2
+	err = SanityCheckSignedPolicySGX(nil, &sigPol)
+	require.Error(err, "sanity check should fail for an invalid Ed25519 signature")
+
+	// Unknown algorithms should be rejected.
+	sigPol.Algorithm = "unknown"
+	err = SanityCheckSignedPolicySGX(nil, &sigPol)
+	require.ErrorContains(err, "unsupported policy signature algorithm")
+
+	// Registered algorithms should be used for verification.
+	RegisterPolicySignatureVerifier("test-ok", &testPolicySignatureVerifier{})
+	RegisterPolicySignatureVerifier("test-fail", &testPolicySignatureVerifier{err: fmt.Errorf("test")})
+
+	sigPol.Algorithm = "test-ok"
+	err = SanityCheckSignedPolicySGX(nil, &sigPol)
+	require.NoError(err, "sanity check should succeed if the registered verifier succeeds")
+
+	sigPol.Algorithm = "test-fail"
+	err = SanityCheckSignedPolicySGX(nil, &sigPol)
+	require.Error(err, "sanity check should fail if the registered verifier fails")
+
+	require.Panics(func() {
+		RegisterPolicySignatureVerifier(PolicySignatureAlgorithmEd25519, &testPolicySignatureVerifier{})
+	}, "registering an algorithm twice should panic")
+}
+
+func TestSanityCheckSignedPolicySGXDescription(t *testing.T) {
+	require := require.New(t)
+
+	signer := memorySigner.NewTestSigner("policy signer")
+	policy := PolicySGX{
+		Serial: 1,
+	}
+	sig, err := signature.Sign(signer, PolicySGXSignatureContext, cbor.Marshal(policy))
+	require.NoError(err, "Sign")
+
+	sigPol := SignedPolicySGX{
+		Policy:      policy,
+		Signatures:  []signature.Signature{*sig},
+		Description: "rotated after incident X",
+	}
+	err = SanityCheckSignedPolicySGX(nil, &sigPol)
+	require.NoError(err, "sanity check should succeed for a valid description")
+
+	sigPol.Description = strings.Repeat("ą", MaxPolicyDescriptionLength/2)
+	err = SanityCheckSignedPolicySGX(nil, &sigPol)
+	require.NoError(err, "sanity check should succeed for a description of maximum length")
+
+	sigPol.Description = strings.Repeat("a", MaxPolicyDescriptionLength+1)
+	err = SanityCheckSignedPolicySGX(nil, &sigPol)
+	require.ErrorContains(err, "policy description too long")
+
+	sigPol.Description = string([]byte{0xff, 0xfe})
+	err = SanityCheckSignedPolicySGX(nil, &sigPol)
+	require.ErrorContains(err, "policy description is not valid UTF-8")
+}
+
+func TestComputePolicyHash(t *testing.T) {
+	require := require.New(t)
+
+	// Nil policies should hash to the checksum of an empty input.
+	require.Equal(sha3.Sum256(nil), ComputePolicyHash(sha3.Sum256, nil), "nil policy")
+	require.Equal(sha512.Sum512_256(nil), ComputePolicyHash(sha512.Sum512_256, nil), "nil policy with SHA-512/256")
+
+	policy := &SignedPolicySGX{
+		Policy: PolicySGX{
+			Serial: 1,
+		},
+	}
+	require.Equal(sha3.Sum256(cbor.Marshal(policy)), ComputePolicyHash(sha3.Sum256, policy), "policy")
+	require.Equal(sha512.Sum512_256(cbor.Marshal(policy)), ComputePolicyHash(sha512.Sum512_256, policy), "policy with SHA-512/256")
+	require.NotEqual(ComputePolicyHash(sha3.Sum256, nil), ComputePolicyHash(sha3.Sum256, policy), "nil and non-nil policies should differ")
+}
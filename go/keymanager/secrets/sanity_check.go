@@ -2,6 +2,7 @@ package secrets
 
 import (
 	"fmt"
+	"slices"
 )
 
 // SanityCheckStatuses examines the statuses table.
@@ -12,13 +13,23 @@ func SanityCheckStatuses(statuses []*Status) error {
 			return fmt.Errorf("keymanager: sanity check failed: key manager runtime ID %s is invalid", status.ID)
 		}
 
-		// Verify currently active key manager node IDs.
-		for _, node := range status.Nodes {
+		// Verify currently active and standby key manager node IDs.
+		for _, node := range append(slices.Clone(status.Nodes), status.StandbyNodes...) {
 			if !node.IsValid() {
 				return fmt.Errorf("keymanager: sanity check failed: key manager node ID %s is invalid", node.String())
 			}
 		}
 
+		// Verify compromised generations, which must be sorted, unique and generated.
+		for i, generation := range status.CompromisedGenerations {
+			if i > 0 && generation <= status.CompromisedGenerations[i-1] {
+				return fmt.Errorf("keymanager: sanity check failed: compromised generations of %s are not sorted", status.ID)
+			}
+			if generation >= status.NextGeneration() {
+				return fmt.Errorf("keymanager: sanity check failed: compromised generation %d of %s has not been generated", generation, status.ID)
+			}
+		}
+
 		// Verify SGX policy signatures if the policy exists.
 		if status.Policy != nil {
 			if err := SanityCheckSignedPolicySGX(nil, status.Policy); err != nil {
@@ -40,12 +51,33 @@ func (g *Genesis) SanityCheck() error {
 
 // SanityCheck performs a sanity check on the consensus parameters.
 func (p *ConsensusParameters) SanityCheck() error {
+	if _, err := GetPolicyChecksumFunc(p.PolicyChecksumAlgorithm); err != nil {
+		return err
+	}
+	if p.MinSecureNodePercent > 100 {
+		return fmt.Errorf("minimum secure node percentage %d exceeds 100", p.MinSecureNodePercent)
+	}
+	if p.MaxAdmissionRecords > MaxAdmissionRecordsLimit {
+		return fmt.Errorf("maximum number of admission records %d exceeds %d", p.MaxAdmissionRecords, MaxAdmissionRecordsLimit)
+	}
 	return nil
 }
 
 // SanityCheck performs a sanity check on the consensus parameter changes.
 func (c *ConsensusParameterChanges) SanityCheck() error {
-	if c.GasCosts == nil {
+	if c.GasCosts == nil &&
+		c.NodeExpirationGracePeriod == nil &&
+		c.MaxAdmissionRecords == nil &&
+		c.DisableInsecureKeyManagers == nil &&
+		c.PolicyChecksumAlgorithm == nil &&
+		c.EnforceActiveDeployment == nil &&
+		c.MaxCommitteeAdditions == nil &&
+		c.MaxCommitteeRemovals == nil &&
+		c.MaxRetainedGenerations == nil &&
+		c.MaxPolicySize == nil &&
+		c.MaxActiveCommitteeSize == nil &&
+		c.MinSecureNodePercent == nil &&
+		len(c.CompromisedSecrets) == 0 {
 		return fmt.Errorf("consensus parameter changes should not be empty")
 	}
 	return nil
@@ -1,6 +1,7 @@
 package secrets
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/oasisprotocol/curve25519-voi/primitives/x25519"
@@ -8,6 +9,7 @@ import (
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 )
 
@@ -21,6 +23,72 @@ var EncryptedMasterSecretSignatureContext = signature.NewContext("oasis-core/key
 // EncryptedEphemeralSecretSignatureContext is the context used to sign encrypted key manager ephemeral secrets.
 var EncryptedEphemeralSecretSignatureContext = signature.NewContext("oasis-core/keymanager: encrypted ephemeral secret")
 
+// VerifyErrorReason is the reason why the verification of an encrypted secret failed.
+type VerifyErrorReason uint8
+
+const (
+	// VerifyErrRAK is the reason used when the secret is not signed by the RAK.
+	VerifyErrRAK VerifyErrorReason = iota + 1
+	// VerifyErrEpoch is the reason used when the secret is not for the expected epoch.
+	VerifyErrEpoch
+	// VerifyErrGeneration is the reason used when the secret is not for the expected generation.
+	VerifyErrGeneration
+	// VerifyErrREK is the reason used when the secret is not encrypted with the expected REKs.
+	VerifyErrREK
+)
+
+// String returns a string representation of the verification failure reason.
+func (r VerifyErrorReason) String() string {
+	switch r {
+	case VerifyErrRAK:
+		return "rak"
+	case VerifyErrEpoch:
+		return "epoch"
+	case VerifyErrGeneration:
+		return "generation"
+	case VerifyErrREK:
+		return "rek"
+	default:
+		return fmt.Sprintf("[unknown reason: %d]", uint8(r))
+	}
+}
+
+var (
+	// ErrNoREKs is the error wrapped by the verification error when there are no REKs
+	// to which the secret could be encrypted.
+	ErrNoREKs = errors.New("keymanager: sanity check failed: secret has to be encrypted with at least one key")
+
+	// ErrUnknownREK is the error wrapped by the verification error when the secret
+	// is encrypted with a REK that is not expected.
+	ErrUnknownREK = errors.New("keymanager: sanity check failed: secret is encrypted with an unknown key")
+)
+
+// VerifyError is the error returned when the verification of an encrypted secret fails.
+type VerifyError struct {
+	// Reason is the reason why the verification failed.
+	Reason VerifyErrorReason
+
+	// Err is the error describing the failure in detail.
+	Err error
+}
+
+func newVerifyError(reason VerifyErrorReason, format string, a ...interface{}) error {
+	return &VerifyError{
+		Reason: reason,
+		Err:    fmt.Errorf(format, a...),
+	}
+}
+
+// Error implements the error interface.
+func (e *VerifyError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
 // EncryptedSecret is a secret encrypted with Deoxys-II MRAE algorithm.
 type EncryptedSecret struct {
 	// Checksum is the secret verification checksum.
@@ -37,20 +105,20 @@ type EncryptedSecret struct {
 func (s *EncryptedSecret) SanityCheck(reks map[x25519.PublicKey]struct{}) error {
 	// Secret should be encrypted to at least one member of the key manager committee.
 	if len(reks) == 0 {
-		return fmt.Errorf("keymanager: sanity check failed: secret has to be encrypted with at least one key")
+		return &VerifyError{Reason: VerifyErrREK, Err: ErrNoREKs}
 	}
 
 	// Secret should be encrypted to the enclaves from the key manager committee only.
 	for pk := range s.Ciphertexts {
 		if _, ok := reks[pk]; !ok {
-			return fmt.Errorf("keymanager: sanity check failed: secret is encrypted with an unknown key")
+			return &VerifyError{Reason: VerifyErrREK, Err: ErrUnknownREK}
 		}
 	}
 
 	// Most of the enclaves should be able to decrypt the secret.
 	percent := len(s.Ciphertexts) * 100 / len(reks)
 	if percent < minEnclavesPercent {
-		return fmt.Errorf("keymanager: sanity check failed: secret is not encrypted with enough keys")
+		return newVerifyError(VerifyErrREK, "keymanager: sanity check failed: secret is not encrypted with enough keys")
 	}
 
 	return nil
@@ -74,11 +142,11 @@ type EncryptedMasterSecret struct {
 // SanityCheck performs a sanity check on the master secret.
 func (s *EncryptedMasterSecret) SanityCheck(generation uint64, epoch beacon.EpochTime, reks map[x25519.PublicKey]struct{}) error {
 	if generation != s.Generation {
-		return fmt.Errorf("keymanager: sanity check failed: master secret contains an invalid generation: (expected: %d, got: %d)", generation, s.Generation)
+		return newVerifyError(VerifyErrGeneration, "keymanager: sanity check failed: master secret contains an invalid generation: (expected: %d, got: %d)", generation, s.Generation)
 	}
 
 	if epoch != s.Epoch {
-		return fmt.Errorf("keymanager: sanity check failed: master secret contains an invalid epoch: (expected: %d, got: %d)", epoch, s.Epoch)
+		return newVerifyError(VerifyErrEpoch, "keymanager: sanity check failed: master secret contains an invalid epoch: (expected: %d, got: %d)", epoch, s.Epoch)
 	}
 
 	return s.Secret.SanityCheck(reks)
@@ -99,7 +167,7 @@ type EncryptedEphemeralSecret struct {
 // SanityCheck performs a sanity check on the ephemeral secret.
 func (s *EncryptedEphemeralSecret) SanityCheck(epoch beacon.EpochTime, reks map[x25519.PublicKey]struct{}) error {
 	if epoch != s.Epoch {
-		return fmt.Errorf("keymanager: sanity check failed: ephemeral secret contains an invalid epoch: (expected: %d, got: %d)", epoch, s.Epoch)
+		return newVerifyError(VerifyErrEpoch, "keymanager: sanity check failed: ephemeral secret contains an invalid epoch: (expected: %d, got: %d)", epoch, s.Epoch)
 	}
 
 	return s.Secret.SanityCheck(reks)
@@ -122,12 +190,18 @@ func (s *SignedEncryptedMasterSecret) Verify(generation uint64, epoch beacon.Epo
 
 	raw := cbor.Marshal(s.Secret)
 	if !rak.Verify(EncryptedMasterSecretSignatureContext, raw, s.Signature[:]) {
-		return fmt.Errorf("keymanager: sanity check failed: master secret contains an invalid signature")
+		return newVerifyError(VerifyErrRAK, "keymanager: sanity check failed: master secret contains an invalid signature")
 	}
 
 	return nil
 }
 
+// IdempotencyKey returns a content-derived key of the signed master secret, which can be used
+// to detect resubmissions of the same secret.
+func (s *SignedEncryptedMasterSecret) IdempotencyKey() hash.Hash {
+	return hash.NewFrom(s)
+}
+
 // SignedEncryptedEphemeralSecret is a RAK signed encrypted ephemeral secret.
 type SignedEncryptedEphemeralSecret struct {
 	// Secret is the encrypted ephemeral secret.
@@ -147,8 +221,57 @@ func (s *SignedEncryptedEphemeralSecret) Verify(epoch beacon.EpochTime, reks map
 	// Verify the signature.
 	raw := cbor.Marshal(s.Secret)
 	if !rak.Verify(EncryptedEphemeralSecretSignatureContext, raw, s.Signature[:]) {
-		return fmt.Errorf("keymanager: sanity check failed: ephemeral secret contains an invalid signature")
+		return newVerifyError(VerifyErrRAK, "keymanager: sanity check failed: ephemeral secret contains an invalid signature")
 	}
 
 	return nil
 }
+
+// IdempotencyKey returns a content-derived key of the signed ephemeral secret, which can be used
+// to detect resubmissions of the same secret.
+func (s *SignedEncryptedEphemeralSecret) IdempotencyKey() hash.Hash {
+	return hash.NewFrom(s)
+}
+
+// MaxCompromisedGenerations is the maximum number of master secret generations of a key manager
+// that can be marked as compromised in a single proposal.
+const MaxCompromisedGenerations = 128
+
+// CompromisedSecrets marks generations of a key manager master secret as compromised,
+// as part of a governance change parameters proposal.
+//
+// Marking a generation as compromised doesn't delete the secret, as it is still needed
+// to decrypt data during the migration to a newer generation. The generation is only
+// flagged in the key manager status, so that runtimes can stop trusting data encrypted
+// under it and force its re-encryption.
+type CompromisedSecrets struct {
+	// ID is the runtime ID of the key manager.
+	ID common.Namespace `json:"runtime_id"`
+
+	// Generations are the compromised generations of the master secret.
+	Generations []uint64 `json:"generations"`
+}
+
+// StatusRefresh requests the key manager status to be regenerated from the current node
+// registrations immediately, instead of on the next epoch transition.
+type StatusRefresh struct {
+	// ID is the runtime ID of the key manager.
+	ID common.Namespace `json:"runtime_id"`
+}
+
+// SanityCheck performs a sanity check on the compromised secrets. Only generations
+// preceding the given next generation, i.e. generated ones, can be marked as compromised.
+func (cs *CompromisedSecrets) SanityCheck(nextGeneration uint64) error {
+	if len(cs.Generations) == 0 {
+		return fmt.Errorf("keymanager: sanity check failed: no compromised generations")
+	}
+	if len(cs.Generations) > MaxCompromisedGenerations {
+		return fmt.Errorf("keymanager: sanity check failed: too many compromised generations (max: %d, got: %d)", MaxCompromisedGenerations, len(cs.Generations))
+	}
+	for _, generation := range cs.Generations {
+		if generation >= nextGeneration {
+			return fmt.Errorf("keymanager: sanity check failed: master secret generation %d has not been generated", generation)
+		}
+	}
+	return nil
+}
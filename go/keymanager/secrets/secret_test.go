@@ -2,6 +2,7 @@ package secrets
 
 import (
 	"crypto/sha512"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -11,6 +12,7 @@ import (
 
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
 	"github.com/oasisprotocol/oasis-core/go/common"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
 )
 
 func TestEncryptedSecret(t *testing.T) {
@@ -114,6 +116,52 @@ func TestEncryptedEphemeralSecret(t *testing.T) {
 	require.EqualError(err, "keymanager: sanity check failed: secret has to be encrypted with at least one key")
 }
 
+func TestSignedEncryptedMasterSecretVerifyReason(t *testing.T) {
+	require := require.New(t)
+
+	sec, reks := generateTestSecret(3)
+	gen := uint64(0)
+	epoch := beacon.EpochTime(100)
+	signer := memorySigner.NewTestSigner("rak")
+	rak := signer.Public()
+	sigSec := SignedEncryptedMasterSecret{
+		Secret: EncryptedMasterSecret{
+			ID:         common.NewTestNamespaceFromSeed([]byte("runtime 1"), common.NamespaceKeyManager),
+			Generation: gen,
+			Epoch:      epoch,
+			Secret:     sec,
+		},
+	}
+
+	reason := func(err error) VerifyErrorReason {
+		var verr *VerifyError
+		require.True(errors.As(err, &verr), "error should be a verification error")
+		return verr.Reason
+	}
+
+	// Unsigned secret.
+	err := sigSec.Verify(gen, epoch, reks, &rak)
+	require.EqualError(err, "keymanager: sanity check failed: master secret contains an invalid signature")
+	require.Equal(VerifyErrRAK, reason(err))
+
+	err = sigSec.Verify(gen, epoch+1, reks, &rak)
+	require.Equal(VerifyErrEpoch, reason(err))
+
+	err = sigSec.Verify(gen+1, epoch, reks, &rak)
+	require.Equal(VerifyErrGeneration, reason(err))
+
+	err = sigSec.Verify(gen, epoch, nil, &rak)
+	require.Equal(VerifyErrREK, reason(err))
+	require.Equal("rek", reason(err).String())
+	require.ErrorIs(err, ErrNoREKs)
+
+	sk := x25519.PrivateKey(sha512.Sum512_256([]byte("unknown")))
+	unknownREKs := map[x25519.PublicKey]struct{}{*sk.Public(): {}}
+	err = sigSec.Verify(gen, epoch, unknownREKs, &rak)
+	require.Equal(VerifyErrREK, reason(err))
+	require.ErrorIs(err, ErrUnknownREK)
+}
+
 func generateTestSecret(n int) (EncryptedSecret, map[x25519.PublicKey]struct{}) {
 	reks := make(map[x25519.PublicKey]struct{})
 	ciphertexts := make(map[x25519.PublicKey][]byte)
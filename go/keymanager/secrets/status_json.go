@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"encoding/hex"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+)
+
+// StatusJSON is a human-friendly view of a key manager status for display and consumption by
+// external tooling. Byte fields are hex-encoded, node IDs use their string form and versions
+// are formatted as strings.
+//
+// The view is one-way, it cannot be converted back into a status.
+type StatusJSON struct {
+	ID                     string                      `json:"id"`
+	IsInitialized          bool                        `json:"is_initialized"`
+	IsSecure               bool                        `json:"is_secure"`
+	IsDegraded             bool                        `json:"is_degraded,omitempty"`
+	Generation             uint64                      `json:"generation"`
+	RotationEpoch          beacon.EpochTime            `json:"rotation_epoch"`
+	Checksum               string                      `json:"checksum"`
+	RotationPending        bool                        `json:"rotation_pending"`
+	PendingGeneration      uint64                      `json:"pending_generation,omitempty"`
+	PendingEpoch           beacon.EpochTime            `json:"pending_epoch,omitempty"`
+	Nodes                  []string                    `json:"nodes"`
+	StandbyNodes           []string                    `json:"standby_nodes,omitempty"`
+	PolicySerial           *uint32                     `json:"policy_serial,omitempty"`
+	PolicyDescription      string                      `json:"policy_description,omitempty"`
+	RSK                    string                      `json:"rsk,omitempty"`
+	ReportedNodes          uint64                      `json:"reported_nodes,omitempty"`
+	SupportedVersions      map[string]uint64           `json:"supported_versions,omitempty"`
+	CompromisedGenerations []uint64                    `json:"compromised_generations,omitempty"`
+	LastSeenEpochs         map[string]beacon.EpochTime `json:"last_seen_epochs,omitempty"`
+	CommitteeChanges       *CommitteeChanges           `json:"committee_changes,omitempty"`
+}
+
+// JSONView returns the human-friendly view of the status.
+func (s *Status) JSONView() *StatusJSON {
+	v := &StatusJSON{
+		ID:                     s.ID.Hex(),
+		IsInitialized:          s.IsInitialized,
+		IsSecure:               s.IsSecure,
+		IsDegraded:             s.IsDegraded,
+		Generation:             s.Generation,
+		RotationEpoch:          s.RotationEpoch,
+		Checksum:               hex.EncodeToString(s.Checksum),
+		RotationPending:        s.RotationPending,
+		PendingGeneration:      s.PendingGeneration,
+		PendingEpoch:           s.PendingEpoch,
+		Nodes:                  make([]string, 0, len(s.Nodes)),
+		PolicyDescription:      s.PolicyDescription,
+		ReportedNodes:          s.ReportedNodes,
+		CompromisedGenerations: s.CompromisedGenerations,
+		CommitteeChanges:       s.CommitteeChanges,
+	}
+	for _, id := range s.Nodes {
+		v.Nodes = append(v.Nodes, id.String())
+	}
+	for _, id := range s.StandbyNodes {
+		v.StandbyNodes = append(v.StandbyNodes, id.String())
+	}
+	if s.Policy != nil {
+		serial := s.Policy.Policy.Serial
+		v.PolicySerial = &serial
+	}
+	if s.RSK != nil {
+		v.RSK = hex.EncodeToString(s.RSK[:])
+	}
+	if len(s.SupportedVersions) > 0 {
+		v.SupportedVersions = make(map[string]uint64, len(s.SupportedVersions))
+		for _, vc := range s.SupportedVersions {
+			v.SupportedVersions[vc.Version.String()] = vc.Nodes
+		}
+	}
+	if len(s.LastSeenEpochs) > 0 {
+		v.LastSeenEpochs = make(map[string]beacon.EpochTime, len(s.LastSeenEpochs))
+		for id, epoch := range s.LastSeenEpochs {
+			v.LastSeenEpochs[id.String()] = epoch
+		}
+	}
+	return v
+}
@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+)
+
+func TestStatusJSONView(t *testing.T) {
+	require := require.New(t)
+
+	var id common.Namespace
+	err := id.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000001")
+	require.NoError(err, "UnmarshalHex")
+
+	node := memorySigner.NewTestSigner("node").Public()
+	var rsk signature.PublicKey
+	rsk[0], rsk[31] = 0xab, 0xcd
+
+	// Empty statuses should still have stable fields.
+	data, err := json.Marshal((&Status{ID: id}).JSONView())
+	require.NoError(err, "Marshal")
+	require.JSONEq(`{
+		"id": "8000000000000000000000000000000000000000000000000000000000000001",
+		"is_initialized": false,
+		"is_secure": false,
+		"generation": 0,
+		"rotation_epoch": 0,
+		"checksum": "",
+		"rotation_pending": false,
+		"nodes": []
+	}`, string(data))
+
+	status := &Status{
+		ID:                id,
+		IsInitialized:     true,
+		IsSecure:          true,
+		Generation:        2,
+		RotationEpoch:     10,
+		Checksum:          []byte{0x01, 0x02, 0xff},
+		Nodes:             []signature.PublicKey{node},
+		Policy:            &SignedPolicySGX{Policy: PolicySGX{Serial: 3}},
+		PolicyDescription: "update",
+		RSK:               &rsk,
+		SupportedVersions: []VersionCount{
+			{Version: version.Version{Major: 1, Minor: 2, Patch: 3}, Nodes: 1},
+		},
+		CompromisedGenerations: []uint64{0},
+		LastSeenEpochs:         map[signature.PublicKey]beacon.EpochTime{node: 10},
+	}
+	data, err = json.Marshal(status.JSONView())
+	require.NoError(err, "Marshal")
+	require.JSONEq(fmt.Sprintf(`{
+		"id": "8000000000000000000000000000000000000000000000000000000000000001",
+		"is_initialized": true,
+		"is_secure": true,
+		"generation": 2,
+		"rotation_epoch": 10,
+		"checksum": "0102ff",
+		"rotation_pending": false,
+		"nodes": [%[1]q],
+		"policy_serial": 3,
+		"policy_description": "update",
+		"rsk": "ab000000000000000000000000000000000000000000000000000000000000cd",
+		"supported_versions": {"1.2.3": 1},
+		"compromised_generations": [0],
+		"last_seen_epochs": {%[1]q: 10}
+	}`, node.String()), string(data))
+}
@@ -558,7 +558,8 @@ func AppendRootHashState(doc *genesis.Document, exports []string, l *logging.Log
 func AppendKeyManagerState(doc *genesis.Document, statuses []string, l *logging.Logger) error {
 	kmSt := secrets.Genesis{
 		Parameters: secrets.ConsensusParameters{
-			GasCosts: secrets.DefaultGasCosts, // TODO: Make these configurable.
+			GasCosts:      secrets.DefaultGasCosts, // TODO: Make these configurable.
+			MaxPolicySize: secrets.DefaultMaxPolicySize,
 		},
 	}
 
@@ -322,6 +322,7 @@ func testBeacon(t *testing.T, node *testNode) {
 
 	timeSource := (node.Consensus.Beacon()).(beacon.SetableBackend)
 	beaconTests.BeaconImplementationTests(t, timeSource)
+	beaconTests.ReadOnlyBeaconImplementationTests(t, timeSource)
 }
 
 func testStorage(t *testing.T, _ *testNode) {
@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
+	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
+	"github.com/oasisprotocol/oasis-core/go/keymanager/secrets"
+)
+
+const (
+	// ConsensusKeyManagerRefreshStatus is the name of the upgrade that configures the gas cost
+	// of key manager status refreshes.
+	ConsensusKeyManagerRefreshStatus = "consensus-km-refresh-status"
+)
+
+var _ Handler = (*kmRefreshStatusHandler)(nil)
+
+type kmRefreshStatusHandler struct{}
+
+func (th *kmRefreshStatusHandler) StartupUpgrade() error {
+	return nil
+}
+
+func (th *kmRefreshStatusHandler) ConsensusUpgrade(privateCtx interface{}) error {
+	abciCtx := privateCtx.(*abciAPI.Context)
+	switch abciCtx.Mode() {
+	case abciAPI.ContextBeginBlock:
+		// Nothing to do during begin block.
+	case abciAPI.ContextEndBlock:
+		// Update a consensus parameters during EndBlock.
+
+		// Key manager.
+		kmState := secretsState.NewMutableState(abciCtx.State())
+
+		kmParams, err := kmState.ConsensusParameters(abciCtx)
+		if err != nil {
+			return fmt.Errorf("unable to load key manager consensus parameters: %w", err)
+		}
+
+		// Configure the default gas cost for status refreshes, as refreshes would be free
+		// otherwise.
+		if kmParams.GasCosts == nil {
+			kmParams.GasCosts = make(transaction.Costs)
+		}
+		kmParams.GasCosts[secrets.GasOpRefreshStatus] = secrets.DefaultGasCosts[secrets.GasOpRefreshStatus]
+
+		if err = kmState.SetConsensusParameters(abciCtx, kmParams); err != nil {
+			return fmt.Errorf("failed to update key manager consensus parameters: %w", err)
+		}
+	default:
+		return fmt.Errorf("upgrade handler called in unexpected context: %s", abciCtx.Mode())
+	}
+	return nil
+}
+
+func init() {
+	Register(ConsensusKeyManagerRefreshStatus, &kmRefreshStatusHandler{})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
+	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
+	"github.com/oasisprotocol/oasis-core/go/keymanager/secrets"
+)
+
+func TestKeyManagerRefreshStatusMigration(t *testing.T) {
+	require := require.New(t)
+
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	// Consensus parameters of an existing network, without the refresh status gas cost.
+	state := secretsState.NewMutableState(ctx.State())
+	err := state.SetConsensusParameters(ctx, &secrets.ConsensusParameters{
+		GasCosts: transaction.Costs{
+			secrets.GasOpUpdatePolicy: 2000,
+		},
+	})
+	require.NoError(err, "SetConsensusParameters")
+
+	handler, err := GetHandler(ConsensusKeyManagerRefreshStatus)
+	require.NoError(err, "GetHandler")
+
+	err = handler.ConsensusUpgrade(ctx)
+	require.NoError(err, "ConsensusUpgrade(EndBlock)")
+
+	params, err := state.ConsensusParameters(ctx)
+	require.NoError(err, "ConsensusParameters")
+	require.Equal(transaction.Costs{
+		secrets.GasOpUpdatePolicy:  2000,
+		secrets.GasOpRefreshStatus: secrets.DefaultGasCosts[secrets.GasOpRefreshStatus],
+	}, params.GasCosts, "refresh status gas cost should be configured")
+}
@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"fmt"
+
+	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	beaconState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/beacon/state"
+	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
+)
+
+const (
+	// ConsensusKeyManagerRotationEpoch is the name of the upgrade that backfills the master
+	// secret rotation epoch of key manager statuses persisted before the field existed.
+	ConsensusKeyManagerRotationEpoch = "consensus-km-rotation-epoch"
+)
+
+var _ Handler = (*kmRotationEpochHandler)(nil)
+
+type kmRotationEpochHandler struct{}
+
+func (th *kmRotationEpochHandler) StartupUpgrade() error {
+	return nil
+}
+
+func (th *kmRotationEpochHandler) ConsensusUpgrade(privateCtx interface{}) error {
+	abciCtx := privateCtx.(*abciAPI.Context)
+	switch abciCtx.Mode() {
+	case abciAPI.ContextBeginBlock:
+		// Nothing to do during begin block.
+	case abciAPI.ContextEndBlock:
+		// Backfill key manager statuses during EndBlock.
+		if err := backfillKeyManagerRotationEpochs(abciCtx); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("upgrade handler called in unexpected context: %s", abciCtx.Mode())
+	}
+	return nil
+}
+
+// backfillKeyManagerRotationEpochs sets the rotation epoch of legacy key manager statuses
+// to the current epoch.
+//
+// Legacy statuses have master secrets, but a zero rotation epoch which is indistinguishable
+// from a rotation in the first epoch. The first generation of the master secret can never be
+// a rotation, so a zero rotation epoch is only meaningful if no secret has been generated yet.
+// The epoch of the upgrade is used as it is the first epoch in which the status is known
+// to have a meaningful rotation epoch, which conservatively postpones the next rotation.
+func backfillKeyManagerRotationEpochs(ctx *abciAPI.Context) error {
+	epoch, _, err := beaconState.NewMutableState(ctx.State()).GetEpoch(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load current epoch: %w", err)
+	}
+
+	state := secretsState.NewMutableState(ctx.State())
+	statuses, err := state.Statuses(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to load key manager statuses: %w", err)
+	}
+
+	for _, status := range statuses {
+		if status.RotationEpoch != 0 || len(status.Checksum) == 0 {
+			continue
+		}
+
+		ctx.Logger().Info("backfilling key manager rotation epoch",
+			"id", status.ID,
+			"generation", status.Generation,
+			"rotation_epoch", epoch,
+		)
+
+		status.RotationEpoch = epoch
+		if err = state.SetStatus(ctx, status); err != nil {
+			return fmt.Errorf("failed to update key manager status: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	Register(ConsensusKeyManagerRotationEpoch, &kmRotationEpochHandler{})
+}
@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/api"
+	beaconState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/beacon/state"
+	secretsState "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/apps/keymanager/secrets/state"
+	"github.com/oasisprotocol/oasis-core/go/keymanager/secrets"
+)
+
+func TestKeyManagerRotationEpochMigration(t *testing.T) {
+	require := require.New(t)
+
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock)
+	defer ctx.Close()
+
+	const epoch = beacon.EpochTime(42)
+	err := beaconState.NewMutableState(ctx.State()).SetEpoch(ctx, epoch, 100)
+	require.NoError(err, "SetEpoch")
+
+	newID := func(seed string) common.Namespace {
+		return common.NewTestNamespaceFromSeed([]byte(seed), common.NamespaceKeyManager)
+	}
+	statuses := map[string]*secrets.Status{
+		// Legacy status with master secrets, but without a rotation epoch.
+		"legacy": {
+			ID:            newID("legacy"),
+			IsInitialized: true,
+			Generation:    2,
+			Checksum:      []byte{1, 2, 3},
+		},
+		// Status without master secrets.
+		"no secrets": {
+			ID:            newID("no secrets"),
+			IsInitialized: true,
+		},
+		// Status with a meaningful rotation epoch.
+		"rotated": {
+			ID:            newID("rotated"),
+			IsInitialized: true,
+			Generation:    3,
+			RotationEpoch: 7,
+			Checksum:      []byte{4, 5, 6},
+		},
+	}
+
+	state := secretsState.NewMutableState(ctx.State())
+	for _, status := range statuses {
+		err = state.SetStatus(ctx, status)
+		require.NoError(err, "SetStatus")
+	}
+
+	// Run the migration, first in BeginBlock where it should be a no-op.
+	handler, err := GetHandler(ConsensusKeyManagerRotationEpoch)
+	require.NoError(err, "GetHandler")
+
+	bbCtx := appState.NewContext(abciAPI.ContextBeginBlock)
+	err = handler.ConsensusUpgrade(bbCtx)
+	bbCtx.Close()
+	require.NoError(err, "ConsensusUpgrade(BeginBlock)")
+
+	err = handler.ConsensusUpgrade(ctx)
+	require.NoError(err, "ConsensusUpgrade(EndBlock)")
+
+	for name, expRotationEpoch := range map[string]beacon.EpochTime{
+		"legacy":     epoch,
+		"no secrets": 0,
+		"rotated":    7,
+	} {
+		status, err := state.Status(ctx, statuses[name].ID)
+		require.NoError(err, "Status")
+		require.Equal(expRotationEpoch, status.RotationEpoch, "rotation epoch of status %s", name)
+	}
+}
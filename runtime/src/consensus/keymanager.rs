@@ -5,11 +5,12 @@ use thiserror::Error;
 
 use crate::common::{
     crypto::{
-        signature::{Signature, SignatureBundle, Signer},
+        signature::{PublicKey, Signature, SignatureBundle, Signer},
         x25519,
     },
     namespace::Namespace,
     sgx::EnclaveIdentity,
+    version::Version,
 };
 
 use super::beacon::EpochTime;
@@ -17,6 +18,9 @@ use super::beacon::EpochTime;
 /// Context used to sign key manager policies.
 const POLICY_SIGNATURE_CONTEXT: &[u8] = b"oasis-core/keymanager: policy";
 
+/// Identifier of the Ed25519 policy signature scheme.
+const POLICY_SIGNATURE_ALGORITHM_ED25519: &str = "ed25519";
+
 /// Context used to sign encrypted key manager master secrets.
 const ENCRYPTED_MASTER_SECRET_SIGNATURE_CONTEXT: &[u8] =
     b"oasis-core/keymanager: encrypted master secret";
@@ -30,6 +34,8 @@ const ENCRYPTED_EPHEMERAL_SECRET_SIGNATURE_CONTEXT: &[u8] =
 pub enum Error {
     #[error("invalid signature")]
     InvalidSignature,
+    #[error("unsupported signature algorithm: {0}")]
+    UnsupportedSignatureAlgorithm(String),
 }
 
 /// Key manager access control policy.
@@ -42,6 +48,16 @@ pub struct PolicySGX {
     pub master_secret_rotation_interval: EpochTime,
     #[cbor(optional)]
     pub max_ephemeral_secret_age: EpochTime,
+    #[cbor(optional)]
+    pub admit_any_conforming_version: bool,
+    #[cbor(optional)]
+    pub master_secret_proposal_cooldown: EpochTime,
+    #[cbor(optional)]
+    pub committee_freeze_period: EpochTime,
+    #[cbor(optional)]
+    pub allowed_entities: Vec<PublicKey>,
+    #[cbor(optional)]
+    pub min_enclave_version: Option<Version>,
 }
 
 /// Per enclave key manager access control policy.
@@ -56,11 +72,18 @@ pub struct EnclavePolicySGX {
 pub struct SignedPolicySGX {
     pub policy: PolicySGX,
     pub signatures: Vec<SignatureBundle>,
+    /// Identifier of the policy signature scheme, Ed25519 if empty.
+    #[cbor(optional)]
+    pub algorithm: String,
 }
 
 impl SignedPolicySGX {
     /// Verify the signatures.
     pub fn verify(&self) -> Result<&PolicySGX, Error> {
+        if !self.algorithm.is_empty() && self.algorithm != POLICY_SIGNATURE_ALGORITHM_ED25519 {
+            return Err(Error::UnsupportedSignatureAlgorithm(self.algorithm.clone()));
+        }
+
         let raw_policy = cbor::to_vec(self.policy.clone());
         for sig in &self.signatures {
             sig.signature
@@ -144,3 +167,46 @@ impl SignedEncryptedEphemeralSecret {
         Ok(Self { secret, signature })
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_consistent_policy() {
+        // NOTE: These tests MUST be synced with go/keymanager/secrets/policy_sgx_test.go.
+        let tcs = vec![
+            (
+                "o2JpZFggAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABmc2VyaWFsAWhlbmNsYXZlc6A=",
+                PolicySGX {
+                    serial: 1,
+                    ..Default::default()
+                },
+            ),
+            (
+                "pGJpZFggAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABmc2VyaWFsAWhlbmNsYXZlc6BzbWluX2VuY2xhdmVfdmVyc2lvbqA=",
+                PolicySGX {
+                    serial: 1,
+                    min_enclave_version: Some(Version::default()),
+                    ..Default::default()
+                },
+            ),
+            (
+                "pGJpZFggAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABmc2VyaWFsAWhlbmNsYXZlc6BzbWluX2VuY2xhdmVfdmVyc2lvbqNlbWFqb3IBZW1pbm9yAmVwYXRjaAM=",
+                PolicySGX {
+                    serial: 1,
+                    min_enclave_version: Some(Version::new(1, 2, 3)),
+                    ..Default::default()
+                },
+            ),
+        ];
+        for (encoded_base64, policy) in tcs {
+            let dec: PolicySGX = cbor::from_slice(&base64::decode(encoded_base64).unwrap())
+                .expect("policy should deserialize correctly");
+            assert_eq!(dec, policy, "decoded policy should match the expected value");
+
+            let ser = base64::encode(cbor::to_vec(dec));
+            assert_eq!(ser, encoded_base64, "policy should serialize correctly");
+        }
+    }
+}
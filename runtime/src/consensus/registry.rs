@@ -171,6 +171,10 @@ pub struct NodeRuntime {
 
     /// Extra per node + per runtime opaque data associated with the current instance.
     pub extra_info: Option<Vec<u8>>,
+
+    /// Whether the runtime instance is a shadow (canary) instance.
+    #[cbor(optional)]
+    pub shadow: bool,
 }
 
 /// TEE hardware implementation.
@@ -932,6 +936,7 @@ mod tests {
                                }),
                             },
                             extra_info: Some(vec![5,3,2,1]),
+                            ..Default::default()
                         },
                     ]),
                     ..Default::default()
@@ -1057,6 +1062,7 @@ mod tests {
                                 }),
                             },
                             extra_info: Some(vec![5,3,2,1]),
+                            ..Default::default()
                         },
                     ]),
                     ..Default::default()
@@ -1106,6 +1112,7 @@ mod tests {
                                 }),
                             },
                             extra_info: Some(vec![5,3,2,1]),
+                            ..Default::default()
                         },
                     ]),
                     ..Default::default()
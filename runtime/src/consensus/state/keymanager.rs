@@ -1,4 +1,6 @@
 //! Key manager state in the consensus layer.
+use std::collections::HashMap;
+
 use anyhow::anyhow;
 
 use crate::{
@@ -6,6 +8,7 @@ use crate::{
         crypto::{hash::Hash, signature::PublicKey},
         key_format::{KeyFormat, KeyFormatAtom},
         namespace::Namespace,
+        version::Version,
     },
     consensus::{
         beacon::EpochTime,
@@ -43,18 +46,73 @@ pub struct Status {
     pub is_initialized: bool,
     /// True iff the key manager is secure.
     pub is_secure: bool,
+    /// True iff too few of the eligible nodes passed secure verification.
+    #[cbor(optional)]
+    pub is_degraded: bool,
     /// Generation of the latest master secret.
     pub generation: u64,
     /// Epoch of the last master secret rotation.
     pub rotation_epoch: EpochTime,
     /// Key manager master secret verification checksum.
     pub checksum: Vec<u8>,
+    /// True iff the proposal for the next master secret is pending acceptance.
+    #[cbor(optional)]
+    pub rotation_pending: bool,
+    /// Generation of the pending proposal, if any.
+    #[cbor(optional)]
+    pub pending_generation: u64,
+    /// Epoch of the pending proposal, if any.
+    #[cbor(optional)]
+    pub pending_epoch: EpochTime,
     /// List of currently active key manager node IDs.
     pub nodes: Vec<PublicKey>,
+    /// List of key manager node IDs exceeding the active committee size limit.
+    #[cbor(optional)]
+    pub standby_nodes: Vec<PublicKey>,
     /// Key manager policy.
     pub policy: Option<SignedPolicySGX>,
+    /// Description of the last policy update.
+    #[cbor(optional)]
+    pub policy_description: String,
     /// Runtime signing key of the key manager.
     pub rsk: Option<PublicKey>,
+    /// Number of nodes that reported in while the key manager is initializing.
+    #[cbor(optional)]
+    pub reported_nodes: u64,
+    /// Key manager runtime versions run by the committee nodes.
+    #[cbor(optional)]
+    pub supported_versions: Vec<VersionCount>,
+    /// Generations of the master secret that have been marked as compromised.
+    #[cbor(optional)]
+    pub compromised_generations: Vec<u64>,
+    /// Epochs in which the committee nodes were last admitted to the committee.
+    #[cbor(optional)]
+    pub last_seen_epochs: HashMap<PublicKey, EpochTime>,
+    /// Committee changes made in the most recent epoch in which the committee changed.
+    #[cbor(optional)]
+    pub committee_changes: Option<CommitteeChanges>,
+}
+
+/// Key manager committee changes made in an epoch.
+#[derive(Clone, Debug, Default, PartialEq, Eq, cbor::Decode, cbor::Encode)]
+pub struct CommitteeChanges {
+    /// Epoch in which the changes were made.
+    pub epoch: EpochTime,
+    /// Number of nodes that joined the committee.
+    #[cbor(optional)]
+    pub additions: u64,
+    /// Number of nodes that left the committee.
+    #[cbor(optional)]
+    pub removals: u64,
+}
+
+/// Number of key manager committee nodes running a runtime version.
+#[derive(Clone, Debug, Default, PartialEq, Eq, cbor::Decode, cbor::Encode)]
+pub struct VersionCount {
+    /// Key manager runtime version.
+    pub version: Version,
+    /// Number of committee nodes running the version.
+    pub nodes: u64,
 }
 
 impl<'a, T: ImmutableMKVS> ImmutableState<'a, T> {
@@ -221,21 +279,37 @@ mod test {
                 id: keymanager1,
                 is_initialized: false,
                 is_secure: false,
+                is_degraded: false,
                 generation: 0,
                 rotation_epoch: 0,
                 checksum: vec![],
+                rotation_pending: false,
+                pending_generation: 0,
+                pending_epoch: 0,
                 nodes: vec![],
+                standby_nodes: vec![],
                 policy: None,
                 rsk: None,
+                reported_nodes: 0,
+                policy_description: String::new(),
+                supported_versions: vec![],
+                compromised_generations: vec![],
+                last_seen_epochs: HashMap::new(),
+                committee_changes: None,
             },
             Status {
                 id: keymanager2,
                 is_initialized: true,
                 is_secure: true,
+                is_degraded: false,
                 generation: 0,
                 rotation_epoch: 0,
                 checksum: checksum,
+                rotation_pending: false,
+                pending_generation: 0,
+                pending_epoch: 0,
                 nodes: vec![signer1, signer2],
+                standby_nodes: vec![],
                 policy: Some(SignedPolicySGX {
                     policy: PolicySGX {
                         serial: 1,
@@ -249,6 +323,11 @@ mod test {
                         )]),
                         master_secret_rotation_interval: 0,
                         max_ephemeral_secret_age: 10,
+                        admit_any_conforming_version: false,
+                        master_secret_proposal_cooldown: 0,
+                        committee_freeze_period: 0,
+                        allowed_entities: vec![],
+                        min_enclave_version: None,
                     },
                     signatures: vec![
                         SignatureBundle {
@@ -260,8 +339,15 @@ mod test {
                             signature: sig2,
                         },
                     ],
+                    algorithm: String::new(),
                 }),
                 rsk: None,
+                reported_nodes: 0,
+                policy_description: String::new(),
+                supported_versions: vec![],
+                compromised_generations: vec![],
+                last_seen_epochs: HashMap::new(),
+                committee_changes: None,
             },
         ];
 
@@ -194,6 +194,7 @@ mod test {
                                }),
                             },
                             extra_info: Some(vec![5,3,2,1]),
+                            ..Default::default()
                         },
                     ]),
                     ..Default::default()